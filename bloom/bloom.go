@@ -0,0 +1,259 @@
+// Package bloom 布隆过滤器、计数布隆过滤器与可扩展布隆过滤器
+//
+// 使用 murmur3 128位哈希的两个64位结果做双重哈希 h1 + i*h2 计算k个位置，
+// 已有哈希值的调用方可以直接使用 AddHash/TestHash。过滤器均非并发安全，需要调用方加锁。
+//
+//	var f = bloom.NewWithEstimates(100000, 0.01)
+//	f.AddString("key")
+//	f.TestString("key") // true
+package bloom
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"math/bits"
+
+	"github.com/spaolacci/murmur3"
+)
+
+var (
+	// 合并的过滤器位数或哈希函数个数不一致
+	ErrIncompatible = errors.New("bloom: incompatible filters")
+	// 序列化数据格式错误
+	ErrBadFormat = errors.New("bloom: bad format")
+)
+
+const (
+	filterMagic = "BLF1"
+
+	// 反序列化允许的最大位数与哈希函数个数，避免损坏的数据导致超大内存分配
+	maxBits   = 1 << 32
+	maxHashes = 64
+)
+
+// Hash 计算双重哈希使用的两个哈希值
+func Hash(data []byte) (uint64, uint64) {
+	var h1, h2 = murmur3.Sum128(data)
+	// h2为0时所有位置相同
+	return h1, h2 | 1
+}
+
+// EstimateParameters 预计插入n个元素、误判率为fpRate时的位数m与哈希函数个数k
+func EstimateParameters(n uint64, fpRate float64) (m uint64, k uint64) {
+	if n == 0 {
+		n = 1
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		fpRate = 0.01
+	}
+	m = uint64(math.Ceil(-float64(n) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k = uint64(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return m, k
+}
+
+// Filter 标准布隆过滤器
+type Filter struct {
+	bits []uint64
+	m    uint64 // 位数
+	k    uint64 // 哈希函数个数
+	n    uint64 // 已插入的元素个数（含重复）
+}
+
+// New m为位数，k为哈希函数个数
+func New(m, k uint64) *Filter {
+	if m < 1 {
+		m = 1
+	}
+	if k < 1 {
+		k = 1
+	}
+	return &Filter{bits: make([]uint64, (m+63)/64), m: m, k: k}
+}
+
+// NewWithEstimates 按预计元素个数与误判率创建
+func NewWithEstimates(n uint64, fpRate float64) *Filter {
+	return New(EstimateParameters(n, fpRate))
+}
+
+// Cap 位数
+func (f *Filter) Cap() uint64 {
+	return f.m
+}
+
+// K 哈希函数个数
+func (f *Filter) K() uint64 {
+	return f.k
+}
+
+// Count 已插入的元素个数，包括重复插入
+func (f *Filter) Count() uint64 {
+	return f.n
+}
+
+func (f *Filter) Add(data []byte) {
+	f.AddHash(Hash(data))
+}
+
+func (f *Filter) AddString(s string) {
+	f.Add([]byte(s))
+}
+
+// AddHash 按调用方计算的哈希值插入
+func (f *Filter) AddHash(h1, h2 uint64) {
+	for i := uint64(0); i < f.k; i++ {
+		var pos = (h1 + i*h2) % f.m
+		f.bits[pos/64] |= 1 << (pos % 64)
+	}
+	f.n++
+}
+
+// Test 是否可能存在，false表示一定不存在
+func (f *Filter) Test(data []byte) bool {
+	return f.TestHash(Hash(data))
+}
+
+func (f *Filter) TestString(s string) bool {
+	return f.Test([]byte(s))
+}
+
+func (f *Filter) TestHash(h1, h2 uint64) bool {
+	for i := uint64(0); i < f.k; i++ {
+		var pos = (h1 + i*h2) % f.m
+		if f.bits[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// TestAndAdd 插入并返回插入前是否可能存在
+func (f *Filter) TestAndAdd(data []byte) bool {
+	var h1, h2 = Hash(data)
+	var ok = f.TestHash(h1, h2)
+	f.AddHash(h1, h2)
+	return ok
+}
+
+// EstimatedFPRate 按当前填充率估计的误判率
+func (f *Filter) EstimatedFPRate() float64 {
+	var set int
+	for _, w := range f.bits {
+		set += bits.OnesCount64(w)
+	}
+	return math.Pow(float64(set)/float64(f.m), float64(f.k))
+}
+
+// Merge 合并other中的元素（并集），位数与哈希函数个数需要一致
+func (f *Filter) Merge(other *Filter) error {
+	if f.m != other.m || f.k != other.k {
+		return ErrIncompatible
+	}
+	for i, w := range other.bits {
+		f.bits[i] |= w
+	}
+	f.n += other.n
+	return nil
+}
+
+// Copy 复制过滤器
+func (f *Filter) Copy() *Filter {
+	var c = *f
+	c.bits = append([]uint64(nil), f.bits...)
+	return &c
+}
+
+func (f *Filter) Clear() {
+	for i := range f.bits {
+		f.bits[i] = 0
+	}
+	f.n = 0
+}
+
+// WriteTo 序列化到w，实现 io.WriterTo
+func (f *Filter) WriteTo(w io.Writer) (int64, error) {
+	var bw = bufio.NewWriter(w)
+	var cw = &countWriter{w: bw}
+	var err = writeAll(cw, []byte(filterMagic), f.m, f.k, f.n, f.bits)
+	if err == nil {
+		err = bw.Flush()
+	}
+	return cw.n, err
+}
+
+// ReadFrom 从r反序列化并覆盖当前过滤器，实现 io.ReaderFrom
+func (f *Filter) ReadFrom(r io.Reader) (int64, error) {
+	var cr = &countReader{r: r}
+	var err = f.read(cr)
+	return cr.n, err
+}
+
+func (f *Filter) read(r io.Reader) error {
+	var magic = make([]byte, len(filterMagic))
+	var m, k, n uint64
+	if err := readAll(r, magic, &m, &k, &n); err != nil {
+		return err
+	}
+	if string(magic) != filterMagic || m == 0 || m > maxBits || k == 0 || k > maxHashes {
+		return ErrBadFormat
+	}
+	var words = make([]uint64, (m+63)/64)
+	if err := readAll(r, words); err != nil {
+		return err
+	}
+	*f = Filter{bits: words, m: m, k: k, n: n}
+	return nil
+}
+
+// 按大端序依次写出定长数据
+func writeAll(w io.Writer, data ...interface{}) error {
+	for _, d := range data {
+		if err := binary.Write(w, binary.BigEndian, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 按大端序依次读入定长数据，数据不完整时返回 io.ErrUnexpectedEOF
+func readAll(r io.Reader, data ...interface{}) error {
+	for _, d := range data {
+		if err := binary.Read(r, binary.BigEndian, d); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	var n, err = c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+type countReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countReader) Read(p []byte) (int, error) {
+	var n, err = c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,221 @@
+package bloom_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/1005281342/basic_component/bloom"
+)
+
+// 误判率，查询n个未插入的元素
+func fpRate(test func(string) bool, n int) float64 {
+	var fp int
+	for i := 0; i < n; i++ {
+		if test(fmt.Sprintf("absent%d", i)) {
+			fp++
+		}
+	}
+	return float64(fp) / float64(n)
+}
+
+func TestFilter(t *testing.T) {
+	var f = bloom.NewWithEstimates(10000, 0.01)
+	for i := 0; i < 10000; i++ {
+		f.AddString(fmt.Sprintf("key%d", i))
+	}
+	for i := 0; i < 10000; i++ {
+		if !f.TestString(fmt.Sprintf("key%d", i)) {
+			t.Fatalf("false negative for key%d", i)
+		}
+	}
+	if rate := fpRate(f.TestString, 10000); rate > 0.02 {
+		t.Fatalf("false positive rate = %v, want about 0.01", rate)
+	}
+	if rate := f.EstimatedFPRate(); rate < 0.005 || rate > 0.02 {
+		t.Fatalf("EstimatedFPRate() = %v, want about 0.01", rate)
+	}
+
+	// 合并
+	var a, b = bloom.New(1024, 3), bloom.New(1024, 3)
+	a.AddString("a")
+	b.AddString("b")
+	if err := a.Merge(b); err != nil || !a.TestString("a") || !a.TestString("b") || a.Count() != 2 {
+		t.Fatalf("Merge = %v, want union", err)
+	}
+	if err := a.Merge(bloom.New(2048, 3)); err != bloom.ErrIncompatible {
+		t.Fatalf("Merge(incompatible) = %v, want ErrIncompatible", err)
+	}
+
+	// 序列化
+	var buf bytes.Buffer
+	var n, err = f.WriteTo(&buf)
+	if err != nil || n != int64(buf.Len()) {
+		t.Fatalf("WriteTo = %d, %v, buffer %d", n, err, buf.Len())
+	}
+	var g bloom.Filter
+	if n, err = g.ReadFrom(&buf); err != nil || g.Cap() != f.Cap() || g.K() != f.K() || g.Count() != f.Count() {
+		t.Fatalf("ReadFrom = %d, %v", n, err)
+	}
+	if !g.TestString("key42") {
+		t.Fatalf("deserialized filter lost key42")
+	}
+	if _, err = g.ReadFrom(bytes.NewReader([]byte("BLF1\x00"))); err == nil {
+		t.Fatalf("ReadFrom(truncated) succeeded")
+	}
+}
+
+func TestCountingFilter(t *testing.T) {
+	var f = bloom.NewCountingWithEstimates(1000, 0.01)
+	for i := 0; i < 1000; i++ {
+		f.AddString(fmt.Sprintf("key%d", i))
+	}
+	for i := 0; i < 500; i++ {
+		if !f.RemoveString(fmt.Sprintf("key%d", i)) {
+			t.Fatalf("Remove(key%d) = false", i)
+		}
+	}
+	for i := 500; i < 1000; i++ {
+		if !f.TestString(fmt.Sprintf("key%d", i)) {
+			t.Fatalf("false negative for key%d after removals", i)
+		}
+	}
+	var present int
+	for i := 0; i < 500; i++ {
+		if f.TestString(fmt.Sprintf("key%d", i)) {
+			present++
+		}
+	}
+	if present > 25 || f.Count() != 500 {
+		t.Fatalf("removed keys present = %d, Count() = %d", present, f.Count())
+	}
+	if !f.Filter().TestString("key999") {
+		t.Fatalf("Filter() lost key999")
+	}
+
+	var other = bloom.NewCounting(f.Cap(), f.K())
+	other.AddString("merged")
+	if err := f.Merge(other); err != nil || !f.TestString("merged") {
+		t.Fatalf("Merge = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var g bloom.CountingFilter
+	if _, err := g.ReadFrom(&buf); err != nil || !g.RemoveString("merged") || g.TestString("merged") {
+		t.Fatalf("ReadFrom = %v, want removable counters", err)
+	}
+}
+
+func TestScalableFilter(t *testing.T) {
+	var f = bloom.NewScalable(100, 0.01)
+	for i := 0; i < 10000; i++ {
+		if f.TestAndAdd([]byte(fmt.Sprintf("key%d", i))) && i < 100 {
+			t.Fatalf("key%d reported present before insertion", i)
+		}
+	}
+	if f.Filters() < 5 {
+		t.Fatalf("Filters() = %d, want growth", f.Filters())
+	}
+	for i := 0; i < 10000; i++ {
+		if !f.TestString(fmt.Sprintf("key%d", i)) {
+			t.Fatalf("false negative for key%d", i)
+		}
+	}
+	if rate := fpRate(f.TestString, 10000); rate > 0.02 {
+		t.Fatalf("false positive rate = %v, want below 0.01", rate)
+	}
+
+	var other = bloom.NewScalable(100, 0.01)
+	other.AddString("other")
+	f.Merge(other)
+	if !f.TestString("other") || !f.TestString("key1") {
+		t.Fatalf("Merge lost keys")
+	}
+
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var g bloom.ScalableFilter
+	if _, err := g.ReadFrom(&buf); err != nil || g.Filters() != f.Filters() || g.Count() != f.Count() {
+		t.Fatalf("ReadFrom = %v, Filters() = %d, want %d", err, g.Filters(), f.Filters())
+	}
+	g.AddString("after")
+	if !g.TestString("after") || !g.TestString("key9999") {
+		t.Fatalf("deserialized filter lost keys")
+	}
+}
+
+// 损坏的头部不应导致超大内存分配
+func TestReadCorrupt(t *testing.T) {
+	var header = func(magic string, m, k uint64) io.Reader {
+		var b = make([]byte, 28)
+		copy(b, magic)
+		binary.BigEndian.PutUint64(b[4:], m)
+		binary.BigEndian.PutUint64(b[12:], k)
+		return bytes.NewReader(b)
+	}
+	var cases = []struct {
+		name string
+		m, k uint64
+	}{
+		{"huge m", 1 << 62, 3},
+		{"huge k", 1024, 1 << 40},
+	}
+	for _, c := range cases {
+		var f bloom.Filter
+		if _, err := f.ReadFrom(header("BLF1", c.m, c.k)); err != bloom.ErrBadFormat {
+			t.Errorf("Filter.ReadFrom(%s) = %v, want ErrBadFormat", c.name, err)
+		}
+		var cf bloom.CountingFilter
+		if _, err := cf.ReadFrom(header("BLC1", c.m, c.k)); err != bloom.ErrBadFormat {
+			t.Errorf("CountingFilter.ReadFrom(%s) = %v, want ErrBadFormat", c.name, err)
+		}
+	}
+}
+
+// 可扩展过滤器的参数不合法时返回 ErrBadFormat
+func TestScalableReadCorrupt(t *testing.T) {
+	var f = bloom.NewScalable(100, 0.01)
+	f.AddString("key")
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	// 头部：magic n fpRate growth tightening count capacities...
+	var cases = []struct {
+		name   string
+		offset int
+		value  uint64
+	}{
+		{"zero growth", 20, 0},
+		{"NaN fpRate", 12, math.Float64bits(math.NaN())},
+		{"fpRate 1", 12, math.Float64bits(1)},
+		{"zero tightening", 28, math.Float64bits(0)},
+		{"zero capacity", 44, 0},
+	}
+	for _, c := range cases {
+		var b = append([]byte(nil), buf.Bytes()...)
+		binary.BigEndian.PutUint64(b[c.offset:], c.value)
+		var g bloom.ScalableFilter
+		if _, err := g.ReadFrom(bytes.NewReader(b)); err != bloom.ErrBadFormat {
+			t.Errorf("ReadFrom(%s) = %v, want ErrBadFormat", c.name, err)
+		}
+	}
+}
+
+func BenchmarkFilter_Test(b *testing.B) {
+	var f = bloom.NewWithEstimates(1<<20, 0.01)
+	var data = []byte("benchmark-key")
+	f.Add(data)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		f.Test(data)
+	}
+}
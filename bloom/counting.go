@@ -0,0 +1,169 @@
+package bloom
+
+import (
+	"bufio"
+	"io"
+)
+
+const countingMagic = "BLC1"
+
+// CountingFilter 计数布隆过滤器，每个位置为8位计数器，支持删除
+// 计数器达到255后不再增减，避免删除导致误判为不存在
+type CountingFilter struct {
+	counters []uint8
+	m        uint64
+	k        uint64
+	n        uint64 // 当前元素个数
+}
+
+func NewCounting(m, k uint64) *CountingFilter {
+	if m < 1 {
+		m = 1
+	}
+	if k < 1 {
+		k = 1
+	}
+	return &CountingFilter{counters: make([]uint8, m), m: m, k: k}
+}
+
+// NewCountingWithEstimates 按预计元素个数与误判率创建
+func NewCountingWithEstimates(n uint64, fpRate float64) *CountingFilter {
+	return NewCounting(EstimateParameters(n, fpRate))
+}
+
+func (f *CountingFilter) Cap() uint64 {
+	return f.m
+}
+
+func (f *CountingFilter) K() uint64 {
+	return f.k
+}
+
+// Count 当前元素个数，即插入次数减去成功删除的次数
+func (f *CountingFilter) Count() uint64 {
+	return f.n
+}
+
+func (f *CountingFilter) Add(data []byte) {
+	f.AddHash(Hash(data))
+}
+
+func (f *CountingFilter) AddString(s string) {
+	f.Add([]byte(s))
+}
+
+func (f *CountingFilter) AddHash(h1, h2 uint64) {
+	for i := uint64(0); i < f.k; i++ {
+		var pos = (h1 + i*h2) % f.m
+		if f.counters[pos] < 255 {
+			f.counters[pos]++
+		}
+	}
+	f.n++
+}
+
+// Remove 删除一次插入的元素，return 删除前是否可能存在，不存在时不修改
+// 删除未插入过的元素会导致其他元素误判为不存在
+func (f *CountingFilter) Remove(data []byte) bool {
+	return f.RemoveHash(Hash(data))
+}
+
+func (f *CountingFilter) RemoveString(s string) bool {
+	return f.Remove([]byte(s))
+}
+
+func (f *CountingFilter) RemoveHash(h1, h2 uint64) bool {
+	if !f.TestHash(h1, h2) {
+		return false
+	}
+	for i := uint64(0); i < f.k; i++ {
+		var pos = (h1 + i*h2) % f.m
+		if f.counters[pos] < 255 {
+			f.counters[pos]--
+		}
+	}
+	if f.n > 0 {
+		f.n--
+	}
+	return true
+}
+
+func (f *CountingFilter) Test(data []byte) bool {
+	return f.TestHash(Hash(data))
+}
+
+func (f *CountingFilter) TestString(s string) bool {
+	return f.Test([]byte(s))
+}
+
+func (f *CountingFilter) TestHash(h1, h2 uint64) bool {
+	for i := uint64(0); i < f.k; i++ {
+		if f.counters[(h1+i*h2)%f.m] == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Merge 合并other中的元素，计数器相加，位数与哈希函数个数需要一致
+func (f *CountingFilter) Merge(other *CountingFilter) error {
+	if f.m != other.m || f.k != other.k {
+		return ErrIncompatible
+	}
+	for i, c := range other.counters {
+		if sum := uint16(f.counters[i]) + uint16(c); sum < 255 {
+			f.counters[i] = uint8(sum)
+		} else {
+			f.counters[i] = 255
+		}
+	}
+	f.n += other.n
+	return nil
+}
+
+// Filter 转换为标准布隆过滤器，计数大于0的位置为1
+func (f *CountingFilter) Filter() *Filter {
+	var bf = New(f.m, f.k)
+	for pos, c := range f.counters {
+		if c > 0 {
+			bf.bits[pos/64] |= 1 << (uint(pos) % 64)
+		}
+	}
+	bf.n = f.n
+	return bf
+}
+
+func (f *CountingFilter) Clear() {
+	for i := range f.counters {
+		f.counters[i] = 0
+	}
+	f.n = 0
+}
+
+func (f *CountingFilter) WriteTo(w io.Writer) (int64, error) {
+	var bw = bufio.NewWriter(w)
+	var cw = &countWriter{w: bw}
+	var err = writeAll(cw, []byte(countingMagic), f.m, f.k, f.n, f.counters)
+	if err == nil {
+		err = bw.Flush()
+	}
+	return cw.n, err
+}
+
+func (f *CountingFilter) ReadFrom(r io.Reader) (int64, error) {
+	var cr = &countReader{r: r}
+	var magic = make([]byte, len(countingMagic))
+	var m, k, n uint64
+	if err := readAll(cr, magic, &m, &k, &n); err != nil {
+		return cr.n, err
+	}
+	if string(magic) != countingMagic || m == 0 || m > maxBits || k == 0 || k > maxHashes {
+		return cr.n, ErrBadFormat
+	}
+	var counters = make([]uint8, m)
+	if err := readAll(cr, counters); err != nil {
+		return cr.n, err
+	}
+	*f = CountingFilter{counters: counters, m: m, k: k, n: n}
+	return cr.n, nil
+}
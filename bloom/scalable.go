@@ -0,0 +1,193 @@
+package bloom
+
+import (
+	"bufio"
+	"io"
+	"math"
+)
+
+const (
+	scalableMagic = "BLS1"
+
+	// 默认每个新过滤器的容量增长倍数
+	DefaultGrowth = 2
+	// 默认每个新过滤器的误判率收紧比例
+	DefaultTightening = 0.8
+)
+
+/*
+可扩展布隆过滤器：
+1. 由多个标准布隆过滤器组成，只向最后一个插入，最后一个的元素个数达到其预计容量时追加新的过滤器；
+2. 第i个过滤器的容量为 n*growth^i，误判率为 p*(1-r)*r^i，总误判率不超过 p；
+3. 查询时任一过滤器可能存在即可能存在。
+*/
+type ScalableFilter struct {
+	filters    []*Filter
+	capacities []uint64 // 每个过滤器的预计容量
+	n          uint64   // 第一个过滤器的预计容量
+	fpRate     float64  // 总误判率
+	growth     uint64
+	tightening float64
+}
+
+// NewScalable n为初始预计元素个数，fpRate为总误判率上限
+func NewScalable(n uint64, fpRate float64) *ScalableFilter {
+	return NewScalableWithOpt(n, fpRate, DefaultGrowth, DefaultTightening)
+}
+
+// NewScalableWithOpt growth为容量增长倍数，tightening为误判率收紧比例，取值(0, 1)
+func NewScalableWithOpt(n uint64, fpRate float64, growth uint64, tightening float64) *ScalableFilter {
+	if n == 0 {
+		n = 1
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		fpRate = 0.01
+	}
+	if growth < 1 {
+		growth = DefaultGrowth
+	}
+	if tightening <= 0 || tightening >= 1 {
+		tightening = DefaultTightening
+	}
+	var f = &ScalableFilter{n: n, fpRate: fpRate, growth: growth, tightening: tightening}
+	f.grow()
+	return f
+}
+
+func (f *ScalableFilter) grow() {
+	var i = len(f.filters)
+	var capacity = f.n * uint64(math.Pow(float64(f.growth), float64(i)))
+	var p = f.fpRate * (1 - f.tightening) * math.Pow(f.tightening, float64(i))
+	f.filters = append(f.filters, NewWithEstimates(capacity, p))
+	f.capacities = append(f.capacities, capacity)
+}
+
+// Count 已插入的元素个数，包括重复插入
+func (f *ScalableFilter) Count() uint64 {
+	var n uint64
+	for _, bf := range f.filters {
+		n += bf.n
+	}
+	return n
+}
+
+// Filters 当前的过滤器个数
+func (f *ScalableFilter) Filters() int {
+	return len(f.filters)
+}
+
+func (f *ScalableFilter) Add(data []byte) {
+	f.AddHash(Hash(data))
+}
+
+func (f *ScalableFilter) AddString(s string) {
+	f.Add([]byte(s))
+}
+
+func (f *ScalableFilter) AddHash(h1, h2 uint64) {
+	var last = len(f.filters) - 1
+	if f.filters[last].n >= f.capacities[last] {
+		f.grow()
+		last++
+	}
+	f.filters[last].AddHash(h1, h2)
+}
+
+func (f *ScalableFilter) Test(data []byte) bool {
+	return f.TestHash(Hash(data))
+}
+
+func (f *ScalableFilter) TestString(s string) bool {
+	return f.Test([]byte(s))
+}
+
+func (f *ScalableFilter) TestHash(h1, h2 uint64) bool {
+	for i := len(f.filters) - 1; i >= 0; i-- {
+		if f.filters[i].TestHash(h1, h2) {
+			return true
+		}
+	}
+	return false
+}
+
+// TestAndAdd 不存在时插入，return 插入前是否可能存在
+// 与标准过滤器不同，可能存在时不再插入，避免重复元素占用容量
+func (f *ScalableFilter) TestAndAdd(data []byte) bool {
+	var h1, h2 = Hash(data)
+	if f.TestHash(h1, h2) {
+		return true
+	}
+	f.AddHash(h1, h2)
+	return false
+}
+
+// Merge 合并other中的元素（并集），两者的过滤器个数与参数可以不同，
+// 同一位置参数一致的过滤器按位合并，其余复制追加
+func (f *ScalableFilter) Merge(other *ScalableFilter) {
+	for i, bf := range other.filters {
+		if i < len(f.filters) && f.filters[i].Merge(bf) == nil {
+			continue
+		}
+		f.filters = append(f.filters, bf.Copy())
+		f.capacities = append(f.capacities, other.capacities[i])
+	}
+	// 合并后的最后一个过滤器可能已满，下次插入时追加新的过滤器
+}
+
+func (f *ScalableFilter) Clear() {
+	f.filters, f.capacities = nil, nil
+	f.grow()
+}
+
+func (f *ScalableFilter) WriteTo(w io.Writer) (int64, error) {
+	var bw = bufio.NewWriter(w)
+	var cw = &countWriter{w: bw}
+	var err = writeAll(cw, []byte(scalableMagic), f.n, f.fpRate, f.growth, f.tightening, uint64(len(f.filters)), f.capacities)
+	for _, bf := range f.filters {
+		if err != nil {
+			break
+		}
+		_, err = bf.WriteTo(cw)
+	}
+	if err == nil {
+		err = bw.Flush()
+	}
+	return cw.n, err
+}
+
+func (f *ScalableFilter) ReadFrom(r io.Reader) (int64, error) {
+	var cr = &countReader{r: r}
+	var (
+		magic      = make([]byte, len(scalableMagic))
+		n, growth  uint64
+		fpRate     float64
+		tightening float64
+		count      uint64
+	)
+	if err := readAll(cr, magic, &n, &fpRate, &growth, &tightening, &count); err != nil {
+		return cr.n, err
+	}
+	// 参数范围与 NewScalableWithOpt 一致，NaN不满足任何比较
+	if string(magic) != scalableMagic || n == 0 || count == 0 || count > 1024 || growth < 1 ||
+		!(fpRate > 0 && fpRate < 1) || !(tightening > 0 && tightening < 1) {
+		return cr.n, ErrBadFormat
+	}
+	var capacities = make([]uint64, count)
+	if err := readAll(cr, capacities); err != nil {
+		return cr.n, err
+	}
+	for _, c := range capacities {
+		if c == 0 {
+			return cr.n, ErrBadFormat
+		}
+	}
+	var filters = make([]*Filter, count)
+	for i := range filters {
+		filters[i] = &Filter{}
+		if err := filters[i].read(cr); err != nil {
+			return cr.n, err
+		}
+	}
+	*f = ScalableFilter{filters: filters, capacities: capacities, n: n, fpRate: fpRate, growth: growth, tightening: tightening}
+	return cr.n, nil
+}
@@ -0,0 +1,415 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+/*
+自适应缓存：
+1. 每种候选策略各维护一个缓存，接收相同的访问序列；
+2. 只有当前策略的缓存保存元素值与过期时间，其余缓存作为影子缓存只记录key，用于统计命中；
+3. 候选缓存共用同一个协程池，不单独启动看门狗，由自适应缓存定期回收当前策略缓存中的过期元素；
+4. 在滑动窗口内统计各候选缓存的命中次数，其他策略的命中次数明显高于当前策略时切换当前策略；
+5. 切换时保留各缓存的访问历史，将元素值迁移至新缓存，仅存在于旧缓存的元素执行淘汰回调，仅存在于新缓存的key已执行过淘汰回调，直接移除；
+6. 通过 Stats 获取当前策略及窗口内各策略的命中次数。
+*/
+
+const (
+	// 默认滑动窗口大小为容量的倍数
+	DefaultAdaptiveWindowFactor = 10
+	// 切换策略需要领先的命中次数占窗口大小的比例
+	adaptiveSwitchMargin = 100
+	// 最多支持的候选策略个数，与记录命中情况、驻留情况的位数一致
+	adaptiveMaxCandidates = 8
+)
+
+var (
+	DefaultAdaptiveTypes = []CacheType{LRU, LFU}
+)
+
+// AdaptiveStats 自适应缓存状态
+type AdaptiveStats struct {
+	Mode       CacheType   // 当前淘汰策略
+	Candidates []CacheType // 候选淘汰策略
+	Hits       []int       // 滑动窗口内各候选策略的命中次数
+	Window     int         // 滑动窗口大小
+	Accesses   int         // 滑动窗口内已记录的访问次数
+	Switches   int         // 策略切换次数
+}
+
+type AdaptiveCache struct {
+	*adaptive
+	lock sync.Mutex
+	atomicOp
+}
+
+func NewAdaptiveCache(opt *Opt) (*AdaptiveCache, error) {
+	var (
+		c   *adaptive
+		err error
+	)
+	if c, err = newAdaptive(opt); err != nil {
+		return nil, err
+	}
+	var ac = &AdaptiveCache{adaptive: c}
+	ac.atomicOp = atomicOp{locker: &ac.lock, op: ac.adaptive}
+	startWatchdog(ac.expire, ac)
+	return ac, nil
+}
+
+func (ac *AdaptiveCache) Get(key interface{}) (interface{}, bool) {
+	ac.lock.Lock()
+	defer ac.lock.Unlock()
+	return ac.adaptive.Get(key)
+}
+
+func (ac *AdaptiveCache) Put(key, value interface{}) bool {
+	ac.lock.Lock()
+	defer ac.lock.Unlock()
+	return ac.adaptive.Put(key, value)
+}
+
+func (ac *AdaptiveCache) PutWithExpire(key interface{}, value interface{}, lifeSpan time.Duration) bool {
+	ac.lock.Lock()
+	defer ac.lock.Unlock()
+	return ac.adaptive.PutWithExpire(key, value, lifeSpan)
+}
+
+func (ac *AdaptiveCache) Remove(key interface{}) bool {
+	ac.lock.Lock()
+	defer ac.lock.Unlock()
+	return ac.adaptive.Remove(key)
+}
+
+func (ac *AdaptiveCache) DeleteExpired() {
+	ac.lock.Lock()
+	defer ac.lock.Unlock()
+	ac.adaptive.DeleteExpired()
+}
+
+func (ac *AdaptiveCache) Len() int {
+	ac.lock.Lock()
+	defer ac.lock.Unlock()
+	return ac.adaptive.Len()
+}
+
+func (ac *AdaptiveCache) Clear() {
+	ac.lock.Lock()
+	defer ac.lock.Unlock()
+	ac.adaptive.Clear()
+}
+
+func (ac *AdaptiveCache) Stats() AdaptiveStats {
+	ac.lock.Lock()
+	defer ac.lock.Unlock()
+	return ac.adaptive.Stats()
+}
+
+type adaptive struct {
+	types    []CacheType   // 候选策略
+	caches   []ExpireCache // 候选缓存，与候选策略一一对应，当前策略的值为adaptiveValue，影子缓存的值为adaptiveShadow
+	mode     int           // 当前策略下标
+	switches int           // 策略切换次数
+
+	window   []uint8 // 滑动窗口，第i位表示第i个候选缓存是否命中
+	pos      int     // 窗口写入位置
+	accesses int     // 窗口内已记录的访问次数
+	hits     []int   // 窗口内各候选缓存的命中次数
+	settled  int     // 上次切换后的访问次数
+
+	resident map[interface{}]*adaptiveKey // 元素在各候选缓存中的驻留情况
+	seq      uint64                       // 写入序号
+
+	evictLock sync.Mutex      // 保护evicted，淘汰回调异步执行
+	evicted   []adaptiveEvict // 候选缓存已淘汰的元素
+
+	onEvict   EvictCallback   // 淘汰元素时执行的回调
+	admission AdmissionPolicy // 准入策略，在写入候选缓存前统一判断
+	*expire                   // 过期属性
+}
+
+type adaptiveKey struct {
+	seq  uint64 // 最近一次写入的序号
+	mask uint8  // 第i位表示元素驻留在第i个候选缓存中
+}
+
+type adaptiveValue struct {
+	value interface{}
+	seq   uint64
+}
+
+// 影子缓存只记录写入序号，不保存元素值
+type adaptiveShadow uint64
+
+type adaptiveEvict struct {
+	key   interface{}
+	seq   uint64
+	index int
+}
+
+func newAdaptive(opt *Opt) (*adaptive, error) {
+	if opt.Capacity <= 0 {
+		return nil, ErrSize
+	}
+	var types = opt.AdaptiveTypes
+	if len(types) == 0 {
+		types = DefaultAdaptiveTypes
+	}
+	if len(types) < 2 || len(types) > adaptiveMaxCandidates {
+		return nil, fmt.Errorf("adaptive cache needs 2 to %d candidate types", adaptiveMaxCandidates)
+	}
+	var window = opt.AdaptiveWindow
+	if window <= 0 {
+		window = DefaultAdaptiveWindowFactor * opt.Capacity
+	}
+
+	var c = &adaptive{
+		types:     append([]CacheType(nil), types...),
+		caches:    make([]ExpireCache, len(types)),
+		window:    make([]uint8, window),
+		hits:      make([]int, len(types)),
+		resident:  make(map[interface{}]*adaptiveKey),
+		onEvict:   opt.Callback,
+		admission: opt.Admission,
+		expire:    newExpire(opt),
+	}
+	for i, ct := range c.types {
+		// Bytes 只接受[]byte值，无法保存包装后的adaptiveValue
+		if ct == Simple || ct == Adaptive || ct == Bytes {
+			return nil, fmt.Errorf("%v is not supported as an adaptive candidate", ct)
+		}
+		var candidateOpt = *opt
+		candidateOpt.Callback = c.evictHook(i)
+		candidateOpt.Admission = nil
+		candidateOpt.shared = c.expire
+		var cache, err = NewCache(ct, &candidateOpt)
+		if err != nil {
+			return nil, err
+		}
+		c.caches[i] = cache
+	}
+	return c, nil
+}
+
+// 候选缓存的淘汰回调，记录被淘汰的元素，只有保存了元素值的缓存执行用户回调
+func (c *adaptive) evictHook(index int) EvictCallback {
+	return func(key interface{}, value interface{}) {
+		var seq uint64
+		var av, ok = value.(adaptiveValue)
+		if ok {
+			seq = av.seq
+		} else {
+			seq = uint64(value.(adaptiveShadow))
+		}
+		c.evictLock.Lock()
+		c.evicted = append(c.evicted, adaptiveEvict{key: key, seq: seq, index: index})
+		c.evictLock.Unlock()
+		if ok {
+			callEvict(c.onEvict, key, av.value)
+		}
+	}
+}
+
+// 同步候选缓存的淘汰记录，写入序号不一致说明key已被重新写入
+func (c *adaptive) drain() {
+	c.evictLock.Lock()
+	var evicted = c.evicted
+	c.evicted = nil
+	c.evictLock.Unlock()
+	for _, e := range evicted {
+		if ak, ok := c.resident[e.key]; ok && ak.seq == e.seq {
+			ak.mask &^= 1 << uint(e.index)
+			if ak.mask == 0 {
+				delete(c.resident, e.key)
+			}
+		}
+	}
+}
+
+// 记录一次访问的命中情况，并判断是否需要切换策略
+func (c *adaptive) record(bits uint8) {
+	if c.accesses == len(c.window) {
+		var old = c.window[c.pos]
+		for i := range c.hits {
+			if old&(1<<uint(i)) != 0 {
+				c.hits[i]--
+			}
+		}
+	} else {
+		c.accesses++
+	}
+	for i := range c.hits {
+		if bits&(1<<uint(i)) != 0 {
+			c.hits[i]++
+		}
+	}
+	c.window[c.pos] = bits
+	c.pos = (c.pos + 1) % len(c.window)
+
+	// 切换后至少经过一个窗口再比较，避免频繁切换
+	c.settled++
+	if c.settled < len(c.window) {
+		return
+	}
+	var mode, best = c.mode, c.mode
+	for i := range c.hits {
+		if c.hits[i] > c.hits[best] {
+			best = i
+		}
+	}
+	if best != mode && c.hits[best]-c.hits[mode] > len(c.window)/adaptiveSwitchMargin {
+		c.switchTo(best)
+	}
+}
+
+// 切换当前策略，将元素值迁移至新缓存，旧缓存转为影子缓存
+func (c *adaptive) switchTo(mode int) {
+	c.drain()
+	var oldBit, newBit = uint8(1) << uint(c.mode), uint8(1) << uint(mode)
+	var from, to = c.caches[c.mode].(itemPeeker), c.caches[mode].(itemPeeker)
+	c.mode = mode
+	c.settled = 0
+	c.switches++
+
+	for key, ak := range c.resident {
+		var it item
+		var held = ak.mask&oldBit != 0
+		if held {
+			// 淘汰回调尚未同步时，元素可能已从旧缓存移除
+			if it, held = from.peekItem(key); held {
+				from.replaceItem(key, item{value: adaptiveShadow(ak.seq)})
+			} else {
+				ak.mask &^= oldBit
+			}
+		}
+		if ak.mask&newBit != 0 {
+			if !held {
+				// 已从旧缓存淘汰并执行过回调，新缓存不再保留
+				c.caches[mode].Remove(key)
+				ak.mask &^= newBit
+			} else if !to.replaceItem(key, it) {
+				// LRU-K、2Q等策略写入后不一定驻留
+				ak.mask &^= newBit
+			}
+		}
+		if held && ak.mask&newBit == 0 {
+			// 仅存在于旧缓存，对外表现为被淘汰
+			c.callEvict(key, it.value.(adaptiveValue).value)
+		}
+		if ak.mask == 0 {
+			delete(c.resident, key)
+		}
+	}
+}
+
+func (c *adaptive) callEvict(key, value interface{}) {
+	if c.onEvict != nil {
+		_ = c.goroutinePool.Submit(func() {
+			c.onEvict(key, value)
+		})
+	}
+}
+
+func (c *adaptive) Get(key interface{}) (interface{}, bool) {
+	c.drain()
+	var (
+		bits  uint8
+		value interface{}
+	)
+	for i, cache := range c.caches {
+		if v, ok := cache.Get(key); ok {
+			bits |= 1 << uint(i)
+			if i == c.mode {
+				value = v.(adaptiveValue).value
+			}
+		}
+	}
+	var ok = bits&(1<<uint(c.mode)) != 0
+	c.record(bits)
+	return value, ok
+}
+
+func (c *adaptive) Put(key, value interface{}) bool {
+	return c.PutWithExpire(key, value, NoExpiration)
+}
+
+// 不存在则添加，存在则更新；return 当前策略的缓存是否淘汰元素
+func (c *adaptive) PutWithExpire(key interface{}, value interface{}, lifeSpan time.Duration) bool {
+	c.drain()
+	var ak, ok = c.resident[key]
+	if !ok {
+		// 不满足准入策略则不写入
+		if !admit(c.admission, key) {
+			return false
+		}
+		ak = &adaptiveKey{}
+		c.resident[key] = ak
+	}
+	c.seq++
+	ak.seq = c.seq
+	ak.mask = 1<<uint(len(c.caches)) - 1
+
+	var evict bool
+	for i, cache := range c.caches {
+		if i == c.mode {
+			evict = cache.PutWithExpire(key, adaptiveValue{value: value, seq: c.seq}, lifeSpan)
+		} else {
+			// 影子缓存只用于统计命中，不保存元素值，也不会过期
+			cache.PutWithExpire(key, adaptiveShadow(c.seq), NoExpiration)
+		}
+	}
+	return evict
+}
+
+// 只读查询当前策略的缓存，包括已过期但未回收的元素，不视为访问，不计入命中统计
+func (c *adaptive) peek(key interface{}) (*item, bool) {
+	var it, ok = c.caches[c.mode].(itemPeeker).peekItem(key)
+	if !ok {
+		return nil, false
+	}
+	it.value = it.value.(adaptiveValue).value
+	return &it, true
+}
+
+func (c *adaptive) Remove(key interface{}) bool {
+	c.drain()
+	var ok bool
+	for i, cache := range c.caches {
+		if cache.Remove(key) && i == c.mode {
+			ok = true
+		}
+	}
+	delete(c.resident, key)
+	return ok
+}
+
+// 影子缓存不会过期，只需回收当前策略的缓存
+func (c *adaptive) DeleteExpired() {
+	c.caches[c.mode].DeleteExpired()
+	c.drain()
+}
+
+func (c *adaptive) Len() int {
+	return c.caches[c.mode].Len()
+}
+
+func (c *adaptive) Clear() {
+	for _, cache := range c.caches {
+		cache.Clear()
+	}
+	c.resident = make(map[interface{}]*adaptiveKey)
+	c.drain()
+}
+
+func (c *adaptive) Stats() AdaptiveStats {
+	return AdaptiveStats{
+		Mode:       c.types[c.mode],
+		Candidates: append([]CacheType(nil), c.types...),
+		Hits:       append([]int(nil), c.hits...),
+		Window:     len(c.window),
+		Accesses:   c.accesses,
+		Switches:   c.switches,
+	}
+}
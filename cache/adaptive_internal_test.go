@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdaptive_SwitchPeek(t *testing.T) {
+	var c, err = newAdaptive(&Opt{Capacity: 10, AdaptiveTypes: []CacheType{LRU, LFU}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Put("a", 1)
+	c.Put("b", 2)
+	var lfu = c.caches[1].(*LFUCache).lfu
+	var freq = func(key string) int {
+		return lfu.cache[key].Value.(*entryWithFreq).freq
+	}
+	var a, b = freq("a"), freq("b")
+
+	// 切换时确认驻留情况不应视为访问
+	c.switchTo(1)
+	c.switchTo(0)
+	if freq("a") != a || freq("b") != b {
+		t.Fatalf("freq = %d, %d after switch, want %d, %d", freq("a"), freq("b"), a, b)
+	}
+	if c.switches != 2 {
+		t.Fatalf("switches = %d, want 2", c.switches)
+	}
+}
+
+func TestAdaptive_Shadow(t *testing.T) {
+	var c, err = newAdaptive(&Opt{Capacity: 10, DefaultExpiration: time.Minute, AdaptiveTypes: []CacheType{LRU, LFU}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := c.caches[1].(*LFUCache).Interval(); d != 0 {
+		t.Fatalf("shadow watchdog interval = %v, want 0", d)
+	}
+	c.Put("a", 1)
+	c.PutWithExpire("b", 2, time.Hour)
+	var check = func(active, shadow int) {
+		t.Helper()
+		var it, ok = c.caches[active].(itemPeeker).peekItem("a")
+		if !ok || it.value.(adaptiveValue).value != 1 || it.expiration != 0 {
+			t.Fatalf("active a = %+v, %v, want 1 without expiration", it, ok)
+		}
+		if it, ok = c.caches[active].(itemPeeker).peekItem("b"); !ok || it.expiration == 0 {
+			t.Fatalf("active b = %+v, %v, want expiration", it, ok)
+		}
+		for _, key := range []string{"a", "b"} {
+			it, ok = c.caches[shadow].(itemPeeker).peekItem(key)
+			if _, isShadow := it.value.(adaptiveShadow); !ok || !isShadow || it.expiration != 0 {
+				t.Fatalf("shadow %s = %+v, %v, want key only", key, it, ok)
+			}
+		}
+	}
+	check(0, 1)
+	// 切换后元素值迁移至新缓存，旧缓存只保留key
+	c.switchTo(1)
+	check(1, 0)
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) = %v, %v after switch, want 1", v, ok)
+	}
+}
@@ -0,0 +1,72 @@
+package cache_test
+
+import (
+	"testing"
+
+	"github.com/1005281342/basic_component/cache"
+)
+
+func TestAdaptive_Switch(t *testing.T) {
+	var c, err = cache.NewAdaptiveCache(&cache.Opt{Capacity: 100, AdaptiveWindow: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var access = func(key int) {
+		if _, ok := c.Get(key); !ok {
+			c.Put(key, key)
+		}
+	}
+
+	// 热点key夹杂扫描，LFU命中更多
+	for i := 0; i < 150; i++ {
+		access(i % 50)
+	}
+	var scan = 1000
+	for i := 0; i < 5000; i++ {
+		access(i % 50)
+		access(scan)
+		access(scan + 1)
+		scan += 2
+	}
+	var stats = c.Stats()
+	if stats.Mode != cache.LFU || stats.Switches != 1 {
+		t.Fatalf("stats = %+v, want LFU after 1 switch", stats)
+	}
+	// 切换后热点key仍然保留
+	for i := 0; i < 50; i++ {
+		if v, ok := c.Get(i); !ok || v != i {
+			t.Fatalf("Get(%d) = %v, %v after switch", i, v, ok)
+		}
+	}
+
+	// 工作集迁移，旧热点频次过高，LRU命中更多
+	for i := 0; i < 5000; i++ {
+		access(10000 + i%80)
+	}
+	stats = c.Stats()
+	if stats.Mode != cache.LRU || stats.Switches != 2 {
+		t.Fatalf("stats = %+v, want LRU after 2 switches", stats)
+	}
+	if c.Len() > 100 {
+		t.Fatalf("Len() = %d, want <= 100", c.Len())
+	}
+}
+
+func TestAdaptive_Candidates(t *testing.T) {
+	if _, err := cache.NewAdaptiveCache(&cache.Opt{Capacity: 10, AdaptiveTypes: []cache.CacheType{cache.LRU}}); err == nil {
+		t.Fatal("want error for a single candidate")
+	}
+	if _, err := cache.NewAdaptiveCache(&cache.Opt{Capacity: 10, AdaptiveTypes: []cache.CacheType{cache.LRU, cache.Simple}}); err == nil {
+		t.Fatal("want error for an unbounded candidate")
+	}
+	if _, err := cache.NewAdaptiveCache(&cache.Opt{Capacity: 10, AdaptiveTypes: []cache.CacheType{cache.LRU, cache.Bytes}}); err == nil {
+		t.Fatal("want error for a bytes candidate")
+	}
+	var c, err = cache.NewAdaptiveCache(&cache.Opt{Capacity: 10, AdaptiveTypes: []cache.CacheType{cache.ClockPro, cache.LIRS, cache.S3FIFO}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats := c.Stats(); stats.Mode != cache.ClockPro || len(stats.Hits) != 3 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
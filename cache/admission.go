@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/1005281342/basic_component/bloom"
+)
+
+// AdmissionPolicy 准入策略，新元素写入缓存前进行检查，拒绝的元素不会写入缓存
+// 已存在元素的更新不受准入策略影响
+type AdmissionPolicy interface {
+	// Admit 是否允许key写入缓存
+	Admit(key interface{}) bool
+}
+
+// 未设置准入策略时全部准入
+func admit(p AdmissionPolicy, key interface{}) bool {
+	return p == nil || p.Admit(key)
+}
+
+const (
+	DefaultDoorkeeperWindow = 100000
+	DefaultDoorkeeperFPRate = 0.01
+)
+
+// Doorkeeper 基于布隆过滤器的准入策略，只准入在最近窗口内出现过的key，过滤只访问一次的元素
+// 使用两个布隆过滤器轮转：当前窗口记录的key数达到window后，当前过滤器成为上一窗口，新建当前过滤器
+type Doorkeeper struct {
+	window   int           // 窗口内记录的key数
+	count    int           // 当前窗口已记录的key数
+	current  *bloom.Filter // 当前窗口
+	previous *bloom.Filter // 上一窗口
+	lock     sync.Mutex
+}
+
+// NewDoorkeeper window为每个窗口记录的key数，fpRate为布隆过滤器误判率
+func NewDoorkeeper(window int, fpRate float64) *Doorkeeper {
+	if window <= 0 {
+		window = DefaultDoorkeeperWindow
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		fpRate = DefaultDoorkeeperFPRate
+	}
+	return &Doorkeeper{
+		window:   window,
+		current:  bloom.NewWithEstimates(uint64(window), fpRate),
+		previous: bloom.NewWithEstimates(uint64(window), fpRate),
+	}
+}
+
+func (d *Doorkeeper) Admit(key interface{}) bool {
+	var h1, h2 = hashKey(key)
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.current.TestHash(h1, h2) || d.previous.TestHash(h1, h2) {
+		return true
+	}
+
+	// 首次出现，记录后拒绝
+	d.current.AddHash(h1, h2)
+	d.count++
+	if d.count >= d.window {
+		d.previous, d.current = d.current, d.previous
+		d.current.Clear()
+		d.count = 0
+	}
+	return false
+}
+
+// ProbabilisticAdmission 以固定概率准入新元素
+type ProbabilisticAdmission struct {
+	probability float64
+	rand        *rand.Rand
+	lock        sync.Mutex
+}
+
+// NewProbabilisticAdmission probability为准入概率 [0, 1]
+func NewProbabilisticAdmission(probability float64) *ProbabilisticAdmission {
+	return &ProbabilisticAdmission{
+		probability: probability,
+		rand:        rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (p *ProbabilisticAdmission) Admit(key interface{}) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.rand.Float64() < p.probability
+}
+
+// 计算key的两个哈希值
+func hashKey(key interface{}) (uint64, uint64) {
+	var h = fnv.New64a()
+	switch k := key.(type) {
+	case string:
+		_, _ = h.Write([]byte(k))
+	case []byte:
+		_, _ = h.Write(k)
+	case int:
+		var buf [8]byte
+		binary.LittleEndian.PutUint64(buf[:], uint64(k))
+		_, _ = h.Write(buf[:])
+	case int64:
+		var buf [8]byte
+		binary.LittleEndian.PutUint64(buf[:], uint64(k))
+		_, _ = h.Write(buf[:])
+	case uint64:
+		var buf [8]byte
+		binary.LittleEndian.PutUint64(buf[:], k)
+		_, _ = h.Write(buf[:])
+	default:
+		_, _ = fmt.Fprintf(h, "%T:%v", key, key)
+	}
+	var h1 = h.Sum64()
+	// 由h1派生第二个哈希值，保证为奇数
+	var h2 = (h1^(h1>>31))*0x9E3779B97F4A7C15 | 1
+	return h1, h2
+}
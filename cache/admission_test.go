@@ -0,0 +1,54 @@
+package cache_test
+
+import (
+	"testing"
+
+	"github.com/1005281342/basic_component/cache"
+	"github.com/1005281342/basic_component/cache/cachetest"
+)
+
+func TestDoorkeeper(t *testing.T) {
+	var d = cache.NewDoorkeeper(4, 0.01)
+	if d.Admit("a") {
+		t.Fatal("first access admitted")
+	}
+	if !d.Admit("a") {
+		t.Fatal("second access rejected")
+	}
+
+	// 轮转两个窗口后遗忘
+	for i := 0; i < 8; i++ {
+		d.Admit(i)
+	}
+	if d.Admit("a") {
+		t.Fatal("key remembered after two windows")
+	}
+}
+
+func TestProbabilisticAdmission(t *testing.T) {
+	var never, always = cache.NewProbabilisticAdmission(0), cache.NewProbabilisticAdmission(1)
+	for i := 0; i < 100; i++ {
+		if never.Admit(i) {
+			t.Fatal("admitted with probability 0")
+		}
+		if !always.Admit(i) {
+			t.Fatal("rejected with probability 1")
+		}
+	}
+}
+
+func TestDoorkeeperConformance(t *testing.T) {
+	for _, ct := range []cache.CacheType{cache.Simple, cache.LRU, cache.LFU, cache.LRUmq} {
+		var ct = ct
+		cachetest.Run(t, cachetest.Policy{
+			Name: ct.String() + "+Doorkeeper",
+			New: func(opt *cache.Opt) (cache.ExpireCache, error) {
+				opt.Admission = cache.NewDoorkeeper(0, 0)
+				return cache.NewCache(ct, opt)
+			},
+			Unbounded:      ct == cache.Simple,
+			DeferredExpire: ct == cache.Simple,
+			PutsToAdmit:    2,
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// 未加锁的基本操作，由各缓存的内部实现提供
+type unlockedOp interface {
+	PutWithExpire(interface{}, interface{}, time.Duration) bool
+	Remove(interface{}) bool
+	// 只读查询，不视为访问，不回收过期元素
+	peek(interface{}) (*item, bool)
+	now() int64
+}
+
+// 内置缓存通过 atomicOp 实现，供包装缓存查询被覆盖的元素或原地替换元素
+type itemPeeker interface {
+	peekItem(key interface{}) (item, bool)
+	replaceItem(key interface{}, it item) bool
+}
+
+// ComputeFunc 根据当前值计算新值，exists表示当前值是否存在；keep为false时移除该元素
+type ComputeFunc func(old interface{}, exists bool) (value interface{}, keep bool)
+
+/*
+原子操作：
+1. 持有缓存锁组合查询与写入，避免先Get再Put之间被其他协程修改；
+2. 查询不视为访问，不调整淘汰顺序与访问频率；已过期的元素视为不存在，查询时回收并触发淘汰回调；
+3. 不带过期时间的方法保留元素原有的过期时间，新写入的元素永不过期；
+4. 结果以写入后元素是否驻留为准，LRU-K、2Q等策略首次写入只记录访问历史，不视为写入。
+*/
+type atomicOp struct {
+	locker   sync.Locker                   // 缓存锁
+	lockerOf func(interface{}) sync.Locker // 分片缓存按key获取锁，非nil时优先使用
+	op       unlockedOp
+}
+
+func (a atomicOp) lock(key interface{}) sync.Locker {
+	if a.lockerOf != nil {
+		return a.lockerOf(key)
+	}
+	return a.locker
+}
+
+// 持有缓存锁查询元素的副本，包括已过期但未回收的元素，不视为访问
+func (a atomicOp) peekItem(key interface{}) (item, bool) {
+	var l = a.lock(key)
+	l.Lock()
+	defer l.Unlock()
+	var it, ok = a.op.peek(key)
+	if !ok {
+		return item{}, false
+	}
+	return *it, true
+}
+
+// 持有缓存锁原地替换元素的值与过期时间，不视为访问，不调整淘汰顺序；return 元素是否存在
+func (a atomicOp) replaceItem(key interface{}, it item) bool {
+	var l = a.lock(key)
+	l.Lock()
+	defer l.Unlock()
+	var cur, ok = a.op.peek(key)
+	if ok {
+		*cur = it
+	}
+	return ok
+}
+
+// 查询元素的当前值，过期元素移除后视为不存在
+func (a atomicOp) lookup(key interface{}) (interface{}, bool) {
+	var it, ok = a.op.peek(key)
+	if !ok {
+		return nil, false
+	}
+	if it.expiredAt(a.op.now()) {
+		a.op.Remove(key)
+		return nil, false
+	}
+	return it.value, true
+}
+
+// 写入元素，return 写入后元素是否驻留
+func (a atomicOp) store(key, value interface{}, lifeSpan time.Duration) bool {
+	a.op.PutWithExpire(key, value, lifeSpan)
+	var _, ok = a.lookup(key)
+	return ok
+}
+
+// PutIfAbsent 不存在时添加，return 是否添加
+func (a atomicOp) PutIfAbsent(key, value interface{}) bool {
+	return a.PutIfAbsentWithExpire(key, value, NoExpiration)
+}
+
+func (a atomicOp) PutIfAbsentWithExpire(key, value interface{}, lifeSpan time.Duration) bool {
+	var l = a.lock(key)
+	l.Lock()
+	defer l.Unlock()
+	if _, ok := a.lookup(key); ok {
+		return false
+	}
+	return a.store(key, value, lifeSpan)
+}
+
+// Replace 存在时替换，return 是否替换
+func (a atomicOp) Replace(key, value interface{}) bool {
+	return a.ReplaceWithExpire(key, value, keepExpiration)
+}
+
+func (a atomicOp) ReplaceWithExpire(key, value interface{}, lifeSpan time.Duration) bool {
+	var l = a.lock(key)
+	l.Lock()
+	defer l.Unlock()
+	if _, ok := a.lookup(key); !ok {
+		return false
+	}
+	return a.store(key, value, lifeSpan)
+}
+
+// CompareAndSwap 当前值等于old时替换为new，return 是否替换
+// 元素值需要可比较，否则会panic
+func (a atomicOp) CompareAndSwap(key, old, new interface{}) bool {
+	return a.CompareAndSwapWithExpire(key, old, new, keepExpiration)
+}
+
+func (a atomicOp) CompareAndSwapWithExpire(key, old, new interface{}, lifeSpan time.Duration) bool {
+	var l = a.lock(key)
+	l.Lock()
+	defer l.Unlock()
+	if value, ok := a.lookup(key); !ok || value != old {
+		return false
+	}
+	return a.store(key, new, lifeSpan)
+}
+
+// Compute 根据当前值计算新值并写入，return 计算后的值及其是否存在
+// fn 在缓存锁内执行，不能再访问该缓存
+func (a atomicOp) Compute(key interface{}, fn ComputeFunc) (interface{}, bool) {
+	return a.ComputeWithExpire(key, fn, keepExpiration)
+}
+
+func (a atomicOp) ComputeWithExpire(key interface{}, fn ComputeFunc, lifeSpan time.Duration) (interface{}, bool) {
+	var l = a.lock(key)
+	l.Lock()
+	defer l.Unlock()
+	var old, exists = a.lookup(key)
+	var value, keep = fn(old, exists)
+	if !keep {
+		if exists {
+			a.op.Remove(key)
+		}
+		return nil, false
+	}
+	if !a.store(key, value, lifeSpan) {
+		return nil, false
+	}
+	return value, true
+}
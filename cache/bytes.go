@@ -0,0 +1,407 @@
+package cache
+
+import (
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+/*
+BytesCache：
+1. 只支持string类型的key与[]byte类型的值（也接受string类型的值），适合元素数量巨大的场景；
+2. 元素按key的哈希值分段，每段预分配一块环形字节数组，元素头、key、值依次写入数组尾部；
+3. 索引为 map[uint64]int64（哈希值 -> 元素偏移），不含指针，GC无需扫描；哈希冲突时后写入的元素覆盖先写入的；
+4. 空间不足时从数组头部回收：已删除或过期的元素直接回收，被访问过的元素清除访问位后搬到尾部，否则淘汰，近似LRU；
+5. 更新长度不变的值时原地覆盖，否则标记旧元素删除并追加新元素，删除元素占用的空间在头部经过时回收；
+6. Get 返回值的副本，淘汰回调的值同样为副本。
+*/
+
+const (
+	// 默认内存容量
+	DefaultBytesArenaSize = 32 << 20
+	// 默认分段数
+	DefaultBytesSegments = 256
+	// 每段最小字节数
+	minBytesSegmentSize = 64 << 10
+
+	// 元素头：过期时间(8) key长度(2) 值长度(4) 标志位(1) 保留(1)
+	bytesHeaderSize = 16
+	// key最大长度
+	maxBytesKeyLen = 1<<16 - 1
+
+	bytesFlagDeleted  = 1 << 0 // 已删除
+	bytesFlagAccessed = 1 << 1 // 写入或搬移后被访问过
+)
+
+type BytesCache struct {
+	segments []*bytesSegment
+	mask     uint64
+	onEvict  EvictCallback // 淘汰元素时执行的回调
+	*expire                // 过期属性
+}
+
+func NewBytesCache(opt *Opt) (*BytesCache, error) {
+	var size = opt.BytesArenaSize
+	if size <= 0 {
+		size = DefaultBytesArenaSize
+	}
+	var n = opt.BytesSegments
+	if n <= 0 {
+		n = DefaultBytesSegments
+	}
+	// 分段数向下取整为2的幂，每段不小于minBytesSegmentSize
+	for n&(n-1) != 0 {
+		n &= n - 1
+	}
+	for n > 1 && size/n < minBytesSegmentSize {
+		n >>= 1
+	}
+	if size/n < bytesHeaderSize {
+		return nil, ErrSize
+	}
+	var bc = &BytesCache{
+		segments: make([]*bytesSegment, n),
+		mask:     uint64(n - 1),
+		onEvict:  opt.Callback,
+		expire:   newExpire(opt),
+	}
+	for i := range bc.segments {
+		bc.segments[i] = &bytesSegment{
+			buf:   make([]byte, size/n),
+			index: make(map[uint64]int64),
+			cache: bc,
+		}
+	}
+	startWatchdog(bc.expire, bc)
+	return bc, nil
+}
+
+// PutBytes 添加元素并设置存活时长，元素大小超过分段容量或key过长时返回false
+func (bc *BytesCache) PutBytes(key string, value []byte, lifeSpan time.Duration) bool {
+	if len(key) > maxBytesKeyLen {
+		return false
+	}
+	var expiration = bc.absoluteTime(lifeSpan)
+	var h = shardHash(key)
+	var sg = bc.segments[h&bc.mask]
+	sg.lock.Lock()
+	defer sg.lock.Unlock()
+	return sg.put(h, key, value, expiration, bc.now())
+}
+
+// GetBytes 查询元素，返回值的副本
+func (bc *BytesCache) GetBytes(key string) ([]byte, bool) {
+	var h = shardHash(key)
+	var sg = bc.segments[h&bc.mask]
+	sg.lock.Lock()
+	defer sg.lock.Unlock()
+	return sg.get(h, key, bc.now())
+}
+
+// RemoveBytes 移除元素，若存在且未过期则返回true
+func (bc *BytesCache) RemoveBytes(key string) bool {
+	var h = shardHash(key)
+	var sg = bc.segments[h&bc.mask]
+	sg.lock.Lock()
+	defer sg.lock.Unlock()
+	return sg.remove(h, key, bc.now())
+}
+
+func (bc *BytesCache) Put(key, value interface{}) bool {
+	return bc.PutWithExpire(key, value, NoExpiration)
+}
+
+// PutWithExpire key必须为string，值必须为[]byte或string，否则不写入
+func (bc *BytesCache) PutWithExpire(key interface{}, value interface{}, lifeSpan time.Duration) bool {
+	var k, ok = key.(string)
+	if !ok {
+		return false
+	}
+	switch v := value.(type) {
+	case []byte:
+		return bc.PutBytes(k, v, lifeSpan)
+	case string:
+		return bc.PutBytes(k, []byte(v), lifeSpan)
+	default:
+		return false
+	}
+}
+
+func (bc *BytesCache) Get(key interface{}) (interface{}, bool) {
+	var k, ok = key.(string)
+	if !ok {
+		return nil, false
+	}
+	var v []byte
+	if v, ok = bc.GetBytes(k); !ok {
+		return nil, false
+	}
+	return v, true
+}
+
+func (bc *BytesCache) Remove(key interface{}) bool {
+	var k, ok = key.(string)
+	if !ok {
+		return false
+	}
+	return bc.RemoveBytes(k)
+}
+
+func (bc *BytesCache) Len() int {
+	var n int
+	for _, sg := range bc.segments {
+		sg.lock.Lock()
+		n += sg.count
+		sg.lock.Unlock()
+	}
+	return n
+}
+
+func (bc *BytesCache) Clear() {
+	for _, sg := range bc.segments {
+		sg.lock.Lock()
+		sg.clear()
+		sg.lock.Unlock()
+	}
+}
+
+// 回收过期的元素，逐段加锁
+func (bc *BytesCache) DeleteExpired() {
+	var now = bc.now()
+	for _, sg := range bc.segments {
+		sg.lock.Lock()
+		sg.deleteExpired(now)
+		sg.lock.Unlock()
+	}
+}
+
+// 异步执行淘汰回调
+func (bc *BytesCache) evict(key string, value []byte) {
+	_ = bc.goroutinePool.Submit(func() {
+		callEvict(bc.onEvict, key, value)
+	})
+}
+
+// 分段：环形字节数组，有效数据位于逻辑偏移 [head, tail)，物理偏移为逻辑偏移对数组长度取模
+type bytesSegment struct {
+	lock    sync.Mutex
+	buf     []byte
+	head    int64
+	tail    int64
+	index   map[uint64]int64 // 哈希值 -> 元素逻辑偏移
+	count   int              // 元素个数（含已过期未回收的）
+	scratch []byte           // 搬移元素时的临时空间
+	cache   *BytesCache
+}
+
+type bytesHeader struct {
+	expiration int64
+	keyLen     uint16
+	valLen     uint32
+	flags      uint8
+}
+
+func (h bytesHeader) size() int64 {
+	return bytesHeaderSize + int64(h.keyLen) + int64(h.valLen)
+}
+
+func (h bytesHeader) expiredAt(now int64) bool {
+	return h.expiration > 0 && now > h.expiration
+}
+
+// 按逻辑偏移读取，处理环形回绕
+func (sg *bytesSegment) readAt(off int64, p []byte) {
+	var i = int(off % int64(len(sg.buf)))
+	var n = copy(p, sg.buf[i:])
+	copy(p[n:], sg.buf)
+}
+
+// 按逻辑偏移写入，处理环形回绕
+func (sg *bytesSegment) writeAt(off int64, p []byte) {
+	var i = int(off % int64(len(sg.buf)))
+	var n = copy(sg.buf[i:], p)
+	copy(sg.buf, p[n:])
+}
+
+func (sg *bytesSegment) header(off int64) bytesHeader {
+	var b [bytesHeaderSize]byte
+	sg.readAt(off, b[:])
+	return bytesHeader{
+		expiration: int64(binary.LittleEndian.Uint64(b[0:8])),
+		keyLen:     binary.LittleEndian.Uint16(b[8:10]),
+		valLen:     binary.LittleEndian.Uint32(b[10:14]),
+		flags:      b[14],
+	}
+}
+
+func (sg *bytesSegment) writeHeader(off int64, h bytesHeader) {
+	var b [bytesHeaderSize]byte
+	binary.LittleEndian.PutUint64(b[0:8], uint64(h.expiration))
+	binary.LittleEndian.PutUint16(b[8:10], h.keyLen)
+	binary.LittleEndian.PutUint32(b[10:14], h.valLen)
+	b[14] = h.flags
+	sg.writeAt(off, b[:])
+}
+
+func (sg *bytesSegment) setFlags(off int64, flags uint8) {
+	sg.writeAt(off+14, []byte{flags})
+}
+
+// 读取元素的key到临时空间
+func (sg *bytesSegment) key(off int64, h bytesHeader) []byte {
+	var k = sg.grow(int(h.keyLen))
+	sg.readAt(off+bytesHeaderSize, k)
+	return k
+}
+
+func (sg *bytesSegment) value(off int64, h bytesHeader) []byte {
+	var v = make([]byte, h.valLen)
+	sg.readAt(off+bytesHeaderSize+int64(h.keyLen), v)
+	return v
+}
+
+func (sg *bytesSegment) grow(n int) []byte {
+	if cap(sg.scratch) < n {
+		sg.scratch = make([]byte, n)
+	}
+	return sg.scratch[:n]
+}
+
+// 查找key对应的元素，哈希冲突的元素视为不存在
+func (sg *bytesSegment) lookup(h uint64, key string) (int64, bytesHeader, bool) {
+	var off, ok = sg.index[h]
+	if !ok {
+		return 0, bytesHeader{}, false
+	}
+	var hd = sg.header(off)
+	if int(hd.keyLen) != len(key) || string(sg.key(off, hd)) != key {
+		return off, hd, false
+	}
+	return off, hd, true
+}
+
+// 标记元素删除并执行淘汰回调
+func (sg *bytesSegment) delete(h uint64, off int64, hd bytesHeader) {
+	if sg.cache.onEvict != nil {
+		sg.cache.evict(string(sg.key(off, hd)), sg.value(off, hd))
+	}
+	sg.drop(h, off, hd)
+}
+
+// 标记元素删除，不执行回调
+func (sg *bytesSegment) drop(h uint64, off int64, hd bytesHeader) {
+	sg.setFlags(off, hd.flags|bytesFlagDeleted)
+	delete(sg.index, h)
+	sg.count--
+}
+
+func (sg *bytesSegment) put(h uint64, key string, value []byte, expiration int64, now int64) bool {
+	var size = bytesHeaderSize + int64(len(key)) + int64(len(value))
+	if size > int64(len(sg.buf)) {
+		return false
+	}
+	if off, hd, ok := sg.lookup(h, key); ok && hd.valLen == uint32(len(value)) {
+		// 长度不变，原地覆盖
+		hd.expiration = expiration
+		sg.writeHeader(off, hd)
+		sg.writeAt(off+bytesHeaderSize+int64(hd.keyLen), value)
+		return true
+	} else if ok {
+		// 长度变化，标记旧值删除后追加
+		sg.drop(h, off, hd)
+	} else if _, exist := sg.index[h]; exist {
+		// 哈希冲突，淘汰已有元素
+		sg.delete(h, off, hd)
+	}
+
+	for sg.tail-sg.head+size > int64(len(sg.buf)) {
+		sg.evictHead(now)
+	}
+	var off = sg.tail
+	sg.writeHeader(off, bytesHeader{expiration: expiration, keyLen: uint16(len(key)), valLen: uint32(len(value))})
+	sg.writeAt(off+bytesHeaderSize, []byte(key))
+	sg.writeAt(off+bytesHeaderSize+int64(len(key)), value)
+	sg.index[h] = off
+	sg.tail += size
+	sg.count++
+	return true
+}
+
+// 回收头部元素：已删除或过期的直接回收，被访问过的清除访问位后搬到尾部，否则淘汰
+func (sg *bytesSegment) evictHead(now int64) {
+	var off = sg.head
+	var hd = sg.header(off)
+	var size = hd.size()
+	if hd.flags&bytesFlagDeleted == 0 {
+		var k = sg.key(off, hd)
+		var h = shardHash(string(k))
+		if hd.flags&bytesFlagAccessed != 0 && !hd.expiredAt(now) {
+			// 先读出整个元素，写入尾部时可能覆盖头部
+			var b = sg.grow(int(size))
+			sg.readAt(off, b)
+			b[14] &^= bytesFlagAccessed
+			sg.writeAt(sg.tail, b)
+			sg.index[h] = sg.tail
+			sg.tail += size
+		} else {
+			sg.delete(h, off, hd)
+		}
+	}
+	sg.head += size
+}
+
+func (sg *bytesSegment) get(h uint64, key string, now int64) ([]byte, bool) {
+	var off, hd, ok = sg.lookup(h, key)
+	if !ok {
+		return nil, false
+	}
+	if hd.expiredAt(now) {
+		sg.delete(h, off, hd)
+		return nil, false
+	}
+	if hd.flags&bytesFlagAccessed == 0 {
+		sg.setFlags(off, hd.flags|bytesFlagAccessed)
+	}
+	return sg.value(off, hd), true
+}
+
+func (sg *bytesSegment) remove(h uint64, key string, now int64) bool {
+	var off, hd, ok = sg.lookup(h, key)
+	if !ok {
+		return false
+	}
+	sg.delete(h, off, hd)
+	return !hd.expiredAt(now)
+}
+
+func (sg *bytesSegment) deleteExpired(now int64) {
+	for off := sg.head; off < sg.tail; {
+		var hd = sg.header(off)
+		if hd.flags&bytesFlagDeleted == 0 && hd.expiredAt(now) {
+			sg.delete(shardHash(string(sg.key(off, hd))), off, hd)
+		}
+		off += hd.size()
+	}
+	// 回收头部已删除的元素
+	for sg.head < sg.tail {
+		var hd = sg.header(sg.head)
+		if hd.flags&bytesFlagDeleted == 0 {
+			break
+		}
+		sg.head += hd.size()
+	}
+}
+
+func (sg *bytesSegment) clear() {
+	if sg.cache.onEvict != nil {
+		for off := sg.head; off < sg.tail; {
+			var hd = sg.header(off)
+			if hd.flags&bytesFlagDeleted == 0 {
+				callEvict(sg.cache.onEvict, string(sg.key(off, hd)), sg.value(off, hd))
+			}
+			off += hd.size()
+		}
+	}
+	sg.head, sg.tail, sg.count = 0, 0, 0
+	sg.index = make(map[uint64]int64)
+}
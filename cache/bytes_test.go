@@ -0,0 +1,142 @@
+package cache_test
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/1005281342/basic_component/cache"
+	"github.com/1005281342/basic_component/cache/cachetest"
+)
+
+func newBytesCache(t testing.TB, opt *cache.Opt) *cache.BytesCache {
+	opt.Interval = time.Hour
+	var c, err = cache.NewCache(cache.Bytes, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c.(*cache.BytesCache)
+}
+
+func TestBytesCache(t *testing.T) {
+	var clock = cachetest.NewFakeClock()
+	var c = newBytesCache(t, &cache.Opt{Clock: clock})
+
+	if c.Put(1, []byte("v")) || c.Put("k", 1) {
+		t.Fatalf("Put with unsupported type = true, want false")
+	}
+	c.PutBytes("a", []byte("1"), cache.NoExpiration)
+	c.PutBytes("b", []byte("2"), time.Second)
+	c.Put("c", "3")
+	for k, want := range map[string]string{"a": "1", "b": "2", "c": "3"} {
+		if v, ok := c.Get(k); !ok || string(v.([]byte)) != want {
+			t.Fatalf("Get(%s) = %v, %v, want %s", k, v, ok, want)
+		}
+	}
+
+	// 原地覆盖与追加更新
+	c.PutBytes("a", []byte("x"), cache.NoExpiration)
+	c.PutBytes("c", []byte("longer"), cache.NoExpiration)
+	if v, _ := c.GetBytes("a"); string(v) != "x" {
+		t.Fatalf("GetBytes(a) = %s, want x", v)
+	}
+	if v, _ := c.GetBytes("c"); string(v) != "longer" {
+		t.Fatalf("GetBytes(c) = %s, want longer", v)
+	}
+	if n := c.Len(); n != 3 {
+		t.Fatalf("Len() = %d, want 3", n)
+	}
+
+	clock.Advance(2 * time.Second)
+	if _, ok := c.GetBytes("b"); ok {
+		t.Fatalf("GetBytes(b) hit after expiration")
+	}
+	if !c.Remove("a") || c.Remove("a") {
+		t.Fatalf("Remove(a) twice, want true then false")
+	}
+	if n := c.Len(); n != 1 {
+		t.Fatalf("Len() = %d, want 1", n)
+	}
+	c.Clear()
+	if n := c.Len(); n != 0 {
+		t.Fatalf("Len() after Clear = %d, want 0", n)
+	}
+}
+
+// 随机写入不同长度的值，环形数组多次回绕后命中的值必须是最后一次写入的值
+func TestBytesCacheWrap(t *testing.T) {
+	var evicted int64
+	var c = newBytesCache(t, &cache.Opt{
+		BytesArenaSize: 64 << 10,
+		BytesSegments:  1,
+		Callback:       func(key, value interface{}) { atomic.AddInt64(&evicted, 1) },
+	})
+	var model = make(map[string][]byte)
+	var rnd = rand.New(rand.NewSource(1))
+	for i := 0; i < 20000; i++ {
+		var k = fmt.Sprintf("k%d", rnd.Intn(2000))
+		switch rnd.Intn(4) {
+		case 0:
+			c.RemoveBytes(k)
+			delete(model, k)
+		case 1:
+			if v, ok := c.GetBytes(k); ok && !bytes.Equal(v, model[k]) {
+				t.Fatalf("GetBytes(%s) = %q, want %q", k, v, model[k])
+			}
+		default:
+			var v = bytes.Repeat([]byte{byte(i)}, 1+rnd.Intn(200))
+			if !c.PutBytes(k, v, cache.NoExpiration) {
+				t.Fatalf("PutBytes(%s) = false", k)
+			}
+			model[k] = v
+		}
+	}
+	for k, want := range model {
+		if v, ok := c.GetBytes(k); ok && !bytes.Equal(v, want) {
+			t.Fatalf("GetBytes(%s) = %q, want %q", k, v, want)
+		}
+	}
+	if c.Len() >= len(model) {
+		t.Fatalf("Len() = %d, want eviction below %d", c.Len(), len(model))
+	}
+	time.Sleep(100 * time.Millisecond)
+	if atomic.LoadInt64(&evicted) == 0 {
+		t.Fatalf("no eviction callback")
+	}
+}
+
+// 被访问过的元素在空间不足时保留
+func TestBytesCacheSecondChance(t *testing.T) {
+	var c = newBytesCache(t, &cache.Opt{BytesArenaSize: 64 << 10, BytesSegments: 1})
+	var value = make([]byte, 1000)
+	c.PutBytes("hot", value, cache.NoExpiration)
+	for i := 0; i < 1000; i++ {
+		c.PutBytes(fmt.Sprintf("cold%d", i), value, cache.NoExpiration)
+		if _, ok := c.GetBytes("hot"); !ok {
+			t.Fatalf("hot evicted after %d puts", i)
+		}
+	}
+	if _, ok := c.GetBytes("cold0"); ok {
+		t.Fatalf("cold0 not evicted")
+	}
+}
+
+func BenchmarkBytesCache_GetParallel(b *testing.B) {
+	var c = newBytesCache(b, &cache.Opt{})
+	var keys = make([]string, 1024)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+		c.PutBytes(keys[i], []byte(keys[i]), cache.NoExpiration)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var i int
+		for pb.Next() {
+			c.GetBytes(keys[i&1023])
+			i++
+		}
+	})
+}
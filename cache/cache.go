@@ -6,19 +6,49 @@ import (
 	"time"
 )
 
-type cacheType int
+// CacheType 缓存淘汰策略类型
+type CacheType int
 
 const (
-	Simple cacheType = iota
+	Simple CacheType = iota
 	LRU
 	LFU
 	LRUk
 	LRU2q
 	LRUmq
 	ARC
+	LIRS
+	Clock
+	ClockPro
+	S3FIFO
+	Adaptive
+	Bytes
 )
 
-func NewCache(ct cacheType, opt *Opt) (ExpireCache, error) {
+var cacheTypeNames = map[CacheType]string{
+	Simple:   "Simple",
+	LRU:      "LRU",
+	LFU:      "LFU",
+	LRUk:     "LRU-K",
+	LRU2q:    "2Q",
+	LRUmq:    "MQ",
+	ARC:      "ARC",
+	LIRS:     "LIRS",
+	Clock:    "CLOCK",
+	ClockPro: "CLOCK-Pro",
+	S3FIFO:   "S3-FIFO",
+	Adaptive: "Adaptive",
+	Bytes:    "Bytes",
+}
+
+func (ct CacheType) String() string {
+	if name, ok := cacheTypeNames[ct]; ok {
+		return name
+	}
+	return fmt.Sprintf("CacheType(%d)", int(ct))
+}
+
+func NewCache(ct CacheType, opt *Opt) (ExpireCache, error) {
 	switch ct {
 	case Simple:
 		return NewSimpleCache(opt), nil
@@ -32,6 +62,18 @@ func NewCache(ct cacheType, opt *Opt) (ExpireCache, error) {
 		return NewLRU2QCache(opt)
 	case LRUmq:
 		return NewLRUMQCache(opt)
+	case LIRS:
+		return NewLIRSCache(opt)
+	case Clock:
+		return NewClockCache(opt)
+	case ClockPro:
+		return NewClockProCache(opt)
+	case S3FIFO:
+		return NewS3FIFOCache(opt)
+	case Adaptive:
+		return NewAdaptiveCache(opt)
+	case Bytes:
+		return NewBytesCache(opt)
 	default:
 		return nil, fmt.Errorf("not supported")
 	}
@@ -63,14 +105,78 @@ type ExpireCache interface {
 	PutWithExpire(k interface{}, v interface{}, lifeSpan time.Duration) bool // 添加元素并设置存活时长
 }
 
+// ExpireOptCache 支持按次设置过期选项的缓存
+type ExpireOptCache interface {
+	ExpireCache
+	// 添加元素并按过期选项设置存活时长
+	PutWithExpireOpt(k interface{}, v interface{}, lifeSpan time.Duration, o ExpireOpt) bool
+}
+
+// AtomicCache 支持原子读改写的缓存
+type AtomicCache interface {
+	ExpireCache
+	// 不存在时添加，return 是否添加
+	PutIfAbsent(k interface{}, v interface{}) bool
+	PutIfAbsentWithExpire(k interface{}, v interface{}, lifeSpan time.Duration) bool
+	// 存在时替换，return 是否替换
+	Replace(k interface{}, v interface{}) bool
+	ReplaceWithExpire(k interface{}, v interface{}, lifeSpan time.Duration) bool
+	// 当前值等于old时替换为new，return 是否替换
+	CompareAndSwap(k interface{}, old interface{}, new interface{}) bool
+	CompareAndSwapWithExpire(k interface{}, old interface{}, new interface{}, lifeSpan time.Duration) bool
+	// 根据当前值计算新值，return 计算后的值及其是否存在
+	Compute(k interface{}, fn ComputeFunc) (interface{}, bool)
+	ComputeWithExpire(k interface{}, fn ComputeFunc, lifeSpan time.Duration) (interface{}, bool)
+}
+
+// PinnedCache 支持固定元素的缓存，固定的元素不会被淘汰，但仍计入容量并会过期
+type PinnedCache interface {
+	ExpireCache
+	// 固定已存在的元素，元素不存在返回ErrNotFound，达到上限返回ErrPinLimit
+	Pin(k interface{}) error
+	// 取消固定，return 元素是否处于固定状态
+	Unpin(k interface{}) bool
+	// 添加元素并固定，不经过准入策略与访问历史
+	PutPinned(k interface{}, v interface{}, lifeSpan time.Duration) error
+}
+
+// InspectCache 支持查看内部元素的缓存，用于调试，不影响淘汰顺序
+type InspectCache interface {
+	ExpireCache
+	// 缓存容量，0表示无容量限制
+	Cap() int
+	// 遍历未过期的元素直到fn返回false，ttl为剩余存活时长，永不过期为NoExpiration
+	// fn 在缓存锁内执行，不能再访问该缓存
+	Range(fn func(k interface{}, v interface{}, ttl time.Duration) bool)
+	// 查询元素值与剩余存活时长，不存在或已过期返回false
+	Peek(k interface{}) (v interface{}, ttl time.Duration, ok bool)
+}
+
 type Opt struct {
-	Callback              EvictCallback // 淘汰回调
-	DefaultExpiration     time.Duration // 默认过期间隔
-	Interval              time.Duration // 回收间隔，限制最小为10s
-	Capacity              int           // 缓存容量
-	AntsPoolCapacity      int           // 协程池容量
-	AntsOptionList        []ants.Option // 可选操作扩展列表
-	LruK                  int           // LRU-K/LRU-MQ的频次k
-	LruKMinUpdateInterval time.Duration // LRU-K/LRU-MQ历史访问节点最小更新间隔，超过该间隔将频次置为0
-	LRUMQLevel            int           //	LRUMQLevel
+	Callback              EvictCallback   // 淘汰回调
+	DefaultExpiration     time.Duration   // 默认过期间隔
+	Interval              time.Duration   // 回收间隔，限制最小为10s
+	Capacity              int             // 缓存容量
+	AntsPoolCapacity      int             // 协程池容量
+	AntsOptionList        []ants.Option   // 可选操作扩展列表
+	LruK                  int             // LRU-K/LRU-MQ的频次k
+	LruKMinUpdateInterval time.Duration   // LRU-K/LRU-MQ历史访问节点最小更新间隔，超过该间隔将频次置为0
+	LRUMQLevel            int             //	LRUMQLevel
+	Clock                 TimeSource      // 时钟，默认使用系统时间
+	Admission             AdmissionPolicy // 准入策略，为nil时全部准入
+	LFUHalveEvery         int             // LFU每访问N次将所有元素频次减半，0表示不减半，不小于容量
+	LFULogFactor          int             // LFU对数计数因子，>0时频次以 1/((freq-1)*factor+1) 的概率自增
+	LFUDecayTime          time.Duration   // LFU频次衰减周期，元素每空闲一个周期频次减1，0表示不衰减
+	LFUTieBreak           LFUTieBreak     // LFU同频次元素的淘汰顺序，默认LRU
+	LIRSHIRRatio          float64         // LIRS中HIR驻留元素所占容量比例，默认1%
+	AdaptiveTypes         []CacheType     // 自适应缓存的候选策略，默认LRU与LFU
+	AdaptiveWindow        int             // 自适应缓存统计命中的滑动窗口大小，默认为容量的10倍
+	ExpireAfterAccess     bool            // 访问后按存活时长重新计算过期时间，支持Simple、LRU、LFU、LRU-K、2Q、MQ
+	ExpireJitter          float64         // 存活时长随机抖动比例，取值[0, 1)，避免批量写入的元素同时过期
+	PinLimit              int             // 固定元素个数上限，默认为容量的一半，不超过容量-1
+	SimpleShards          int             // SimpleCache分片数，向上取整为2的幂，默认32
+	BytesArenaSize        int             // BytesCache预分配的内存容量（字节），默认32MB，不使用Capacity
+	BytesSegments         int             // BytesCache分段数，向下取整为2的幂，默认256，每段不小于64KB
+
+	shared *expire // 非nil时复用其协程池且不启动看门狗，供自适应缓存的候选缓存使用
 }
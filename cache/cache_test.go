@@ -0,0 +1,48 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/1005281342/basic_component/cache"
+	"github.com/1005281342/basic_component/cache/cachetest"
+)
+
+func policies() []cachetest.Policy {
+	var newCache = func(ct cache.CacheType) cachetest.Factory {
+		return func(opt *cache.Opt) (cache.ExpireCache, error) {
+			return cache.NewCache(ct, opt)
+		}
+	}
+	return []cachetest.Policy{
+		{Name: "Simple", New: newCache(cache.Simple), Unbounded: true, DeferredExpire: true, Model: cachetest.NewMapModel},
+		{Name: "LRU", New: newCache(cache.LRU), Model: cachetest.NewLRUModel},
+		{Name: "LFU", New: newCache(cache.LFU), Model: cachetest.NewLFUModel},
+		{Name: "LFU+Aging", New: func(opt *cache.Opt) (cache.ExpireCache, error) {
+			opt.LFUHalveEvery = 100
+			opt.LFULogFactor = 1
+			opt.LFUDecayTime = time.Second
+			return cache.NewCache(cache.LFU, opt)
+		}},
+		{Name: "LRUk", New: newCache(cache.LRUk), PutsToAdmit: cache.DefaultLruK},
+		{Name: "LRU2q", New: newCache(cache.LRU2q), PutsToAdmit: 2},
+		{Name: "LRUmq", New: newCache(cache.LRUmq)},
+		{Name: "LIRS", New: newCache(cache.LIRS)},
+		{Name: "Clock", New: newCache(cache.Clock)},
+		{Name: "ClockPro", New: newCache(cache.ClockPro)},
+		{Name: "S3FIFO", New: newCache(cache.S3FIFO)},
+		{Name: "Adaptive", New: newCache(cache.Adaptive)},
+		{Name: "Ref+LRU", New: func(opt *cache.Opt) (cache.ExpireCache, error) {
+			return cache.NewRefCache(cache.LRU, opt)
+		}, UpdateEvicts: true, Model: cachetest.NewLRUModel},
+		{Name: "Negative+LRU", New: func(opt *cache.Opt) (cache.ExpireCache, error) {
+			return cache.NewNegativeCache(cache.LRU, opt, cache.NegativeOpt{})
+		}, Model: cachetest.NewLRUModel},
+	}
+}
+
+func TestConformance(t *testing.T) {
+	for _, p := range policies() {
+		cachetest.Run(t, p)
+	}
+}
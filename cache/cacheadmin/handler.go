@@ -0,0 +1,276 @@
+// Package cacheadmin 缓存调试与管理的 HTTP 接口
+//
+// 可挂载于任意 cache.ExpireCache，响应均为 JSON：
+//
+//	GET  /stats              策略名称、元素个数、容量与统计信息
+//	GET  /keys?limit=100     采样元素的key与剩余存活时长
+//	GET  /key?key=k&type=int 查询元素值与剩余存活时长，不影响淘汰顺序
+//	POST /remove?key=k       移除元素
+//	POST /clear              清空缓存
+//	POST /expire             回收过期的元素
+//
+// key 与 /keys 需要缓存实现 cache.InspectCache。挂载到子路径时配合 http.StripPrefix 使用：
+//
+//	mux.Handle("/debug/cache/", http.StripPrefix("/debug/cache", cacheadmin.NewHandler(c, nil)))
+package cacheadmin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/1005281342/basic_component/cache"
+)
+
+const (
+	// 默认采样key数
+	DefaultSampleSize = 100
+	// 最大采样key数
+	MaxSampleSize = 10000
+)
+
+// Opt 管理接口配置
+type Opt struct {
+	Name       string             // 策略名称，为空时使用缓存的类型名
+	Stats      func() interface{} // 统计信息，为nil时不返回
+	SampleSize int                // /keys 默认采样key数，默认100
+}
+
+type handler struct {
+	cache cache.ExpireCache
+	opt   Opt
+	mux   *http.ServeMux
+}
+
+// NewHandler 创建缓存c的管理接口，opt可以为nil
+func NewHandler(c cache.ExpireCache, opt *Opt) http.Handler {
+	var h = &handler{cache: c, mux: http.NewServeMux()}
+	if opt != nil {
+		h.opt = *opt
+	}
+	if h.opt.Name == "" {
+		h.opt.Name = fmt.Sprintf("%T", c)
+	}
+	if h.opt.SampleSize <= 0 {
+		h.opt.SampleSize = DefaultSampleSize
+	}
+	h.mux.HandleFunc("/stats", h.get(h.stats))
+	h.mux.HandleFunc("/keys", h.get(h.keys))
+	h.mux.HandleFunc("/key", h.get(h.key))
+	h.mux.HandleFunc("/remove", h.post(h.remove))
+	h.mux.HandleFunc("/clear", h.post(h.clear))
+	h.mux.HandleFunc("/expire", h.post(h.expire))
+	return h
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// Stats /stats 响应
+type Stats struct {
+	Name        string      `json:"name"`
+	Len         int         `json:"len"`
+	Capacity    int         `json:"capacity"` // 0表示无容量限制或未知
+	Inspectable bool        `json:"inspectable"`
+	Stats       interface{} `json:"stats,omitempty"`
+}
+
+// Entry 元素信息
+type Entry struct {
+	Key   string `json:"key"`
+	Value string `json:"value,omitempty"`
+	TTL   string `json:"ttl"` // 剩余存活时长，永不过期为never
+}
+
+// Keys /keys 响应
+type Keys struct {
+	Keys      []Entry `json:"keys"`
+	Truncated bool    `json:"truncated"` // 是否还有未返回的元素
+}
+
+// Lookup /key 响应
+type Lookup struct {
+	Found bool `json:"found"`
+	Entry
+}
+
+// Result 管理操作响应
+type Result struct {
+	OK      bool `json:"ok"`
+	Len     int  `json:"len"`               // 操作后的元素个数
+	Removed int  `json:"removed,omitempty"` // 移除或回收的元素个数
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type handlerFunc func(r *http.Request) (interface{}, int, error)
+
+func (h *handler) get(fn handlerFunc) http.HandlerFunc {
+	return h.method(http.MethodGet, fn)
+}
+
+func (h *handler) post(fn handlerFunc) http.HandlerFunc {
+	return h.method(http.MethodPost, fn)
+}
+
+func (h *handler) method(method string, fn handlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+			return
+		}
+		var resp, code, err = fn(r)
+		if err != nil {
+			writeJSON(w, code, errorResponse{Error: err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (h *handler) inspect() (cache.InspectCache, error) {
+	if ic, ok := h.cache.(cache.InspectCache); ok {
+		return ic, nil
+	}
+	return nil, fmt.Errorf("%s does not support inspection", h.opt.Name)
+}
+
+func (h *handler) stats(*http.Request) (interface{}, int, error) {
+	var s = Stats{Name: h.opt.Name, Len: h.cache.Len()}
+	if ic, ok := h.cache.(cache.InspectCache); ok {
+		s.Inspectable = true
+		s.Capacity = ic.Cap()
+	}
+	if h.opt.Stats != nil {
+		s.Stats = h.opt.Stats()
+	}
+	return s, 0, nil
+}
+
+func (h *handler) keys(r *http.Request) (interface{}, int, error) {
+	var ic, err = h.inspect()
+	if err != nil {
+		return nil, http.StatusNotImplemented, err
+	}
+	var limit = h.opt.SampleSize
+	if s := r.URL.Query().Get("limit"); s != "" {
+		if limit, err = strconv.Atoi(s); err != nil || limit <= 0 {
+			return nil, http.StatusBadRequest, fmt.Errorf("invalid limit %q", s)
+		}
+	}
+	if limit > MaxSampleSize {
+		limit = MaxSampleSize
+	}
+	var resp = Keys{Keys: make([]Entry, 0, limit)}
+	ic.Range(func(k, v interface{}, ttl time.Duration) bool {
+		if len(resp.Keys) == limit {
+			resp.Truncated = true
+			return false
+		}
+		resp.Keys = append(resp.Keys, Entry{Key: format(k), TTL: formatTTL(ttl)})
+		return true
+	})
+	return resp, 0, nil
+}
+
+func (h *handler) key(r *http.Request) (interface{}, int, error) {
+	var ic, err = h.inspect()
+	if err != nil {
+		return nil, http.StatusNotImplemented, err
+	}
+	var key interface{}
+	if key, err = parseKey(r); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	var resp = Lookup{Entry: Entry{Key: format(key)}}
+	if v, ttl, ok := ic.Peek(key); ok {
+		resp.Found = true
+		resp.Value = format(v)
+		resp.TTL = formatTTL(ttl)
+	}
+	return resp, 0, nil
+}
+
+func (h *handler) remove(r *http.Request) (interface{}, int, error) {
+	var key, err = parseKey(r)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	var resp = Result{OK: h.cache.Remove(key)}
+	if resp.OK {
+		resp.Removed = 1
+	}
+	resp.Len = h.cache.Len()
+	return resp, 0, nil
+}
+
+func (h *handler) clear(*http.Request) (interface{}, int, error) {
+	var n = h.cache.Len()
+	h.cache.Clear()
+	return Result{OK: true, Len: h.cache.Len(), Removed: n}, 0, nil
+}
+
+func (h *handler) expire(*http.Request) (interface{}, int, error) {
+	var n = h.cache.Len()
+	h.cache.DeleteExpired()
+	var resp = Result{OK: true, Len: h.cache.Len()}
+	if n > resp.Len {
+		resp.Removed = n - resp.Len
+	}
+	return resp, 0, nil
+}
+
+// 按type参数解析key，默认为string
+func parseKey(r *http.Request) (interface{}, error) {
+	var q = r.URL.Query()
+	var s = q.Get("key")
+	if s == "" {
+		return nil, fmt.Errorf("missing key")
+	}
+	var (
+		key interface{}
+		err error
+	)
+	switch t := q.Get("type"); t {
+	case "", "string":
+		key = s
+	case "int":
+		key, err = strconv.Atoi(s)
+	case "int64":
+		key, err = strconv.ParseInt(s, 10, 64)
+	case "uint64":
+		key, err = strconv.ParseUint(s, 10, 64)
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", t)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid key %q: %v", s, err)
+	}
+	return key, nil
+}
+
+func format(v interface{}) string {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+func formatTTL(ttl time.Duration) string {
+	if ttl == cache.NoExpiration {
+		return "never"
+	}
+	return ttl.String()
+}
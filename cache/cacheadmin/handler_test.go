@@ -0,0 +1,135 @@
+package cacheadmin_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/1005281342/basic_component/cache"
+	"github.com/1005281342/basic_component/cache/cacheadmin"
+	"github.com/1005281342/basic_component/cache/cachetest"
+)
+
+func do(t *testing.T, h http.Handler, method, target string, wantCode int, resp interface{}) {
+	t.Helper()
+	var w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+	if w.Code != wantCode {
+		t.Fatalf("%s %s = %d, want %d: %s", method, target, w.Code, wantCode, w.Body)
+	}
+	if resp != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), resp); err != nil {
+			t.Fatalf("%s %s: %v", method, target, err)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	var clock = cachetest.NewFakeClock()
+	var c, err = cache.NewCache(cache.LRU, &cache.Opt{Capacity: 10, Clock: clock, Interval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Put("a", 1)
+	c.PutWithExpire("b", 2, time.Minute)
+	c.PutWithExpire("c", 3, time.Second)
+	var h = cacheadmin.NewHandler(c, &cacheadmin.Opt{Name: "LRU", Stats: func() interface{} { return "ok" }})
+
+	var stats cacheadmin.Stats
+	do(t, h, http.MethodGet, "/stats", http.StatusOK, &stats)
+	if stats.Name != "LRU" || stats.Len != 3 || stats.Capacity != 10 || !stats.Inspectable || stats.Stats != "ok" {
+		t.Fatalf("stats = %+v", stats)
+	}
+
+	var keys cacheadmin.Keys
+	do(t, h, http.MethodGet, "/keys?limit=2", http.StatusOK, &keys)
+	if len(keys.Keys) != 2 || !keys.Truncated {
+		t.Fatalf("keys = %+v, want 2 keys truncated", keys)
+	}
+
+	// 查询不影响淘汰顺序
+	var lookup cacheadmin.Lookup
+	do(t, h, http.MethodGet, "/key?key=b", http.StatusOK, &lookup)
+	if !lookup.Found || lookup.Value != "2" || lookup.TTL != "1m0s" {
+		t.Fatalf("lookup(b) = %+v", lookup)
+	}
+	do(t, h, http.MethodGet, "/key?key=a", http.StatusOK, &lookup)
+	if !lookup.Found || lookup.TTL != "never" {
+		t.Fatalf("lookup(a) = %+v", lookup)
+	}
+	do(t, h, http.MethodGet, "/key?key=1&type=float", http.StatusBadRequest, nil)
+	do(t, h, http.MethodGet, "/key", http.StatusBadRequest, nil)
+
+	// 管理操作只接受POST
+	var result cacheadmin.Result
+	do(t, h, http.MethodGet, "/clear", http.StatusMethodNotAllowed, nil)
+	do(t, h, http.MethodPost, "/remove?key=a", http.StatusOK, &result)
+	if !result.OK || result.Len != 2 {
+		t.Fatalf("remove(a) = %+v", result)
+	}
+	clock.Advance(2 * time.Second)
+	do(t, h, http.MethodPost, "/expire", http.StatusOK, &result)
+	if result.Removed != 1 || result.Len != 1 {
+		t.Fatalf("expire = %+v", result)
+	}
+	do(t, h, http.MethodPost, "/clear", http.StatusOK, &result)
+	if result.Len != 0 {
+		t.Fatalf("clear = %+v", result)
+	}
+}
+
+type nopObserver struct{}
+
+func (nopObserver) OnGet(bool) {}
+func (nopObserver) OnPut()     {}
+func (nopObserver) OnEvict()   {}
+
+func TestHandlerInspectable(t *testing.T) {
+	for _, ct := range []cache.CacheType{cache.Simple, cache.LRU, cache.LFU, cache.LRUk, cache.LRU2q, cache.LRUmq,
+		cache.LIRS, cache.Clock, cache.ClockPro, cache.S3FIFO, cache.Adaptive, cache.Bytes} {
+		var c, err = cache.NewCache(ct, &cache.Opt{Capacity: 10, Interval: time.Hour})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ic, ok = c.(cache.InspectCache)
+		if !ok {
+			t.Fatalf("%s is not an InspectCache", ct)
+		}
+		var k, v interface{} = 1, 1
+		if ct == cache.Bytes {
+			k, v = "1", []byte("1")
+		}
+		for i := 0; i < 3; i++ {
+			c.Put(k, v)
+		}
+		if _, _, ok = ic.Peek(k); !ok {
+			t.Fatalf("%s Peek miss", ct)
+		}
+		var n int
+		ic.Range(func(interface{}, interface{}, time.Duration) bool { n++; return true })
+		if n != c.Len() {
+			t.Fatalf("%s Range = %d, want %d", ct, n, c.Len())
+		}
+	}
+
+	// 可观察缓存转发底层缓存的查看方法
+	var oc, _ = cache.NewObservedCache(cache.LRU, &cache.Opt{Capacity: 10, Interval: time.Hour}, nopObserver{})
+	oc.Put(1, 1)
+	var keys cacheadmin.Keys
+	do(t, cacheadmin.NewHandler(oc, nil), http.MethodGet, "/keys", http.StatusOK, &keys)
+	if len(keys.Keys) != 1 {
+		t.Fatalf("keys = %+v, want 1 key", keys)
+	}
+
+	// 未实现InspectCache的缓存不支持查询
+	var rc, _ = cache.NewRefCache(cache.LRU, &cache.Opt{Capacity: 10, Interval: time.Hour})
+	var h = cacheadmin.NewHandler(rc, nil)
+	do(t, h, http.MethodGet, "/keys", http.StatusNotImplemented, nil)
+	var stats cacheadmin.Stats
+	do(t, h, http.MethodGet, "/stats", http.StatusOK, &stats)
+	if stats.Inspectable || stats.Name != "*cache.RefCache" {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
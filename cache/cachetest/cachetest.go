@@ -0,0 +1,543 @@
+// Package cachetest 缓存一致性测试套件
+//
+// 对任意 cache.ExpireCache 实现校验 Put/Get/Remove/Len/Clear 语义、容量上限、
+// 过期时间、淘汰回调次数、并发安全、原子读改写，以及与参考模型的随机对比。
+// 第三方淘汰策略也可以通过 Run 复用该套件。
+package cachetest
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/1005281342/basic_component/cache"
+)
+
+// Factory 根据配置创建待测缓存
+type Factory func(opt *cache.Opt) (cache.ExpireCache, error)
+
+// Policy 待测淘汰策略的描述
+type Policy struct {
+	Name string  // 策略名称
+	New  Factory // 创建缓存
+
+	// 无容量限制，如Simple
+	Unbounded bool
+	// 元素写入缓存前需要Put的次数，如LRU-K为K、2Q为2；<=1表示首次Put即写入
+	PutsToAdmit int
+	// 查询不回收过期元素，统一由DeleteExpired回收，如Simple
+	DeferredExpire bool
+	// 覆盖视为淘汰旧值，对旧值执行回调，如RefCache
+	UpdateEvicts bool
+	// 参考模型，为nil时只校验通用语义：命中的值必须是最后一次Put的值
+	Model func(capacity int) Model
+}
+
+const (
+	testCapacity = 8
+	// 看门狗回收间隔，测试中由用例主动调用DeleteExpired
+	testInterval = time.Hour
+)
+
+// Run 对策略p执行全部一致性测试
+func Run(t *testing.T, p Policy) {
+	t.Run(p.Name+"/PutGet", func(t *testing.T) { testPutGet(t, p) })
+	t.Run(p.Name+"/Remove", func(t *testing.T) { testRemove(t, p) })
+	t.Run(p.Name+"/Len", func(t *testing.T) { testLen(t, p) })
+	t.Run(p.Name+"/Clear", func(t *testing.T) { testClear(t, p) })
+	t.Run(p.Name+"/Capacity", func(t *testing.T) { testCapacityBound(t, p) })
+	t.Run(p.Name+"/TTL", func(t *testing.T) { testTTL(t, p) })
+	t.Run(p.Name+"/Callback", func(t *testing.T) { testCallback(t, p) })
+	t.Run(p.Name+"/Concurrency", func(t *testing.T) { testConcurrency(t, p) })
+	t.Run(p.Name+"/Model", func(t *testing.T) { testModel(t, p) })
+	t.Run(p.Name+"/Atomic", func(t *testing.T) { testAtomic(t, p) })
+}
+
+func newCache(t *testing.T, p Policy, opt *cache.Opt) cache.ExpireCache {
+	t.Helper()
+	if opt.Capacity == 0 {
+		opt.Capacity = testCapacity
+	}
+	if opt.Interval == 0 {
+		opt.Interval = testInterval
+	}
+	if opt.Clock == nil {
+		opt.Clock = NewFakeClock()
+	}
+	var c, err = p.New(opt)
+	if err != nil {
+		t.Fatalf("create cache: %v", err)
+	}
+	return c
+}
+
+// Admit 写入元素，对需要多次访问才写入的策略重复Put
+func Admit(c cache.ExpireCache, p Policy, key, value interface{}, lifeSpan time.Duration) {
+	var n = p.PutsToAdmit
+	if n < 1 {
+		n = 1
+	}
+	for i := 0; i < n; i++ {
+		c.PutWithExpire(key, value, lifeSpan)
+	}
+}
+
+func mustGet(t *testing.T, c cache.ExpireCache, key, want interface{}) {
+	t.Helper()
+	var v, ok = c.Get(key)
+	if !ok {
+		t.Fatalf("Get(%v) miss, want %v", key, want)
+	}
+	if v != want {
+		t.Fatalf("Get(%v) = %v, want %v", key, v, want)
+	}
+}
+
+func mustMiss(t *testing.T, c cache.ExpireCache, key interface{}) {
+	t.Helper()
+	if v, ok := c.Get(key); ok {
+		t.Fatalf("Get(%v) = %v, want miss", key, v)
+	}
+}
+
+func mustLen(t *testing.T, c cache.ExpireCache, want int) {
+	t.Helper()
+	if n := c.Len(); n != want {
+		t.Fatalf("Len() = %d, want %d", n, want)
+	}
+}
+
+func testPutGet(t *testing.T, p Policy) {
+	var c = newCache(t, p, &cache.Opt{})
+	for i := 0; i < testCapacity/2; i++ {
+		Admit(c, p, i, fmt.Sprint("v", i), cache.NoExpiration)
+	}
+	for i := 0; i < testCapacity/2; i++ {
+		mustGet(t, c, i, fmt.Sprint("v", i))
+	}
+	mustMiss(t, c, testCapacity)
+
+	// 已存在的元素一次Put即更新
+	c.Put(0, "updated")
+	mustGet(t, c, 0, "updated")
+
+	// nil值与未命中可区分
+	Admit(c, p, "nil", nil, cache.NoExpiration)
+	mustGet(t, c, "nil", nil)
+}
+
+func testRemove(t *testing.T, p Policy) {
+	var c = newCache(t, p, &cache.Opt{})
+	Admit(c, p, 1, 1, cache.NoExpiration)
+	Admit(c, p, 2, 2, cache.NoExpiration)
+	if !c.Remove(1) {
+		t.Fatalf("Remove(1) = false, want true")
+	}
+	mustMiss(t, c, 1)
+	mustLen(t, c, 1)
+	if c.Remove(1) {
+		t.Fatalf("Remove(1) again = true, want false")
+	}
+	if c.Remove(3) {
+		t.Fatalf("Remove(3) = true, want false")
+	}
+	mustGet(t, c, 2, 2)
+
+	// 移除后可再次写入
+	Admit(c, p, 1, "again", cache.NoExpiration)
+	mustGet(t, c, 1, "again")
+	mustLen(t, c, 2)
+}
+
+func testLen(t *testing.T, p Policy) {
+	var c = newCache(t, p, &cache.Opt{})
+	mustLen(t, c, 0)
+	for i := 0; i < testCapacity/2; i++ {
+		Admit(c, p, i, i, cache.NoExpiration)
+		mustLen(t, c, i+1)
+	}
+	// 更新不改变元素个数
+	for i := 0; i < testCapacity/2; i++ {
+		c.Put(i, -i)
+	}
+	mustLen(t, c, testCapacity/2)
+}
+
+func testClear(t *testing.T, p Policy) {
+	var c = newCache(t, p, &cache.Opt{})
+	for i := 0; i < testCapacity/2; i++ {
+		Admit(c, p, i, i, cache.NoExpiration)
+	}
+	c.Clear()
+	mustLen(t, c, 0)
+	for i := 0; i < testCapacity/2; i++ {
+		mustMiss(t, c, i)
+	}
+	Admit(c, p, 1, 1, cache.NoExpiration)
+	mustGet(t, c, 1, 1)
+	mustLen(t, c, 1)
+}
+
+func testCapacityBound(t *testing.T, p Policy) {
+	if p.Unbounded {
+		t.Skip("unbounded cache")
+	}
+	var c = newCache(t, p, &cache.Opt{})
+	for i := 0; i < testCapacity*3; i++ {
+		Admit(c, p, i, i, cache.NoExpiration)
+		if n := c.Len(); n > testCapacity {
+			t.Fatalf("Len() = %d after %d puts, exceeds capacity %d", n, i+1, testCapacity)
+		}
+	}
+	mustLen(t, c, testCapacity)
+
+	// 未被淘汰的元素值正确
+	var hits int
+	for i := 0; i < testCapacity*3; i++ {
+		if v, ok := c.Get(i); ok {
+			hits++
+			if v != i {
+				t.Fatalf("Get(%d) = %v, want %d", i, v, i)
+			}
+		}
+	}
+	if hits != testCapacity {
+		t.Fatalf("hits = %d, want %d", hits, testCapacity)
+	}
+}
+
+func testTTL(t *testing.T, p Policy) {
+	var clock = NewFakeClock()
+	var c = newCache(t, p, &cache.Opt{Clock: clock, DefaultExpiration: time.Minute})
+
+	Admit(c, p, "short", 1, time.Second)
+	Admit(c, p, "forever", 2, cache.NoExpiration)
+	Admit(c, p, "default", 3, cache.DefaultExpirationThreshold)
+	mustGet(t, c, "short", 1)
+	mustGet(t, c, "forever", 2)
+	mustGet(t, c, "default", 3)
+
+	clock.Advance(2 * time.Second)
+	mustMiss(t, c, "short")
+	mustGet(t, c, "forever", 2)
+	mustGet(t, c, "default", 3)
+
+	clock.Advance(time.Minute)
+	mustMiss(t, c, "default")
+	mustGet(t, c, "forever", 2)
+	if p.DeferredExpire {
+		mustLen(t, c, 3)
+		c.DeleteExpired()
+	}
+	mustLen(t, c, 1)
+
+	// 过期但未被回收的元素由DeleteExpired回收
+	Admit(c, p, "a", 1, time.Second)
+	Admit(c, p, "b", 1, time.Second)
+	clock.Advance(2 * time.Second)
+	c.DeleteExpired()
+	mustLen(t, c, 1)
+
+	// 移除已过期的元素返回false
+	Admit(c, p, "c", 1, time.Second)
+	clock.Advance(2 * time.Second)
+	if c.Remove("c") {
+		t.Fatalf("Remove(expired) = true, want false")
+	}
+	mustLen(t, c, 1)
+
+	// 更新过期时间
+	Admit(c, p, "d", 1, time.Second)
+	c.PutWithExpire("d", 2, cache.NoExpiration)
+	clock.Advance(2 * time.Second)
+	mustGet(t, c, "d", 2)
+}
+
+// 实现了 cache.AtomicCache 的缓存校验原子读改写语义
+func testAtomic(t *testing.T, p Policy) {
+	var clock = NewFakeClock()
+	var ec = newCache(t, p, &cache.Opt{Clock: clock})
+	var c, ok = ec.(cache.AtomicCache)
+	if !ok {
+		t.Skip("not an AtomicCache")
+	}
+
+	Admit(c, p, "a", 1, cache.NoExpiration)
+	if c.PutIfAbsent("a", 2) {
+		t.Fatalf("PutIfAbsent(existing) = true, want false")
+	}
+	mustGet(t, c, "a", 1)
+	// 需要多次写入的策略在驻留前返回false
+	for i := 1; i < p.PutsToAdmit; i++ {
+		if c.PutIfAbsent("b", 1) {
+			t.Fatalf("PutIfAbsent(missing) = true after %d puts, want false before admitted", i)
+		}
+	}
+	if !c.PutIfAbsent("b", 1) {
+		t.Fatalf("PutIfAbsent(missing) = false, want true")
+	}
+
+	if !c.Replace("a", 3) {
+		t.Fatalf("Replace(existing) = false, want true")
+	}
+	mustGet(t, c, "a", 3)
+	if c.Replace("missing", 1) {
+		t.Fatalf("Replace(missing) = true, want false")
+	}
+	mustMiss(t, c, "missing")
+
+	if c.CompareAndSwap("a", 1, 4) {
+		t.Fatalf("CompareAndSwap(stale) = true, want false")
+	}
+	if !c.CompareAndSwap("a", 3, 4) {
+		t.Fatalf("CompareAndSwap(current) = false, want true")
+	}
+	mustGet(t, c, "a", 4)
+
+	var incr = func(old interface{}, exists bool) (interface{}, bool) {
+		if !exists {
+			return 1, true
+		}
+		return old.(int) + 1, true
+	}
+	if v, ok := c.Compute("a", incr); !ok || v != 5 {
+		t.Fatalf("Compute = %v, %v, want 5, true", v, ok)
+	}
+	mustGet(t, c, "a", 5)
+	if _, ok := c.Compute("a", func(interface{}, bool) (interface{}, bool) { return nil, false }); ok {
+		t.Fatalf("Compute(remove) kept the key")
+	}
+	mustMiss(t, c, "a")
+
+	// 过期的元素视为不存在
+	Admit(c, p, "t", 1, time.Second)
+	clock.Advance(2 * time.Second)
+	if c.Replace("t", 2) {
+		t.Fatalf("Replace(expired) = true, want false")
+	}
+	// 不带过期时间的方法保留原有的过期时间
+	Admit(c, p, "ttl", 1, time.Second)
+	c.Replace("ttl", 2)
+	c.CompareAndSwap("ttl", 2, 3)
+	c.Compute("ttl", incr)
+	mustGet(t, c, "ttl", 4)
+	clock.Advance(2 * time.Second)
+	mustMiss(t, c, "ttl")
+
+	// 需要多次写入的策略可能需要重新积累访问历史
+	var added = c.PutIfAbsentWithExpire("t", 2, time.Second)
+	for i := 1; i < p.PutsToAdmit && !added; i++ {
+		added = c.PutIfAbsentWithExpire("t", 2, time.Second)
+	}
+	if !added {
+		t.Fatalf("PutIfAbsent(expired) = false, want true")
+	}
+
+	// 并发自增不丢失更新
+	const workers, times = 8, 100
+	Admit(c, p, "n", 0, cache.NoExpiration)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < times; j++ {
+				c.Compute("n", incr)
+			}
+		}()
+	}
+	wg.Wait()
+	mustGet(t, c, "n", workers*times)
+}
+
+// 等待异步回调执行完成
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	var deadline = time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func testCallback(t *testing.T, p Policy) {
+	var (
+		clock   = NewFakeClock()
+		evicted int64
+		mu      sync.Mutex
+		keys    = make(map[interface{}]int)
+	)
+	var c = newCache(t, p, &cache.Opt{Clock: clock, Callback: func(key interface{}, value interface{}) {
+		atomic.AddInt64(&evicted, 1)
+		mu.Lock()
+		keys[key]++
+		mu.Unlock()
+		if key != value {
+			t.Errorf("callback(%v, %v): value mismatch", key, value)
+		}
+	}})
+	var expect = func(n int64) {
+		t.Helper()
+		waitFor(t, fmt.Sprintf("%d callbacks", n), func() bool { return atomic.LoadInt64(&evicted) >= n })
+		time.Sleep(10 * time.Millisecond)
+		if got := atomic.LoadInt64(&evicted); got != n {
+			t.Fatalf("callbacks = %d, want %d", got, n)
+		}
+	}
+
+	var total = testCapacity * 2
+	for i := 0; i < total; i++ {
+		Admit(c, p, i, i, cache.NoExpiration)
+	}
+	// 更新不触发回调，UpdateEvicts时对旧值触发一次回调
+	var updated = make(map[interface{}]int)
+	for i := 0; i < total; i++ {
+		if _, ok := c.Get(i); ok {
+			c.Put(i, i)
+			if p.UpdateEvicts {
+				updated[i]++
+			}
+		}
+	}
+	var removed = int64(total - c.Len() + len(updated))
+	expect(removed)
+
+	// 移除触发一次回调
+	for i := 0; i < total; i++ {
+		if c.Remove(i) {
+			removed++
+			break
+		}
+	}
+	expect(removed)
+
+	// 过期回收触发回调
+	Admit(c, p, "expired", "expired", time.Second)
+	clock.Advance(2 * time.Second)
+	c.DeleteExpired()
+	removed++
+	total++
+	expect(removed)
+
+	// 清空时剩余元素各触发一次回调
+	c.Clear()
+	expect(int64(total + len(updated)))
+	mu.Lock()
+	defer mu.Unlock()
+	for k, n := range keys {
+		if n != 1+updated[k] {
+			t.Fatalf("callback for key %v called %d times", k, n)
+		}
+	}
+}
+
+func testConcurrency(t *testing.T, p Policy) {
+	var (
+		clock = NewFakeClock()
+		c     = newCache(t, p, &cache.Opt{Clock: clock, Capacity: 64})
+		wg    sync.WaitGroup
+	)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			var r = rand.New(rand.NewSource(seed))
+			for i := 0; i < 2000; i++ {
+				var key = r.Intn(128)
+				switch r.Intn(10) {
+				case 0, 1, 2:
+					c.Put(key, key)
+				case 3:
+					c.PutWithExpire(key, key, time.Duration(r.Intn(10))*time.Millisecond)
+				case 4:
+					c.Remove(key)
+				case 5:
+					_ = c.Len()
+				case 6:
+					c.DeleteExpired()
+				case 7:
+					clock.Advance(time.Millisecond)
+				default:
+					if v, ok := c.Get(key); ok && v != key {
+						t.Errorf("Get(%d) = %v", key, v)
+						return
+					}
+				}
+				if r.Intn(500) == 0 {
+					c.Clear()
+				}
+			}
+		}(int64(g))
+	}
+	wg.Wait()
+	if n := c.Len(); !p.Unbounded && n > 64 {
+		t.Fatalf("Len() = %d, exceeds capacity 64", n)
+	}
+}
+
+func testModel(t *testing.T, p Policy) {
+	for seed := int64(1); seed <= 5; seed++ {
+		var (
+			r      = rand.New(rand.NewSource(seed))
+			c      = newCache(t, p, &cache.Opt{})
+			model  Model
+			latest = make(map[interface{}]interface{}) // 每个key最后一次Put的值
+		)
+		if p.Model != nil {
+			model = p.Model(testCapacity)
+		}
+		for i := 0; i < 5000; i++ {
+			var (
+				key = r.Intn(testCapacity * 2)
+				op  string
+			)
+			switch r.Intn(4) {
+			case 0, 1:
+				var value = i
+				op = fmt.Sprintf("Put(%d, %d)", key, value)
+				c.Put(key, value)
+				latest[key] = value
+				if model != nil {
+					model.Put(key, value)
+				}
+			case 2:
+				op = fmt.Sprintf("Get(%d)", key)
+				var v, ok = c.Get(key)
+				if model != nil {
+					var mv, mok = model.Get(key)
+					if ok != mok || v != mv {
+						t.Fatalf("seed %d op %d %s = (%v, %v), model (%v, %v)", seed, i, op, v, ok, mv, mok)
+					}
+				}
+				if want, exist := latest[key]; ok && (!exist || v != want) {
+					t.Fatalf("seed %d op %d %s = %v, want latest %v (exist %v)", seed, i, op, v, want, exist)
+				}
+			default:
+				op = fmt.Sprintf("Remove(%d)", key)
+				var ok = c.Remove(key)
+				if model != nil {
+					if mok := model.Remove(key); ok != mok {
+						t.Fatalf("seed %d op %d %s = %v, model %v", seed, i, op, ok, mok)
+					}
+				}
+				if _, exist := latest[key]; ok && !exist {
+					t.Fatalf("seed %d op %d %s = true, key not present", seed, i, op)
+				}
+				delete(latest, key)
+			}
+
+			var n = c.Len()
+			if model != nil && n != model.Len() {
+				t.Fatalf("seed %d op %d after %s Len() = %d, model %d", seed, i, op, n, model.Len())
+			}
+			if !p.Unbounded && n > testCapacity {
+				t.Fatalf("seed %d op %d after %s Len() = %d, exceeds capacity", seed, i, op, n)
+			}
+		}
+	}
+}
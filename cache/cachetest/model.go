@@ -0,0 +1,147 @@
+package cachetest
+
+import "container/list"
+
+// Model 缓存淘汰策略的参考模型，用于与被测缓存逐步对比命中结果
+// 模型不考虑过期时间
+type Model interface {
+	Put(key, value interface{})
+	Get(key interface{}) (interface{}, bool)
+	Remove(key interface{}) bool
+	Len() int
+}
+
+type modelEntry struct {
+	key   interface{}
+	value interface{}
+	freq  int
+}
+
+// lruModel LRU参考模型
+type lruModel struct {
+	capacity int
+	ll       *list.List
+	items    map[interface{}]*list.Element
+}
+
+// NewLRUModel 创建容量为capacity的LRU参考模型
+func NewLRUModel(capacity int) Model {
+	return &lruModel{capacity: capacity, ll: list.New(), items: make(map[interface{}]*list.Element)}
+}
+
+func (m *lruModel) Put(key, value interface{}) {
+	if e, ok := m.items[key]; ok {
+		e.Value.(*modelEntry).value = value
+		m.ll.MoveToFront(e)
+		return
+	}
+	m.items[key] = m.ll.PushFront(&modelEntry{key: key, value: value})
+	if m.ll.Len() > m.capacity {
+		var back = m.ll.Back()
+		m.ll.Remove(back)
+		delete(m.items, back.Value.(*modelEntry).key)
+	}
+}
+
+func (m *lruModel) Get(key interface{}) (interface{}, bool) {
+	if e, ok := m.items[key]; ok {
+		m.ll.MoveToFront(e)
+		return e.Value.(*modelEntry).value, true
+	}
+	return nil, false
+}
+
+func (m *lruModel) Remove(key interface{}) bool {
+	if e, ok := m.items[key]; ok {
+		m.ll.Remove(e)
+		delete(m.items, key)
+		return true
+	}
+	return false
+}
+
+func (m *lruModel) Len() int {
+	return m.ll.Len()
+}
+
+// lfuModel LFU参考模型，频次相同时淘汰最久未被访问的元素
+type lfuModel struct {
+	capacity int
+	ll       *list.List // 按最近访问时间排序，头部最新
+	items    map[interface{}]*list.Element
+}
+
+// NewLFUModel 创建容量为capacity的LFU参考模型
+func NewLFUModel(capacity int) Model {
+	return &lfuModel{capacity: capacity, ll: list.New(), items: make(map[interface{}]*list.Element)}
+}
+
+func (m *lfuModel) Put(key, value interface{}) {
+	if e, ok := m.items[key]; ok {
+		e.Value.(*modelEntry).value = value
+		e.Value.(*modelEntry).freq++
+		m.ll.MoveToFront(e)
+		return
+	}
+	if m.ll.Len() >= m.capacity {
+		// 从最久未被访问的元素开始查找频次最小者
+		var victim *list.Element
+		for e := m.ll.Back(); e != nil; e = e.Prev() {
+			if victim == nil || e.Value.(*modelEntry).freq < victim.Value.(*modelEntry).freq {
+				victim = e
+			}
+		}
+		m.ll.Remove(victim)
+		delete(m.items, victim.Value.(*modelEntry).key)
+	}
+	m.items[key] = m.ll.PushFront(&modelEntry{key: key, value: value, freq: 1})
+}
+
+func (m *lfuModel) Get(key interface{}) (interface{}, bool) {
+	if e, ok := m.items[key]; ok {
+		e.Value.(*modelEntry).freq++
+		m.ll.MoveToFront(e)
+		return e.Value.(*modelEntry).value, true
+	}
+	return nil, false
+}
+
+func (m *lfuModel) Remove(key interface{}) bool {
+	if e, ok := m.items[key]; ok {
+		m.ll.Remove(e)
+		delete(m.items, key)
+		return true
+	}
+	return false
+}
+
+func (m *lfuModel) Len() int {
+	return m.ll.Len()
+}
+
+// mapModel 无容量限制的参考模型
+type mapModel map[interface{}]interface{}
+
+// NewMapModel 创建无容量限制的参考模型，capacity被忽略
+func NewMapModel(capacity int) Model {
+	return mapModel{}
+}
+
+func (m mapModel) Put(key, value interface{}) {
+	m[key] = value
+}
+
+func (m mapModel) Get(key interface{}) (interface{}, bool) {
+	var v, ok = m[key]
+	return v, ok
+}
+
+func (m mapModel) Remove(key interface{}) bool {
+	var _, ok = m[key]
+	delete(m, key)
+	return ok
+}
+
+func (m mapModel) Len() int {
+	return len(m)
+}
@@ -0,0 +1,261 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+CLOCK：
+1. 元素存放在环形数组中，每个元素带有一个访问位；
+2. 命中时只设置访问位，读操作在读锁下完成，不调整元素顺序；
+3. 需要淘汰时指针沿环形数组扫描：访问位为1则清零并跳过，为0（或已过期）则淘汰。
+*/
+
+type ClockCache struct {
+	*clock
+	lock sync.RWMutex
+	atomicOp
+}
+
+func NewClockCache(opt *Opt) (*ClockCache, error) {
+	var (
+		c   *clock
+		err error
+	)
+	if c, err = newClock(opt); err != nil {
+		return nil, err
+	}
+	var cc = &ClockCache{clock: c}
+	cc.atomicOp = atomicOp{locker: &cc.lock, op: cc.clock}
+	startWatchdog(cc.expire, cc)
+	return cc, nil
+}
+
+// Get 在读锁下查询并设置访问位，只有命中过期元素时才获取写锁进行惰性回收
+func (cc *ClockCache) Get(key interface{}) (interface{}, bool) {
+	cc.lock.RLock()
+	var value, ok, expired = cc.clock.get(key)
+	cc.lock.RUnlock()
+	if expired {
+		cc.lock.Lock()
+		cc.clock.removeExpired(key)
+		cc.lock.Unlock()
+	}
+	return value, ok
+}
+
+func (cc *ClockCache) Put(key, value interface{}) bool {
+	cc.lock.Lock()
+	defer cc.lock.Unlock()
+	return cc.clock.Put(key, value)
+}
+
+func (cc *ClockCache) PutWithExpire(key interface{}, value interface{}, lifeSpan time.Duration) bool {
+	cc.lock.Lock()
+	defer cc.lock.Unlock()
+	return cc.clock.PutWithExpire(key, value, lifeSpan)
+}
+
+func (cc *ClockCache) Remove(key interface{}) bool {
+	cc.lock.Lock()
+	defer cc.lock.Unlock()
+	return cc.clock.Remove(key)
+}
+
+func (cc *ClockCache) DeleteExpired() {
+	cc.lock.Lock()
+	defer cc.lock.Unlock()
+	cc.clock.DeleteExpired()
+}
+
+func (cc *ClockCache) Len() int {
+	cc.lock.RLock()
+	defer cc.lock.RUnlock()
+	return cc.clock.Len()
+}
+
+func (cc *ClockCache) Clear() {
+	cc.lock.Lock()
+	defer cc.lock.Unlock()
+	cc.clock.Clear()
+}
+
+type clock struct {
+	slots     []*clockEntry       // 环形数组
+	items     map[interface{}]int // 绑定元素key和数组下标
+	free      []int               // 空闲下标
+	hand      int                 // 扫描指针
+	onEvict   EvictCallback       // 淘汰元素时执行的回调
+	admission AdmissionPolicy     // 准入策略
+	*expire                       // 过期属性
+}
+
+type clockEntry struct {
+	entry
+	ref uint32 // 访问位，读锁下原子设置
+}
+
+func (e *clockEntry) Reset() {
+	e.entry.Reset()
+	atomic.StoreUint32(&e.ref, 0)
+}
+
+func newClock(opt *Opt) (*clock, error) {
+	if opt.Capacity <= 0 {
+		return nil, ErrSize
+	}
+	var c = &clock{
+		slots:     make([]*clockEntry, opt.Capacity),
+		items:     make(map[interface{}]int, opt.Capacity),
+		free:      make([]int, 0, opt.Capacity),
+		onEvict:   opt.Callback,
+		admission: opt.Admission,
+		expire:    newExpire(opt),
+	}
+	c.resetFree()
+	return c, nil
+}
+
+func (c *clock) resetFree() {
+	c.free = c.free[:0]
+	for i := len(c.slots) - 1; i >= 0; i-- {
+		c.free = append(c.free, i)
+	}
+}
+
+// 只读查询，命中时原子设置访问位；expired表示命中了过期元素
+func (c *clock) get(key interface{}) (value interface{}, ok bool, expired bool) {
+	var idx, exist = c.items[key]
+	if !exist {
+		return nil, false, false
+	}
+	var et = c.slots[idx]
+	if et.expiredAt(c.now()) {
+		return nil, false, true
+	}
+	atomic.StoreUint32(&et.ref, 1)
+	return et.value, true, false
+}
+
+// 惰性回收过期元素，获取写锁后需要重新检查
+func (c *clock) removeExpired(key interface{}) {
+	if idx, ok := c.items[key]; ok && c.slots[idx].expiredAt(c.now()) {
+		c.removeSlot(idx)
+	}
+}
+
+func (c *clock) Get(key interface{}) (interface{}, bool) {
+	var value, ok, expired = c.get(key)
+	if expired {
+		c.removeExpired(key)
+	}
+	return value, ok
+}
+
+func (c *clock) Put(key, value interface{}) bool {
+	return c.PutWithExpire(key, value, NoExpiration)
+}
+
+// 不存在则添加，存在则更新；return 是否淘汰元素
+func (c *clock) PutWithExpire(key interface{}, value interface{}, lifeSpan time.Duration) bool {
+	if idx, ok := c.items[key]; ok {
+		var et = c.slots[idx]
+		et.value = value
+		et.expiration = c.renewTime(et.expiration, lifeSpan)
+		atomic.StoreUint32(&et.ref, 1)
+		return false
+	}
+
+	// 不满足准入策略则不写入
+	if !admit(c.admission, key) {
+		return false
+	}
+
+	var evict = len(c.free) == 0
+	if evict {
+		c.sweep()
+	}
+	var idx = c.free[len(c.free)-1]
+	c.free = c.free[:len(c.free)-1]
+
+	var et = clockEntryPool.Get().(*clockEntry)
+	et.Reset()
+	et.key = key
+	et.value = value
+	et.expiration = c.absoluteTime(lifeSpan)
+	c.slots[idx] = et
+	c.items[key] = idx
+	return evict
+}
+
+// 扫描淘汰一个元素：访问位为1则清零并跳过，为0或已过期则淘汰
+func (c *clock) sweep() {
+	var now = c.now()
+	for {
+		var et = c.slots[c.hand]
+		if et != nil {
+			if et.expiredAt(now) || atomic.LoadUint32(&et.ref) == 0 {
+				c.removeSlot(c.hand)
+				c.hand = (c.hand + 1) % len(c.slots)
+				return
+			}
+			atomic.StoreUint32(&et.ref, 0)
+		}
+		c.hand = (c.hand + 1) % len(c.slots)
+	}
+}
+
+func (c *clock) removeSlot(idx int) {
+	var et = c.slots[idx]
+	c.slots[idx] = nil
+	c.free = append(c.free, idx)
+	delete(c.items, et.key)
+	if c.onEvict != nil {
+		// entry 会被放回对象池复用，回调只能捕获其副本
+		var key, value = et.key, et.value
+		_ = c.goroutinePool.Submit(func() {
+			c.onEvict(key, value)
+		})
+	}
+	clockEntryPool.Put(et)
+}
+
+func (c *clock) Remove(key interface{}) bool {
+	var idx, ok = c.items[key]
+	if !ok {
+		return false
+	}
+	var expired = c.slots[idx].expiredAt(c.now())
+	c.removeSlot(idx)
+	return !expired
+}
+
+func (c *clock) DeleteExpired() {
+	var now = c.now() // 减少系统调用
+	for idx, et := range c.slots {
+		if et != nil && et.expiredAt(now) {
+			c.removeSlot(idx)
+		}
+	}
+}
+
+func (c *clock) Len() int {
+	return len(c.items)
+}
+
+func (c *clock) Clear() {
+	for idx, et := range c.slots {
+		if et == nil {
+			continue
+		}
+		if c.onEvict != nil {
+			c.onEvict(et.key, et.value)
+		}
+		c.slots[idx] = nil
+	}
+	c.items = make(map[interface{}]int, len(c.slots))
+	c.resetFree()
+	c.hand = 0
+}
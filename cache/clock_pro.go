@@ -0,0 +1,390 @@
+package cache
+
+import (
+	"container/ring"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+CLOCK-Pro：
+1. 元素分为热元素、冷驻留元素和测试期的非驻留冷元素，全部挂在同一个时钟环上；
+2. 命中时只设置访问位，读操作在读锁下完成；
+3. 新元素作为冷元素插入，冷指针扫描时：有访问位的冷元素变为热元素，否则淘汰其值并转为测试元素；
+4. 热指针扫描时清除热元素的访问位，无访问位的热元素降为冷元素；
+5. 测试元素在测试期内被再次访问则直接作为热元素写入，并增大冷元素容量；测试期结束（被测试指针扫过）则遗忘，并减小冷元素容量；
+6. 测试元素数量不超过容量。
+*/
+
+type ClockProCache struct {
+	*clockPro
+	lock sync.RWMutex
+	atomicOp
+}
+
+func NewClockProCache(opt *Opt) (*ClockProCache, error) {
+	var (
+		c   *clockPro
+		err error
+	)
+	if c, err = newClockPro(opt); err != nil {
+		return nil, err
+	}
+	var cc = &ClockProCache{clockPro: c}
+	cc.atomicOp = atomicOp{locker: &cc.lock, op: cc.clockPro}
+	startWatchdog(cc.expire, cc)
+	return cc, nil
+}
+
+// Get 在读锁下查询并设置访问位，只有命中过期元素时才获取写锁进行惰性回收
+func (cc *ClockProCache) Get(key interface{}) (interface{}, bool) {
+	cc.lock.RLock()
+	var value, ok, expired = cc.clockPro.get(key)
+	cc.lock.RUnlock()
+	if expired {
+		cc.lock.Lock()
+		cc.clockPro.removeExpired(key)
+		cc.lock.Unlock()
+	}
+	return value, ok
+}
+
+func (cc *ClockProCache) Put(key, value interface{}) bool {
+	cc.lock.Lock()
+	defer cc.lock.Unlock()
+	return cc.clockPro.Put(key, value)
+}
+
+func (cc *ClockProCache) PutWithExpire(key interface{}, value interface{}, lifeSpan time.Duration) bool {
+	cc.lock.Lock()
+	defer cc.lock.Unlock()
+	return cc.clockPro.PutWithExpire(key, value, lifeSpan)
+}
+
+func (cc *ClockProCache) Remove(key interface{}) bool {
+	cc.lock.Lock()
+	defer cc.lock.Unlock()
+	return cc.clockPro.Remove(key)
+}
+
+func (cc *ClockProCache) DeleteExpired() {
+	cc.lock.Lock()
+	defer cc.lock.Unlock()
+	cc.clockPro.DeleteExpired()
+}
+
+func (cc *ClockProCache) Len() int {
+	cc.lock.RLock()
+	defer cc.lock.RUnlock()
+	return cc.clockPro.Len()
+}
+
+func (cc *ClockProCache) Clear() {
+	cc.lock.Lock()
+	defer cc.lock.Unlock()
+	cc.clockPro.Clear()
+}
+
+type clockProType int
+
+const (
+	clockProCold clockProType = iota // 冷驻留元素
+	clockProHot                      // 热元素
+	clockProTest                     // 测试期的非驻留冷元素
+)
+
+type clockProEntry struct {
+	entry
+	ptype clockProType
+	ref   uint32 // 访问位，读锁下原子设置
+}
+
+type clockPro struct {
+	items     map[interface{}]*ring.Ring // 绑定元素key和时钟环节点
+	handHot   *ring.Ring                 // 热指针
+	handCold  *ring.Ring                 // 冷指针
+	handTest  *ring.Ring                 // 测试指针
+	memMax    int                        // 容量
+	memCold   int                        // 冷元素目标容量，自适应调整
+	countHot  int                        // 热元素个数
+	countCold int                        // 冷驻留元素个数
+	countTest int                        // 测试元素个数
+	onEvict   EvictCallback              // 淘汰元素时执行的回调
+	admission AdmissionPolicy            // 准入策略
+	*expire                              // 过期属性
+}
+
+func newClockPro(opt *Opt) (*clockPro, error) {
+	if opt.Capacity <= 0 {
+		return nil, ErrSize
+	}
+	return &clockPro{
+		items:     make(map[interface{}]*ring.Ring),
+		memMax:    opt.Capacity,
+		memCold:   opt.Capacity,
+		onEvict:   opt.Callback,
+		admission: opt.Admission,
+		expire:    newExpire(opt),
+	}, nil
+}
+
+// 只读查询，命中时原子设置访问位；expired表示命中了过期元素
+func (c *clockPro) get(key interface{}) (value interface{}, ok bool, expired bool) {
+	var r, exist = c.items[key]
+	if !exist {
+		return nil, false, false
+	}
+	var et = r.Value.(*clockProEntry)
+	if et.ptype == clockProTest {
+		return nil, false, false
+	}
+	if et.expiredAt(c.now()) {
+		return nil, false, true
+	}
+	atomic.StoreUint32(&et.ref, 1)
+	return et.value, true, false
+}
+
+// 惰性回收过期元素，获取写锁后需要重新检查
+func (c *clockPro) removeExpired(key interface{}) {
+	var r, ok = c.items[key]
+	if !ok {
+		return
+	}
+	if et := r.Value.(*clockProEntry); et.ptype != clockProTest && et.expiredAt(c.now()) {
+		c.removeResident(r)
+	}
+}
+
+func (c *clockPro) Get(key interface{}) (interface{}, bool) {
+	var value, ok, expired = c.get(key)
+	if expired {
+		c.removeExpired(key)
+	}
+	return value, ok
+}
+
+func (c *clockPro) Put(key, value interface{}) bool {
+	return c.PutWithExpire(key, value, NoExpiration)
+}
+
+// 不存在则添加，存在则更新；return 是否淘汰元素
+func (c *clockPro) PutWithExpire(key interface{}, value interface{}, lifeSpan time.Duration) bool {
+	var r, ok = c.items[key]
+	if ok {
+		var et = r.Value.(*clockProEntry)
+		// 驻留元素直接更新
+		if et.ptype != clockProTest {
+			et.value = value
+			et.expiration = c.renewTime(et.expiration, lifeSpan)
+			atomic.StoreUint32(&et.ref, 1)
+			return false
+		}
+	}
+
+	// 不满足准入策略则不写入
+	if !admit(c.admission, key) {
+		return false
+	}
+
+	var evict = c.countHot+c.countCold >= c.memMax
+	if !ok {
+		// 新元素作为冷元素写入
+		c.metaAdd(key, value, lifeSpan, clockProCold)
+		c.countCold++
+		return evict
+	}
+
+	// 测试期内被再次访问，增大冷元素容量，并作为热元素写入
+	if c.memCold < c.memMax {
+		c.memCold++
+	}
+	c.countTest--
+	c.metaDel(r)
+	c.metaAdd(key, value, lifeSpan, clockProHot)
+	c.countHot++
+	return evict
+}
+
+// 在热指针之后插入元素，插入前先腾出空间
+func (c *clockPro) metaAdd(key, value interface{}, lifeSpan time.Duration, ptype clockProType) {
+	c.evict()
+
+	var et = clockProEntryPool.Get().(*clockProEntry)
+	et.Reset()
+	et.key = key
+	et.value = value
+	et.expiration = c.absoluteTime(lifeSpan)
+	et.ptype = ptype
+
+	var r = &ring.Ring{Value: et}
+	c.items[key] = r
+	if c.handHot == nil {
+		c.handHot, c.handCold, c.handTest = r, r, r
+	} else {
+		c.handHot.Link(r)
+	}
+	if c.handCold == c.handHot {
+		c.handCold = c.handCold.Prev()
+	}
+}
+
+// 从时钟环中删除节点，指向该节点的指针回退一步
+func (c *clockPro) metaDel(r *ring.Ring) {
+	delete(c.items, r.Value.(*clockProEntry).key)
+	if r.Len() == 1 {
+		c.handHot, c.handCold, c.handTest = nil, nil, nil
+		return
+	}
+	if r == c.handHot {
+		c.handHot = c.handHot.Prev()
+	}
+	if r == c.handCold {
+		c.handCold = c.handCold.Prev()
+	}
+	if r == c.handTest {
+		c.handTest = c.handTest.Prev()
+	}
+	r.Prev().Unlink(1)
+}
+
+// 驻留元素达到容量时运行冷指针淘汰
+func (c *clockPro) evict() {
+	for c.memMax <= c.countHot+c.countCold {
+		c.runHandCold()
+	}
+}
+
+func (c *clockPro) runHandCold() {
+	var et = c.handCold.Value.(*clockProEntry)
+	if et.ptype == clockProCold {
+		if atomic.LoadUint32(&et.ref) == 1 && !et.expiredAt(c.now()) {
+			// 有访问位的冷元素变为热元素
+			et.ptype = clockProHot
+			atomic.StoreUint32(&et.ref, 0)
+			c.countCold--
+			c.countHot++
+		} else {
+			// 淘汰值，转为测试元素
+			c.callEvict(et)
+			et.ptype = clockProTest
+			et.item.Reset()
+			c.countCold--
+			c.countTest++
+			for c.memMax < c.countTest {
+				c.runHandTest()
+			}
+		}
+	}
+	c.handCold = c.handCold.Next()
+	for c.memMax-c.memCold < c.countHot {
+		c.runHandHot()
+	}
+}
+
+func (c *clockPro) runHandHot() {
+	if c.handHot == c.handTest {
+		c.runHandTest()
+	}
+	var et = c.handHot.Value.(*clockProEntry)
+	if et.ptype == clockProHot {
+		if atomic.LoadUint32(&et.ref) == 1 {
+			atomic.StoreUint32(&et.ref, 0)
+		} else {
+			// 无访问位的热元素降为冷元素
+			et.ptype = clockProCold
+			c.countHot--
+			c.countCold++
+		}
+	}
+	c.handHot = c.handHot.Next()
+}
+
+func (c *clockPro) runHandTest() {
+	if c.handTest == c.handCold {
+		c.runHandCold()
+	}
+	var et = c.handTest.Value.(*clockProEntry)
+	if et.ptype == clockProTest {
+		// 测试期结束，遗忘元素并减小冷元素容量
+		var prev = c.handTest.Prev()
+		c.metaDel(c.handTest)
+		clockProEntryPool.Put(et)
+		c.handTest = prev
+		c.countTest--
+		if c.memCold > 1 {
+			c.memCold--
+		}
+	}
+	c.handTest = c.handTest.Next()
+}
+
+func (c *clockPro) callEvict(et *clockProEntry) {
+	if c.onEvict != nil {
+		// entry 会被放回对象池复用，回调只能捕获其副本
+		var key, value = et.key, et.value
+		_ = c.goroutinePool.Submit(func() {
+			c.onEvict(key, value)
+		})
+	}
+}
+
+// 移除驻留元素
+func (c *clockPro) removeResident(r *ring.Ring) {
+	var et = r.Value.(*clockProEntry)
+	if et.ptype == clockProHot {
+		c.countHot--
+	} else {
+		c.countCold--
+	}
+	c.callEvict(et)
+	c.metaDel(r)
+	clockProEntryPool.Put(et)
+}
+
+func (c *clockPro) Remove(key interface{}) bool {
+	var r, ok = c.items[key]
+	if !ok {
+		return false
+	}
+	var et = r.Value.(*clockProEntry)
+	if et.ptype == clockProTest {
+		return false
+	}
+	var expired = et.expiredAt(c.now())
+	c.removeResident(r)
+	return !expired
+}
+
+func (c *clockPro) DeleteExpired() {
+	var now = c.now() // 减少系统调用
+	for _, r := range c.items {
+		if et := r.Value.(*clockProEntry); et.ptype != clockProTest && et.expiredAt(now) {
+			c.removeResident(r)
+		}
+	}
+}
+
+// 驻留元素个数
+func (c *clockPro) Len() int {
+	return c.countHot + c.countCold
+}
+
+func (c *clockPro) Clear() {
+	for k, r := range c.items {
+		if et := r.Value.(*clockProEntry); c.onEvict != nil && et.ptype != clockProTest {
+			c.onEvict(k, et.value)
+		}
+		delete(c.items, k)
+	}
+	c.handHot, c.handCold, c.handTest = nil, nil, nil
+	c.memCold = c.memMax
+	c.countHot, c.countCold, c.countTest = 0, 0, 0
+}
+
+func (e *clockProEntry) Reset() {
+	e.entry.Reset()
+	e.ptype = clockProCold
+	atomic.StoreUint32(&e.ref, 0)
+}
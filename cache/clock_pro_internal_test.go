@@ -0,0 +1,87 @@
+package cache
+
+import (
+	"testing"
+)
+
+func TestClockPro_Transitions(t *testing.T) {
+	var c, err = newClockPro(&Opt{Capacity: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var check = func(key string, want clockProType) {
+		t.Helper()
+		var r, ok = c.items[key]
+		if !ok {
+			t.Fatalf("%s is forgotten", key)
+		}
+		if got := r.Value.(*clockProEntry).ptype; got != want {
+			t.Fatalf("%s type = %d, want %d", key, got, want)
+		}
+	}
+	var counts = func(hot, cold, test int) {
+		t.Helper()
+		if c.countHot != hot || c.countCold != cold || c.countTest != test {
+			t.Fatalf("hot, cold, test = %d, %d, %d, want %d, %d, %d",
+				c.countHot, c.countCold, c.countTest, hot, cold, test)
+		}
+	}
+
+	// 新元素作为冷元素写入
+	c.Put("a", 1)
+	c.Put("b", 2)
+	check("a", clockProCold)
+	check("b", clockProCold)
+	counts(0, 2, 0)
+	// 留出热元素的容量，避免冷指针扫描后立即运行热指针
+	c.memCold = 2
+
+	// 有访问位的冷元素被冷指针扫过后变为热元素
+	c.Get("a")
+	c.handCold = c.items["a"]
+	c.runHandCold()
+	check("a", clockProHot)
+	counts(1, 1, 0)
+
+	// 无访问位的冷元素被淘汰，转为测试元素
+	c.handCold = c.items["b"]
+	c.runHandCold()
+	check("b", clockProTest)
+	counts(1, 0, 1)
+	if _, ok := c.Get("b"); ok || c.Len() != 1 {
+		t.Fatalf("Get(b) = %v, Len = %d after eviction", ok, c.Len())
+	}
+
+	// 测试期内再次写入，直接作为热元素并增大冷元素容量
+	c.Put("b", 3)
+	check("b", clockProHot)
+	counts(2, 0, 0)
+	if c.memCold != 3 {
+		t.Fatalf("memCold = %d, want 3", c.memCold)
+	}
+
+	// 有访问位的热元素被热指针扫过后清除访问位，无访问位的降为冷元素
+	c.Get("b")
+	c.handHot = c.items["b"]
+	c.runHandHot()
+	check("b", clockProHot)
+	c.handHot = c.items["b"]
+	c.runHandHot()
+	check("b", clockProCold)
+	counts(1, 1, 0)
+
+	// 测试期结束（被测试指针扫过）的元素被遗忘，并减小冷元素容量
+	c.Put("c", 4)
+	c.handCold = c.items["c"]
+	c.runHandCold()
+	check("c", clockProTest)
+	c.handTest = c.items["c"]
+	c.runHandTest()
+	if _, ok := c.items["c"]; ok {
+		t.Fatal("c is not forgotten after its test period")
+	}
+	counts(1, 1, 0)
+	if c.memCold != 2 {
+		t.Fatalf("memCold = %d, want 2", c.memCold)
+	}
+}
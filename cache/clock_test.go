@@ -0,0 +1,57 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/1005281342/basic_component/cache"
+)
+
+func benchmarkGetParallel(b *testing.B, ct cache.CacheType) {
+	var c, err = cache.NewCache(ct, &cache.Opt{Capacity: 1024, Interval: time.Hour})
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < 1024; i++ {
+		c.Put(i, i)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var i int
+		for pb.Next() {
+			c.Get(i & 1023)
+			i++
+		}
+	})
+}
+
+func BenchmarkLRU_GetParallel(b *testing.B)      { benchmarkGetParallel(b, cache.LRU) }
+func BenchmarkClock_GetParallel(b *testing.B)    { benchmarkGetParallel(b, cache.Clock) }
+func BenchmarkClockPro_GetParallel(b *testing.B) { benchmarkGetParallel(b, cache.ClockPro) }
+
+// 被访问过的元素在扫描时获得第二次机会，未被访问的元素被淘汰
+func TestClock_SecondChance(t *testing.T) {
+	var keys = []string{"a", "b", "c"}
+	for _, victim := range keys {
+		var c, err = cache.NewClockCache(&cache.Opt{Capacity: 3, Interval: time.Hour})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, k := range keys {
+			c.Put(k, k)
+		}
+		for _, k := range keys {
+			if k != victim {
+				c.Get(k)
+			}
+		}
+		if !c.Put("d", "d") {
+			t.Fatalf("Put(d) evicted nothing")
+		}
+		for _, k := range append(keys, "d") {
+			if _, _, ok := c.Peek(k); ok == (k == victim) {
+				t.Fatalf("victim %s: Peek(%s) = %v", victim, k, ok)
+			}
+		}
+	}
+}
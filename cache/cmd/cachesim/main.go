@@ -0,0 +1,164 @@
+// cachesim 回放访问trace，统计各淘汰策略在不同容量下的命中率
+//
+//	cachesim -trace words.txt -format words -capacity 100,1000,10000
+//	cachesim -trace OLTP.lis -format arc -min 1000 -max 64000 -steps 7 -output csv
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/1005281342/basic_component/cache"
+)
+
+var (
+	tracePath  = flag.String("trace", "", "trace file, - for stdin")
+	format     = flag.String("format", formatPlain, "trace format: plain, words, arc, lirs, csv")
+	policyList = flag.String("policy", "", "comma separated policies, empty for all")
+	capList    = flag.String("capacity", "", "comma separated capacities, overrides -min/-max/-steps")
+	minCap     = flag.Int("min", 100, "min capacity")
+	maxCap     = flag.Int("max", 10000, "max capacity")
+	steps      = flag.Int("steps", 5, "number of capacities between min and max, growing geometrically")
+	output     = flag.String("output", "table", "output format: table, csv")
+)
+
+// 参与回放的淘汰策略
+var policies = []cache.CacheType{cache.LRU, cache.LFU, cache.LRUk, cache.LRU2q, cache.LRUmq, cache.LIRS, cache.Clock, cache.ClockPro, cache.S3FIFO, cache.Adaptive}
+
+func main() {
+	flag.Parse()
+	if *tracePath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var (
+		accesses []access
+		cts      []cache.CacheType
+		caps     []int
+		err      error
+	)
+	if accesses, err = loadTrace(*tracePath, *format); err != nil {
+		log.Fatal(err)
+	}
+	if cts, err = parsePolicies(*policyList); err != nil {
+		log.Fatal(err)
+	}
+	if caps, err = parseCapacities(*capList, *minCap, *maxCap, *steps); err != nil {
+		log.Fatal(err)
+	}
+
+	var results []result
+	for _, ct := range cts {
+		for _, capacity := range caps {
+			results = append(results, simulate(ct, capacity, accesses))
+		}
+	}
+
+	switch *output {
+	case "csv":
+		writeCSV(os.Stdout, results)
+	default:
+		writeTable(os.Stdout, results)
+	}
+}
+
+func loadTrace(path, format string) ([]access, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		var f, err = os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			_ = f.Close()
+		}()
+		r = f
+	}
+	if format == formatWords {
+		return readWords(r)
+	}
+	return readTrace(r, format)
+}
+
+func parsePolicies(s string) ([]cache.CacheType, error) {
+	if s == "" {
+		return policies, nil
+	}
+	var cts []cache.CacheType
+	for _, name := range strings.Split(s, ",") {
+		var found bool
+		for _, ct := range policies {
+			if strings.EqualFold(ct.String(), strings.TrimSpace(name)) {
+				cts = append(cts, ct)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown policy: %s", name)
+		}
+	}
+	return cts, nil
+}
+
+// 解析容量列表，未指定时在[min, max]之间按等比生成steps个容量
+func parseCapacities(s string, min, max, steps int) ([]int, error) {
+	var caps []int
+	if s != "" {
+		for _, field := range strings.Split(s, ",") {
+			var n, err = strconv.Atoi(strings.TrimSpace(field))
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid capacity: %s", field)
+			}
+			caps = append(caps, n)
+		}
+		return caps, nil
+	}
+	if min <= 0 || max < min || steps <= 0 {
+		return nil, fmt.Errorf("invalid capacity range: [%d, %d] steps %d", min, max, steps)
+	}
+	if steps == 1 {
+		return []int{min}, nil
+	}
+	var ratio = float64(max) / float64(min)
+	for i := 0; i < steps; i++ {
+		var n = int(float64(min)*math.Pow(ratio, float64(i)/float64(steps-1)) + 0.5)
+		if len(caps) == 0 || caps[len(caps)-1] != n {
+			caps = append(caps, n)
+		}
+	}
+	return caps, nil
+}
+
+func writeTable(w io.Writer, results []result) {
+	var tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	_, _ = fmt.Fprintln(tw, "policy\tcapacity\taccesses\thits\thit ratio\tbyte hit ratio\telapsed\t")
+	for _, r := range results {
+		if r.err != nil {
+			_, _ = fmt.Fprintf(tw, "%s\t%d\t%s\t\t\t\t\t\n", r.policy, r.capacity, r.err)
+			continue
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%.4f\t%.4f\t%s\t\n",
+			r.policy, r.capacity, r.accesses, r.hits, r.hitRatio(), r.byteHitRatio(), r.elapsed)
+	}
+	_ = tw.Flush()
+}
+
+func writeCSV(w io.Writer, results []result) {
+	_, _ = fmt.Fprintln(w, "policy,capacity,accesses,hits,hit_ratio,byte_hit_ratio,elapsed_ns")
+	for _, r := range results {
+		if r.err != nil {
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "%s,%d,%d,%d,%.6f,%.6f,%d\n",
+			r.policy, r.capacity, r.accesses, r.hits, r.hitRatio(), r.byteHitRatio(), r.elapsed.Nanoseconds())
+	}
+}
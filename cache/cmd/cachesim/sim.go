@@ -0,0 +1,58 @@
+package main
+
+import (
+	"time"
+
+	"github.com/1005281342/basic_component/cache"
+)
+
+// 一次回放的结果
+type result struct {
+	policy   cache.CacheType
+	capacity int
+	accesses int
+	hits     int
+	bytes    int64
+	byteHits int64
+	err      error
+	elapsed  time.Duration
+}
+
+func (r result) hitRatio() float64 {
+	if r.accesses == 0 {
+		return 0
+	}
+	return float64(r.hits) / float64(r.accesses)
+}
+
+func (r result) byteHitRatio() float64 {
+	if r.bytes == 0 {
+		return 0
+	}
+	return float64(r.byteHits) / float64(r.bytes)
+}
+
+// 以容量capacity回放trace，未命中时写入缓存
+func simulate(ct cache.CacheType, capacity int, accesses []access) result {
+	var res = result{policy: ct, capacity: capacity}
+	var c, err = cache.NewCache(ct, &cache.Opt{Capacity: capacity, Interval: time.Hour})
+	if err != nil {
+		res.err = err
+		return res
+	}
+	defer c.Clear()
+
+	var start = time.Now()
+	for _, a := range accesses {
+		res.accesses++
+		res.bytes += a.size
+		if _, ok := c.Get(a.key); ok {
+			res.hits++
+			res.byteHits += a.size
+			continue
+		}
+		c.Put(a.key, struct{}{})
+	}
+	res.elapsed = time.Since(start)
+	return res
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/1005281342/basic_component/container/comm"
+)
+
+// 一次访问
+type access struct {
+	key  string
+	size int64 // 对象大小，未知时为1
+}
+
+// 支持的trace格式
+const (
+	formatPlain = "plain" // 每行一个key
+	formatWords = "words" // 按单词切分的文本，与 comm.ReadWords 一致
+	formatARC   = "arc"   // ARC trace: startblock numblocks ignore requestnumber
+	formatLIRS  = "lirs"  // LIRS trace: 每行一个块号，*开头的行为分隔符
+	formatCSV   = "csv"   // timestamp,key[,size]
+)
+
+// ARC trace单行最多展开的块数，避免异常的numblocks耗尽内存
+const arcMaxBlocks = 1 << 20
+
+// 读取trace中的全部访问
+func readTrace(r io.Reader, format string) ([]access, error) {
+	// 逐个输出解析得到的访问，避免按trace中的数量预分配内存；first表示是否为首行
+	var parse func(line string, first bool, emit func(access)) error
+	switch format {
+	case formatPlain, formatLIRS:
+		parse = parsePlain
+	case formatARC:
+		parse = parseARC
+	case formatCSV:
+		parse = parseCSV
+	default:
+		return nil, fmt.Errorf("unsupported trace format: %s", format)
+	}
+
+	var (
+		accesses []access
+		scanner  = bufio.NewScanner(r)
+		lineNo   int
+		emit     = func(a access) { accesses = append(accesses, a) }
+	)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lineNo++
+		var line = strings.TrimSpace(scanner.Text())
+		// 空行、注释以及LIRS的分隔行
+		if line == "" || line[0] == '#' || line[0] == '*' {
+			continue
+		}
+		if err := parse(line, lineNo == 1, emit); err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNo, err)
+		}
+	}
+	return accesses, scanner.Err()
+}
+
+// 读取单词文本，复用 comm.ReadWords 的切分规则
+func readWords(r io.Reader) ([]access, error) {
+	var words, err = comm.ReadWords(r)
+	if err != nil {
+		return nil, err
+	}
+	var accesses = make([]access, 0, len(words))
+	for _, word := range words {
+		accesses = append(accesses, access{key: word, size: 1})
+	}
+	return accesses, nil
+}
+
+func parsePlain(line string, _ bool, emit func(access)) error {
+	emit(access{key: line, size: 1})
+	return nil
+}
+
+// ARC trace每行表示访问从startblock开始的numblocks个连续块
+func parseARC(line string, _ bool, emit func(access)) error {
+	var fields = strings.Fields(line)
+	if len(fields) < 2 {
+		return fmt.Errorf("arc trace needs at least 2 fields: %q", line)
+	}
+	var (
+		start, n int64
+		err      error
+	)
+	if start, err = strconv.ParseInt(fields[0], 10, 64); err != nil {
+		return err
+	}
+	if n, err = strconv.ParseInt(fields[1], 10, 64); err != nil {
+		return err
+	}
+	if n <= 0 || n > arcMaxBlocks {
+		return fmt.Errorf("arc trace numblocks must be in (0, %d]: %q", arcMaxBlocks, line)
+	}
+	for i := int64(0); i < n; i++ {
+		emit(access{key: strconv.FormatInt(start+i, 10), size: 1})
+	}
+	return nil
+}
+
+// CSV trace: timestamp,key[,size]，只有首行可以是表头
+func parseCSV(line string, first bool, emit func(access)) error {
+	var fields = strings.Split(line, ",")
+	if len(fields) < 2 {
+		return fmt.Errorf("csv trace needs at least 2 fields: %q", line)
+	}
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+	if _, err := strconv.ParseFloat(fields[0], 64); err != nil {
+		if first {
+			// 表头
+			return nil
+		}
+		return fmt.Errorf("csv trace timestamp must be numeric: %q", line)
+	}
+	var a = access{key: fields[1], size: 1}
+	if len(fields) > 2 && fields[2] != "" {
+		var err error
+		if a.size, err = strconv.ParseInt(fields[2], 10, 64); err != nil {
+			return err
+		}
+	}
+	emit(a)
+	return nil
+}
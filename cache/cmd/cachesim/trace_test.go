@@ -0,0 +1,80 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadTrace(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		input  string
+		want   []access
+	}{
+		{"plain", formatPlain, "a\n\nb\na\n", []access{{"a", 1}, {"b", 1}, {"a", 1}}},
+		{"lirs", formatLIRS, "1\n*\n2\n", []access{{"1", 1}, {"2", 1}}},
+		{"arc", formatARC, "10 3 0 1\n5 1 0 2\n", []access{{"10", 1}, {"11", 1}, {"12", 1}, {"5", 1}}},
+		{"csv", formatCSV, "ts,key,size\n1,a,100\n2.5, b ,\n", []access{{"a", 100}, {"b", 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readTrace(strings.NewReader(tt.input), tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readTrace() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadTraceErrors(t *testing.T) {
+	for _, input := range []string{"10 0 0 1\n", "10 -3 0 1\n", "10 x 0 1\n", "10 9223372036854775807 0 1\n"} {
+		if _, err := readTrace(strings.NewReader(input), formatARC); err == nil {
+			t.Errorf("readTrace(%q) succeeded, want error", input)
+		}
+	}
+	// 只有首行可以是表头
+	for _, input := range []string{"ts,key\n1,a\nbad,b\n", "1,a\nts,key\n", "ts,key\n1,a,x\n"} {
+		if _, err := readTrace(strings.NewReader(input), formatCSV); err == nil {
+			t.Errorf("readTrace(%q) succeeded, want error", input)
+		}
+	}
+
+	got, err := readWords(strings.NewReader("Hello, world!\nhello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []access{{"hello", 1}, {"world", 1}, {"hello", 1}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("readWords() = %v, want %v", got, want)
+	}
+	if _, err = loadTrace("testdata/missing.txt", formatWords); err == nil {
+		t.Error("loadTrace(missing) succeeded, want error")
+	}
+}
+
+func TestParseCapacities(t *testing.T) {
+	got, err := parseCapacities("", 100, 10000, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{100, 1000, 10000}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseCapacities() = %v, want %v", got, want)
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	var accesses = []access{{"a", 1}, {"b", 1}, {"a", 1}, {"c", 1}, {"a", 1}}
+	for _, ct := range policies {
+		var r = simulate(ct, 2, accesses)
+		if r.err != nil {
+			t.Fatalf("%s: %v", ct, r.err)
+		}
+		if r.accesses != len(accesses) || r.hits > 2 {
+			t.Errorf("%s: accesses %d hits %d", ct, r.accesses, r.hits)
+		}
+	}
+}
@@ -6,4 +6,12 @@ import (
 
 var (
 	ErrSize = fmt.Errorf("must provide a positive size")
+	// 元素不存在或已过期
+	ErrNotFound = fmt.Errorf("key not found")
+	// 元素已知不存在（否定缓存命中）
+	ErrNegative = fmt.Errorf("key known not to exist")
+	// 固定元素个数已达上限
+	ErrPinLimit = fmt.Errorf("pinned entries reach the limit")
+	// 底层缓存不支持该操作
+	ErrNotSupported = fmt.Errorf("operation not supported by the underlying cache")
 )
@@ -0,0 +1,216 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+滑动窗口计数：
+1. 每个key持有一个环形数组，统计窗口均分为多个时间片，每个时间片一个计数，按当前时间片序号取模定位；
+2. Incr 前先清零已滑出窗口的时间片，同时维护窗口内的总数；
+3. Count 统计最近window（按时间片向上取整）内的计数，CountTumbling 统计当前对齐窗口内的计数，均不修改计数；
+4. key在空闲时长内没有 Incr 则过期，由看门狗定期回收；
+5. key按哈希分片，每个分片一把锁。
+*/
+
+const (
+	// 默认统计窗口
+	DefaultCounterWindow = time.Minute
+	// 默认时间片数
+	DefaultCounterBuckets = 60
+
+	counterShards = 32
+)
+
+// CounterOpt 滑动窗口计数配置
+type CounterOpt struct {
+	Window  time.Duration // 最大统计窗口，默认1分钟
+	Buckets int           // 窗口划分的时间片数，默认60
+	IdleTTL time.Duration // key空闲多久后过期，默认与Window相同，不小于Window
+}
+
+type CounterStore struct {
+	shards  [counterShards]counterShard
+	span    int64         // 时间片长度（纳秒）
+	buckets int           // 时间片数
+	idleTTL time.Duration // 空闲过期时长
+	*expire
+}
+
+type counterShard struct {
+	lock  sync.Mutex
+	items map[interface{}]*counter
+}
+
+type counter struct {
+	counts     []int64
+	slot       int64 // 最后写入的时间片序号
+	total      int64 // 窗口内的总数
+	expiration int64 // 过期时间
+}
+
+// NewCounterStore opt 中的Clock、Interval与协程池配置生效，不使用Capacity与Callback
+func NewCounterStore(opt *Opt, copt CounterOpt) *CounterStore {
+	if copt.Window <= 0 {
+		copt.Window = DefaultCounterWindow
+	}
+	if copt.Buckets <= 0 {
+		copt.Buckets = DefaultCounterBuckets
+	}
+	if copt.IdleTTL < copt.Window {
+		copt.IdleTTL = copt.Window
+	}
+	var cs = &CounterStore{
+		span:    int64(copt.Window) / int64(copt.Buckets),
+		buckets: copt.Buckets,
+		idleTTL: copt.IdleTTL,
+		expire:  newExpire(opt),
+	}
+	if cs.span <= 0 {
+		cs.span = 1
+	}
+	for i := range cs.shards {
+		cs.shards[i].items = make(map[interface{}]*counter)
+	}
+	startWatchdog(cs.expire, cs)
+	return cs
+}
+
+func (cs *CounterStore) shard(key interface{}) *counterShard {
+	return &cs.shards[shardHash(key)%counterShards]
+}
+
+// Incr 增加计数，return 最大统计窗口内的总数
+func (cs *CounterStore) Incr(key interface{}, n int64) int64 {
+	var now = cs.now()
+	var slot = now / cs.span
+	var s = cs.shard(key)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var c, ok = s.items[key]
+	if !ok || c.expiration <= now {
+		c = &counter{counts: make([]int64, cs.buckets), slot: slot}
+		s.items[key] = c
+	}
+	cs.advance(c, slot)
+	c.counts[slot%int64(cs.buckets)] += n
+	c.total += n
+	c.expiration = now + int64(cs.idleTTL)
+	return c.total
+}
+
+// Count 最近window内的计数，window按时间片向上取整，不超过最大统计窗口，<=0时为最大统计窗口
+func (cs *CounterStore) Count(key interface{}, window time.Duration) int64 {
+	var k = cs.bucketsOf(window)
+	var slot = cs.now() / cs.span
+	return cs.sum(key, slot-k+1, slot)
+}
+
+// CountTumbling 当前对齐窗口内的计数，窗口按window（向上取整为时间片）从时间零点对齐
+func (cs *CounterStore) CountTumbling(key interface{}, window time.Duration) int64 {
+	var k = cs.bucketsOf(window)
+	var slot = cs.now() / cs.span
+	return cs.sum(key, slot-slot%k, slot)
+}
+
+// Remove 删除key的计数，return 是否存在
+func (cs *CounterStore) Remove(key interface{}) bool {
+	var s = cs.shard(key)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var _, ok = s.items[key]
+	delete(s.items, key)
+	return ok
+}
+
+// Len 当前key个数，包括已过期但未回收的key
+func (cs *CounterStore) Len() int {
+	var n int
+	for i := range cs.shards {
+		var s = &cs.shards[i]
+		s.lock.Lock()
+		n += len(s.items)
+		s.lock.Unlock()
+	}
+	return n
+}
+
+func (cs *CounterStore) Clear() {
+	for i := range cs.shards {
+		var s = &cs.shards[i]
+		s.lock.Lock()
+		s.items = make(map[interface{}]*counter)
+		s.lock.Unlock()
+	}
+}
+
+// DeleteExpired 回收空闲过期的key
+func (cs *CounterStore) DeleteExpired() {
+	var now = cs.now()
+	for i := range cs.shards {
+		var s = &cs.shards[i]
+		s.lock.Lock()
+		for key, c := range s.items {
+			if c.expiration <= now {
+				delete(s.items, key)
+			}
+		}
+		s.lock.Unlock()
+	}
+}
+
+func (cs *CounterStore) bucketsOf(window time.Duration) int64 {
+	if window <= 0 {
+		return int64(cs.buckets)
+	}
+	var k = (int64(window) + cs.span - 1) / cs.span
+	if k > int64(cs.buckets) {
+		k = int64(cs.buckets)
+	}
+	return k
+}
+
+// 时间片 [from, to] 内的计数
+func (cs *CounterStore) sum(key interface{}, from, to int64) int64 {
+	var s = cs.shard(key)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var c, ok = s.items[key]
+	if !ok {
+		return 0
+	}
+	// 最后写入之后的时间片计数为0，早于窗口的时间片已被覆盖
+	if to > c.slot {
+		to = c.slot
+	}
+	if oldest := c.slot - int64(cs.buckets) + 1; from < oldest {
+		from = oldest
+	}
+	var total int64
+	for i := from; i <= to; i++ {
+		total += c.counts[i%int64(cs.buckets)]
+	}
+	return total
+}
+
+// 清零 (c.slot, slot] 内被复用的时间片
+func (cs *CounterStore) advance(c *counter, slot int64) {
+	if slot <= c.slot {
+		return
+	}
+	var n = slot - c.slot
+	if n >= int64(cs.buckets) {
+		for i := range c.counts {
+			c.counts[i] = 0
+		}
+		c.total = 0
+	} else {
+		for i := c.slot + 1; i <= slot; i++ {
+			var b = i % int64(cs.buckets)
+			c.total -= c.counts[b]
+			c.counts[b] = 0
+		}
+	}
+	c.slot = slot
+}
@@ -0,0 +1,84 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/1005281342/basic_component/cache"
+	"github.com/1005281342/basic_component/cache/cachetest"
+)
+
+func TestCounterStore(t *testing.T) {
+	var clock = cachetest.NewFakeClock()
+	var cs = cache.NewCounterStore(&cache.Opt{Clock: clock, Interval: time.Hour},
+		cache.CounterOpt{Window: 10 * time.Second, Buckets: 10})
+	// 对齐到时间片起点
+	clock.Advance(time.Duration(time.Second.Nanoseconds() - clock.Now().UnixNano()%time.Second.Nanoseconds()))
+
+	for i := 0; i < 10; i++ {
+		if total := cs.Incr("k", 1); total != int64(i+1) {
+			t.Fatalf("Incr = %d, want %d", total, i+1)
+		}
+		clock.Advance(time.Second)
+	}
+	// 当前时间片没有计数
+	for window, want := range map[time.Duration]int64{
+		time.Second:             0,
+		2 * time.Second:         1,
+		1500 * time.Millisecond: 1,
+		5 * time.Second:         4,
+		time.Hour:               9,
+		0:                       9,
+	} {
+		if n := cs.Count("k", window); n != want {
+			t.Fatalf("Count(k, %v) = %d, want %d", window, n, want)
+		}
+	}
+	if n := cs.Count("missing", 0); n != 0 {
+		t.Fatalf("Count(missing) = %d, want 0", n)
+	}
+
+	// 滑出窗口的计数不再统计
+	clock.Advance(5 * time.Second)
+	if total := cs.Incr("k", 10); total != 14 {
+		t.Fatalf("Incr = %d, want 14", total)
+	}
+
+	// 空闲过期
+	cs.Incr("idle", 1)
+	clock.Advance(11 * time.Second)
+	cs.Incr("k", 1)
+	cs.DeleteExpired()
+	if cs.Len() != 1 || cs.Count("idle", 0) != 0 {
+		t.Fatalf("Len() = %d, want 1 after idle expiration", cs.Len())
+	}
+}
+
+func TestCounterStoreTumbling(t *testing.T) {
+	var clock = cachetest.NewFakeClock()
+	var cs = cache.NewCounterStore(&cache.Opt{Clock: clock, Interval: time.Hour},
+		cache.CounterOpt{Window: time.Minute, Buckets: 60})
+	clock.Advance(time.Duration(10*time.Second.Nanoseconds() - clock.Now().UnixNano()%(10*time.Second.Nanoseconds())))
+	for i := 0; i < 15; i++ {
+		cs.Incr("k", 1)
+		clock.Advance(time.Second)
+	}
+	// 当前10s窗口内只有最后5次
+	if n := cs.CountTumbling("k", 10*time.Second); n != 5 {
+		t.Fatalf("CountTumbling = %d, want 5", n)
+	}
+	if n := cs.Count("k", 10*time.Second); n != 9 {
+		t.Fatalf("Count = %d, want 9", n)
+	}
+}
+
+func BenchmarkCounterStore_IncrParallel(b *testing.B) {
+	var cs = cache.NewCounterStore(&cache.Opt{}, cache.CounterOpt{})
+	b.RunParallel(func(pb *testing.PB) {
+		var i int
+		for pb.Next() {
+			cs.Incr(i&1023, 1)
+			i++
+		}
+	})
+}
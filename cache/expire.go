@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"math/rand"
 	"time"
 )
 
@@ -9,25 +10,88 @@ const (
 	NoExpiration time.Duration = -1
 	// 最小过期时间阈值
 	DefaultExpirationThreshold time.Duration = 0
+	// 更新元素时保留原有的过期时间，新元素永不过期，供原子操作内部使用
+	keepExpiration time.Duration = -2
 )
 
+// TimeSource 时钟，默认使用系统时间，测试时可注入假时钟
+type TimeSource interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// ExpireOpt 过期选项
+type ExpireOpt struct {
+	AfterAccess bool    // 访问后按存活时长重新计算过期时间（滑动过期）
+	Jitter      float64 // 存活时长随机抖动比例，取值[0, 1)，例如0.1表示±10%
+}
+
 type expire struct {
 	defaultExpiration time.Duration // 默认多长时间过期
+	expireOpt         ExpireOpt     // 默认过期选项
+	clock             TimeSource    // 时钟
 	watchdog                        // 看门狗，定期回收过期元素
 
 	// 协程池
 	goroutinePool
 }
 
-// 获取绝对时间
+// 获取当前时间
+func (e *expire) now() int64 {
+	return e.clock.Now().UnixNano()
+}
+
+// 获取绝对时间，按默认过期选项进行抖动
 func (e *expire) absoluteTime(d time.Duration) int64 {
 	var t int64
-	if d > 0 {
-		t = time.Now().Add(d).UnixNano()
+	if d = e.lifeSpan(d, e.expireOpt.Jitter); d > 0 {
+		t = e.clock.Now().Add(d).UnixNano()
 	}
 	return t
 }
 
+// 计算存活时长，jitter为随机抖动比例
+func (e *expire) lifeSpan(d time.Duration, jitter float64) time.Duration {
+	// 过期阈值校验
+	if d == DefaultExpirationThreshold {
+		d = e.defaultExpiration
+	}
+	if d > 0 && jitter > 0 {
+		d += time.Duration((2*rand.Float64() - 1) * jitter * float64(d))
+		if d <= 0 {
+			d = 1
+		}
+	}
+	return d
+}
+
+// 更新元素时的绝对过期时间，keepExpiration时保留原有的过期时间
+func (e *expire) renewTime(expiration int64, d time.Duration) int64 {
+	if d == keepExpiration {
+		return expiration
+	}
+	return e.absoluteTime(d)
+}
+
+// 按过期选项设置元素的过期时间
+func (e *expire) setExpire(it *item, d time.Duration, o ExpireOpt) {
+	if d == keepExpiration {
+		return
+	}
+	it.expiration, it.idle = 0, 0
+	if d = e.lifeSpan(d, o.Jitter); d > 0 {
+		it.expiration = e.clock.Now().Add(d).UnixNano()
+		if o.AfterAccess {
+			it.idle = int64(d)
+		}
+	}
+}
+
 // defaultExpiration 默认过期时间，interval看门狗回收间隔
 func newExpire(opt *Opt) *expire {
 	// 回收间隔不规范
@@ -38,9 +102,24 @@ func newExpire(opt *Opt) *expire {
 	if opt.DefaultExpiration <= DefaultExpirationThreshold {
 		opt.DefaultExpiration = NoExpiration
 	}
-	return &expire{
+	if opt.ExpireJitter < 0 || opt.ExpireJitter >= 1 {
+		opt.ExpireJitter = 0
+	}
+	var clock = opt.Clock
+	if clock == nil {
+		clock = realClock{}
+	}
+	var e = &expire{
 		defaultExpiration: opt.DefaultExpiration,
-		watchdog:          watchdog{stop: make(chan struct{}), interval: opt.Interval},
-		goroutinePool:     newGoroutinePool(opt.AntsPoolCapacity, opt.AntsOptionList...),
+		expireOpt:         ExpireOpt{AfterAccess: opt.ExpireAfterAccess, Jitter: opt.ExpireJitter},
+		clock:             clock,
+	}
+	if opt.shared != nil {
+		// 间隔为0时不启动看门狗，由持有者统一回收过期元素
+		e.goroutinePool = opt.shared.goroutinePool
+		return e
 	}
+	e.watchdog = watchdog{stop: make(chan struct{}), interval: opt.Interval}
+	e.goroutinePool = newGoroutinePool(opt.AntsPoolCapacity, opt.AntsOptionList...)
+	return e
 }
@@ -0,0 +1,100 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/1005281342/basic_component/cache"
+	"github.com/1005281342/basic_component/cache/cachetest"
+)
+
+var expireOptTypes = []struct {
+	ct   cache.CacheType
+	puts int
+}{
+	{cache.Simple, 1},
+	{cache.LRU, 1},
+	{cache.LFU, 1},
+	{cache.LRUk, cache.DefaultLruK},
+	{cache.LRU2q, 2},
+	{cache.LRUmq, 1},
+}
+
+func newExpireOptCache(t *testing.T, ct cache.CacheType, opt *cache.Opt) cache.ExpireOptCache {
+	opt.Capacity = 1000
+	opt.Interval = time.Hour
+	var c, err = cache.NewCache(ct, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c.(cache.ExpireOptCache)
+}
+
+func TestExpireAfterAccess(t *testing.T) {
+	for _, tt := range expireOptTypes {
+		t.Run(tt.ct.String(), func(t *testing.T) {
+			var clock = cachetest.NewFakeClock()
+			var c = newExpireOptCache(t, tt.ct, &cache.Opt{Clock: clock, ExpireAfterAccess: true})
+			for i := 0; i < tt.puts; i++ {
+				c.PutWithExpire("opt", 1, time.Second)
+				c.PutWithExpireOpt("call", 1, time.Second, cache.ExpireOpt{AfterAccess: true})
+				c.PutWithExpireOpt("fixed", 1, time.Second, cache.ExpireOpt{})
+			}
+			// 每次访问都延长过期时间
+			for i := 0; i < 3; i++ {
+				clock.Advance(800 * time.Millisecond)
+				for _, key := range []string{"opt", "call"} {
+					if _, ok := c.Get(key); !ok {
+						t.Fatalf("Get(%s) missed after %d accesses", key, i)
+					}
+				}
+			}
+			if _, ok := c.Get("fixed"); ok {
+				t.Fatal("fixed expiration should not slide")
+			}
+			clock.Advance(1100 * time.Millisecond)
+			for _, key := range []string{"opt", "call"} {
+				if _, ok := c.Get(key); ok {
+					t.Fatalf("Get(%s) hit after idle timeout", key)
+				}
+			}
+		})
+	}
+}
+
+func TestExpireJitter(t *testing.T) {
+	const n = 1000
+	for _, tt := range expireOptTypes {
+		t.Run(tt.ct.String(), func(t *testing.T) {
+			var clock = cachetest.NewFakeClock()
+			var c = newExpireOptCache(t, tt.ct, &cache.Opt{Clock: clock, ExpireJitter: 0.2})
+			for i := 0; i < n; i++ {
+				for j := 0; j < tt.puts; j++ {
+					c.PutWithExpire(i, i, 10*time.Second)
+				}
+			}
+			var alive = func() int {
+				var count int
+				for i := 0; i < n; i++ {
+					if _, ok := c.Get(i); ok {
+						count++
+					}
+				}
+				return count
+			}
+			clock.Advance(7900 * time.Millisecond)
+			if got := alive(); got != n {
+				t.Fatalf("alive at 7.9s = %d, want %d", got, n)
+			}
+			// ±20%的抖动应使过期时间分散在[8s, 12s]内
+			clock.Advance(2100 * time.Millisecond)
+			if got := alive(); got < n/4 || got > n*3/4 {
+				t.Fatalf("alive at 10s = %d, want spread around %d", got, n/2)
+			}
+			clock.Advance(2100 * time.Millisecond)
+			if got := alive(); got != 0 {
+				t.Fatalf("alive at 12.1s = %d, want 0", got)
+			}
+		})
+	}
+}
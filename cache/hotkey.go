@@ -0,0 +1,309 @@
+package cache
+
+import (
+	"container/heap"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+热点key统计：
+1. 滑动窗口划分为多个时间片，每个时间片使用 Space-Saving 算法统计，只跟踪固定个数的key；
+2. Space-Saving 跟踪的key已满时替换计数最小的key，新key的计数为被替换key的计数+1，误差为被替换key的计数；
+3. TopKeys 合并窗口内全部时间片的计数，计数与误差按采样率放大为估计值；
+4. HotKeyCache 在任意缓存外层按采样率统计 Get/Put 的key。
+*/
+
+const (
+	// 默认每个时间片跟踪的key数
+	DefaultHotKeyCapacity = 1024
+	// 默认滑动窗口长度
+	DefaultHotKeyWindow = time.Minute
+	// 默认滑动窗口的时间片数
+	DefaultHotKeyBuckets = 6
+)
+
+// HotKeyOps 统计的缓存操作
+type HotKeyOps int
+
+const (
+	HotKeyGet HotKeyOps = 1 << iota
+	HotKeyPut
+)
+
+// HotKeyOpt 热点key统计配置
+type HotKeyOpt struct {
+	Capacity   int           // 每个时间片跟踪的key数，默认1024
+	Window     time.Duration // 滑动窗口长度，默认1分钟
+	Buckets    int           // 滑动窗口的时间片数，默认6
+	SampleRate float64       // 采样率，取值(0, 1]，默认1
+	Ops        HotKeyOps     // HotKeyCache统计的操作，默认Get与Put
+	Clock      TimeSource    // 时钟，默认使用系统时间
+}
+
+// HotKey 热点key及其估计访问次数，真实次数在 [Count-Error, Count] 之间（不计采样误差）
+type HotKey struct {
+	Key   interface{}
+	Count uint64
+	Error uint64
+}
+
+type HotKeyTracker struct {
+	lock    sync.Mutex
+	opt     HotKeyOpt
+	span    int64          // 时间片长度（纳秒）
+	slot    int64          // 当前时间片序号
+	buckets []*spaceSaving // 按时间片序号取模的环形数组
+}
+
+func NewHotKeyTracker(opt HotKeyOpt) *HotKeyTracker {
+	if opt.Capacity <= 0 {
+		opt.Capacity = DefaultHotKeyCapacity
+	}
+	if opt.Window <= 0 {
+		opt.Window = DefaultHotKeyWindow
+	}
+	if opt.Buckets <= 0 {
+		opt.Buckets = DefaultHotKeyBuckets
+	}
+	if opt.SampleRate <= 0 || opt.SampleRate > 1 {
+		opt.SampleRate = 1
+	}
+	if opt.Ops == 0 {
+		opt.Ops = HotKeyGet | HotKeyPut
+	}
+	if opt.Clock == nil {
+		opt.Clock = realClock{}
+	}
+	var t = &HotKeyTracker{
+		opt:     opt,
+		span:    int64(opt.Window) / int64(opt.Buckets),
+		buckets: make([]*spaceSaving, opt.Buckets),
+	}
+	if t.span <= 0 {
+		t.span = 1
+	}
+	for i := range t.buckets {
+		t.buckets[i] = newSpaceSaving(opt.Capacity)
+	}
+	t.slot = opt.Clock.Now().UnixNano() / t.span
+	return t
+}
+
+// Record 按采样率记录一次访问
+func (t *HotKeyTracker) Record(key interface{}) {
+	if t.opt.SampleRate < 1 && rand.Float64() >= t.opt.SampleRate {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.rotate()
+	t.buckets[t.slot%int64(len(t.buckets))].add(key)
+}
+
+// TopKeys 滑动窗口内估计访问次数最多的n个key，按次数降序
+func (t *HotKeyTracker) TopKeys(n int) []HotKey {
+	if n <= 0 {
+		return nil
+	}
+	var merged = make(map[interface{}]*HotKey)
+	t.lock.Lock()
+	t.rotate()
+	for _, b := range t.buckets {
+		for key := range b.counters {
+			if _, ok := merged[key]; !ok {
+				merged[key] = &HotKey{Key: key}
+			}
+		}
+	}
+	for _, b := range t.buckets {
+		// 已满的时间片中未记录的key可能被替换过，次数不超过最小计数
+		var min uint64
+		if len(b.heap) > 0 && len(b.heap) == b.capacity {
+			min = b.heap[0].count
+		}
+		for key, hk := range merged {
+			if c, ok := b.counters[key]; ok {
+				hk.Count += c.count
+				hk.Error += c.error
+			} else {
+				hk.Count += min
+				hk.Error += min
+			}
+		}
+	}
+	t.lock.Unlock()
+
+	var keys = make([]HotKey, 0, len(merged))
+	for _, hk := range merged {
+		hk.Count = uint64(float64(hk.Count) / t.opt.SampleRate)
+		hk.Error = uint64(float64(hk.Error) / t.opt.SampleRate)
+		keys = append(keys, *hk)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Count != keys[j].Count {
+			return keys[i].Count > keys[j].Count
+		}
+		return keys[i].Error < keys[j].Error
+	})
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+	return keys
+}
+
+// Reset 清空统计
+func (t *HotKeyTracker) Reset() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for _, b := range t.buckets {
+		b.reset()
+	}
+}
+
+// 清空已滑出窗口的时间片
+func (t *HotKeyTracker) rotate() {
+	var slot = t.opt.Clock.Now().UnixNano() / t.span
+	if slot <= t.slot {
+		return
+	}
+	var n = slot - t.slot
+	if n > int64(len(t.buckets)) {
+		n = int64(len(t.buckets))
+	}
+	for i := int64(1); i <= n; i++ {
+		t.buckets[(t.slot+i)%int64(len(t.buckets))].reset()
+	}
+	t.slot = slot
+}
+
+// Space-Saving 计数器，按计数组成小顶堆
+type ssCounter struct {
+	key   interface{}
+	count uint64
+	error uint64
+	index int
+}
+
+type spaceSaving struct {
+	capacity int
+	counters map[interface{}]*ssCounter
+	heap     ssHeap
+}
+
+func newSpaceSaving(capacity int) *spaceSaving {
+	return &spaceSaving{capacity: capacity, counters: make(map[interface{}]*ssCounter)}
+}
+
+func (s *spaceSaving) add(key interface{}) {
+	if c, ok := s.counters[key]; ok {
+		c.count++
+		heap.Fix(&s.heap, c.index)
+		return
+	}
+	if len(s.heap) < s.capacity {
+		var c = &ssCounter{key: key, count: 1}
+		s.counters[key] = c
+		heap.Push(&s.heap, c)
+		return
+	}
+	// 替换计数最小的key
+	var c = s.heap[0]
+	delete(s.counters, c.key)
+	c.key, c.error = key, c.count
+	c.count++
+	s.counters[key] = c
+	heap.Fix(&s.heap, 0)
+}
+
+func (s *spaceSaving) reset() {
+	if len(s.heap) == 0 {
+		return
+	}
+	s.counters = make(map[interface{}]*ssCounter)
+	s.heap = s.heap[:0]
+}
+
+type ssHeap []*ssCounter
+
+func (h ssHeap) Len() int           { return len(h) }
+func (h ssHeap) Less(i, j int) bool { return h[i].count < h[j].count }
+func (h ssHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *ssHeap) Push(x interface{}) {
+	var c = x.(*ssCounter)
+	c.index = len(*h)
+	*h = append(*h, c)
+}
+
+func (h *ssHeap) Pop() interface{} {
+	var old = *h
+	var c = old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return c
+}
+
+// HotKeyCache 统计热点key的缓存
+type HotKeyCache struct {
+	ExpireCache
+	tracker *HotKeyTracker
+}
+
+func NewHotKeyCache(ct CacheType, opt *Opt, hopt HotKeyOpt) (*HotKeyCache, error) {
+	if hopt.Clock == nil {
+		hopt.Clock = opt.Clock
+	}
+	var c, err = NewCache(ct, opt)
+	if err != nil {
+		return nil, err
+	}
+	return TrackHotKeys(c, NewHotKeyTracker(hopt)), nil
+}
+
+// TrackHotKeys 在已有缓存外层统计热点key，可以包装 StoreCache 等其他包装缓存
+func TrackHotKeys(c ExpireCache, t *HotKeyTracker) *HotKeyCache {
+	return &HotKeyCache{ExpireCache: c, tracker: t}
+}
+
+func (hc *HotKeyCache) Get(key interface{}) (interface{}, bool) {
+	if hc.tracker.opt.Ops&HotKeyGet != 0 {
+		hc.tracker.Record(key)
+	}
+	return hc.ExpireCache.Get(key)
+}
+
+func (hc *HotKeyCache) Put(key, value interface{}) bool {
+	if hc.tracker.opt.Ops&HotKeyPut != 0 {
+		hc.tracker.Record(key)
+	}
+	return hc.ExpireCache.Put(key, value)
+}
+
+func (hc *HotKeyCache) PutWithExpire(key interface{}, value interface{}, lifeSpan time.Duration) bool {
+	if hc.tracker.opt.Ops&HotKeyPut != 0 {
+		hc.tracker.Record(key)
+	}
+	return hc.ExpireCache.PutWithExpire(key, value, lifeSpan)
+}
+
+// TopKeys 滑动窗口内估计访问次数最多的n个key
+func (hc *HotKeyCache) TopKeys(n int) []HotKey {
+	return hc.tracker.TopKeys(n)
+}
+
+// Tracker 热点key统计
+func (hc *HotKeyCache) Tracker() *HotKeyTracker {
+	return hc.tracker
+}
+
+// Unwrap 返回底层缓存
+func (hc *HotKeyCache) Unwrap() ExpireCache {
+	return hc.ExpireCache
+}
@@ -0,0 +1,112 @@
+package cache_test
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/1005281342/basic_component/cache"
+	"github.com/1005281342/basic_component/cache/cachetest"
+)
+
+func TestHotKeyTracker(t *testing.T) {
+	var clock = cachetest.NewFakeClock()
+	var tracker = cache.NewHotKeyTracker(cache.HotKeyOpt{Capacity: 50, Window: time.Minute, Buckets: 6, Clock: clock})
+
+	// 少量热点key混在大量冷key中
+	var rnd = rand.New(rand.NewSource(1))
+	for i := 0; i < 20000; i++ {
+		if rnd.Intn(4) == 0 {
+			tracker.Record(fmt.Sprintf("hot%d", rnd.Intn(3)))
+		} else {
+			tracker.Record(fmt.Sprintf("cold%d", rnd.Intn(10000)))
+		}
+	}
+	var top = tracker.TopKeys(3)
+	if len(top) != 3 {
+		t.Fatalf("TopKeys(3) = %v", top)
+	}
+	for _, hk := range top {
+		if hk.Key.(string)[:3] != "hot" || hk.Count-hk.Error < 1000 {
+			t.Fatalf("TopKeys(3) = %v, want hot keys", top)
+		}
+	}
+
+	// 滑出窗口
+	clock.Advance(30 * time.Second)
+	tracker.Record("recent")
+	tracker.Record("recent")
+	if top = tracker.TopKeys(1); top[0].Key != "hot0" && top[0].Key != "hot1" && top[0].Key != "hot2" {
+		t.Fatalf("TopKeys(1) = %v, want a hot key within window", top)
+	}
+	clock.Advance(40 * time.Second)
+	if top = tracker.TopKeys(10); len(top) != 1 || top[0].Key != "recent" || top[0].Count != 2 {
+		t.Fatalf("TopKeys(10) = %v, want [recent 2]", top)
+	}
+	clock.Advance(time.Hour)
+	if top = tracker.TopKeys(10); len(top) != 0 {
+		t.Fatalf("TopKeys(10) = %v, want empty", top)
+	}
+}
+
+func TestHotKeyTracker_Churn(t *testing.T) {
+	var clock = cachetest.NewFakeClock()
+	var tracker = cache.NewHotKeyTracker(cache.HotKeyOpt{Capacity: 2, Window: 2 * time.Minute, Buckets: 2, Clock: clock})
+	var truth = make(map[string]uint64)
+	var record = func(key string, n int) {
+		for i := 0; i < n; i++ {
+			tracker.Record(key)
+			truth[key]++
+		}
+	}
+
+	// b 在第一个时间片中被 c 替换，第二个时间片中再次出现
+	record("a", 3)
+	record("b", 2)
+	record("c", 2)
+	clock.Advance(time.Minute)
+	record("b", 5)
+
+	for _, hk := range tracker.TopKeys(10) {
+		var n = truth[hk.Key.(string)]
+		if n < hk.Count-hk.Error || n > hk.Count {
+			t.Fatalf("%v: true count %d not in [Count-Error, Count]", hk, n)
+		}
+	}
+}
+
+func TestHotKeyCache(t *testing.T) {
+	var c, err = cache.NewHotKeyCache(cache.LRU, &cache.Opt{Capacity: 10, Interval: time.Hour},
+		cache.HotKeyOpt{Ops: cache.HotKeyGet, SampleRate: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Put("a", 1)
+	for i := 0; i < 10000; i++ {
+		c.Get("a")
+		if i%10 == 0 {
+			c.Get("b")
+		}
+	}
+	var top = c.TopKeys(10)
+	if len(top) != 2 || top[0].Key != "a" {
+		t.Fatalf("TopKeys(10) = %v, want a, b", top)
+	}
+	// 采样后按采样率放大
+	if top[0].Count < 9000 || top[0].Count > 11000 {
+		t.Fatalf("Count(a) = %d, want about 10000", top[0].Count)
+	}
+}
+
+func BenchmarkHotKeyTracker_Record(b *testing.B) {
+	var tracker = cache.NewHotKeyTracker(cache.HotKeyOpt{})
+	var keys = make([]string, 4096)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tracker.Record(keys[i&4095])
+	}
+}
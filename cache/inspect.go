@@ -0,0 +1,435 @@
+package cache
+
+import (
+	"time"
+)
+
+/*
+查看缓存内部元素，用于调试与运维：
+1. Range 与 Peek 只读，持有读锁执行，不调整淘汰顺序、不设置访问位、不延长滑动过期时间；
+2. 已过期但未被回收的元素视为不存在；
+3. LIRS 的非驻留HIR元素、CLOCK-Pro 的测试元素不包含值，视为不存在。
+*/
+
+// 内部实现按key遍历元素与查询元素，调用方持有锁
+type inspector interface {
+	each(fn func(key interface{}, it *item) bool)
+	peek(key interface{}) (*item, bool)
+}
+
+// 剩余存活时长，永不过期返回NoExpiration
+func (e *expire) ttl(expiration int64, now int64) time.Duration {
+	if expiration == 0 {
+		return NoExpiration
+	}
+	return time.Duration(expiration - now)
+}
+
+func inspectRange(e *expire, in inspector, fn func(key, value interface{}, ttl time.Duration) bool) {
+	var now = e.now()
+	in.each(func(key interface{}, it *item) bool {
+		if it.expiredAt(now) {
+			return true
+		}
+		return fn(key, it.value, e.ttl(it.expiration, now))
+	})
+}
+
+func inspectPeek(e *expire, in inspector, key interface{}) (interface{}, time.Duration, bool) {
+	var it, ok = in.peek(key)
+	if !ok {
+		return nil, 0, false
+	}
+	var now = e.now()
+	if it.expiredAt(now) {
+		return nil, 0, false
+	}
+	return it.value, e.ttl(it.expiration, now), true
+}
+
+// 只读查询内置缓存中的元素，不视为访问，不改变淘汰顺序与访问频率
+func peekValue(c ExpireCache, key interface{}) (interface{}, bool) {
+	var ic, ok = c.(InspectCache)
+	if !ok {
+		return nil, false
+	}
+	var v, _, exists = ic.Peek(key)
+	return v, exists
+}
+
+// Simple
+func (sc *SimpleCache) Cap() int {
+	return 0
+}
+
+// Range 逐个分片持有读锁遍历
+func (sc *SimpleCache) Range(fn func(key, value interface{}, ttl time.Duration) bool) {
+	var now = sc.now()
+	for _, sh := range sc.shards {
+		sh.lock.RLock()
+		for k, it := range sh.items {
+			if it.expiredAt(now) {
+				continue
+			}
+			if !fn(k, it.value, sc.ttl(it.expiration, now)) {
+				sh.lock.RUnlock()
+				return
+			}
+		}
+		sh.lock.RUnlock()
+	}
+}
+
+func (sc *SimpleCache) Peek(key interface{}) (interface{}, time.Duration, bool) {
+	var sh = sc.shardOf(key)
+	sh.lock.RLock()
+	defer sh.lock.RUnlock()
+	var it, ok = sh.items[key]
+	if !ok {
+		return nil, 0, false
+	}
+	var now = sc.now()
+	if it.expiredAt(now) {
+		return nil, 0, false
+	}
+	return it.value, sc.ttl(it.expiration, now), true
+}
+
+// LRU
+func (c *lru) each(fn func(key interface{}, it *item) bool) {
+	for k, node := range c.items {
+		if !fn(k, &node.Value.(*entry).item) {
+			return
+		}
+	}
+}
+
+func (c *lru) peek(key interface{}) (*item, bool) {
+	if node, ok := c.items[key]; ok {
+		return &node.Value.(*entry).item, true
+	}
+	return nil, false
+}
+
+func (lc *LRUCache) Cap() int {
+	return lc.capacity
+}
+
+func (lc *LRUCache) Range(fn func(key, value interface{}, ttl time.Duration) bool) {
+	lc.lock.RLock()
+	defer lc.lock.RUnlock()
+	inspectRange(lc.expire, lc.lru, fn)
+}
+
+func (lc *LRUCache) Peek(key interface{}) (interface{}, time.Duration, bool) {
+	lc.lock.RLock()
+	defer lc.lock.RUnlock()
+	return inspectPeek(lc.expire, lc.lru, key)
+}
+
+// LFU
+func (c *lfu) each(fn func(key interface{}, it *item) bool) {
+	for k, node := range c.cache {
+		if !fn(k, &node.Value.(*entryWithFreq).item) {
+			return
+		}
+	}
+}
+
+func (c *lfu) peek(key interface{}) (*item, bool) {
+	if node, ok := c.cache[key]; ok {
+		return &node.Value.(*entryWithFreq).item, true
+	}
+	return nil, false
+}
+
+func (lc *LFUCache) Cap() int {
+	return lc.capacity
+}
+
+func (lc *LFUCache) Range(fn func(key, value interface{}, ttl time.Duration) bool) {
+	lc.lock.RLock()
+	defer lc.lock.RUnlock()
+	inspectRange(lc.expire, lc.lfu, fn)
+}
+
+func (lc *LFUCache) Peek(key interface{}) (interface{}, time.Duration, bool) {
+	lc.lock.RLock()
+	defer lc.lock.RUnlock()
+	return inspectPeek(lc.expire, lc.lfu, key)
+}
+
+// LRU-K，只包含缓存队列中的元素
+func (c *LRUkCache) Cap() int {
+	return c.cache.capacity
+}
+
+func (c *LRUkCache) Range(fn func(key, value interface{}, ttl time.Duration) bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	inspectRange(c.cache.expire, c.cache, fn)
+}
+
+func (c *LRUkCache) Peek(key interface{}) (interface{}, time.Duration, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return inspectPeek(c.cache.expire, c.cache, key)
+}
+
+// 2Q，只包含缓存队列中的元素
+func (c *LRU2QCache) Cap() int {
+	return c.cache.capacity
+}
+
+func (c *LRU2QCache) Range(fn func(key, value interface{}, ttl time.Duration) bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	inspectRange(c.cache.expire, c.cache, fn)
+}
+
+func (c *LRU2QCache) Peek(key interface{}) (interface{}, time.Duration, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return inspectPeek(c.cache.expire, c.cache, key)
+}
+
+// MQ
+func (c *lruMQ) each(fn func(key interface{}, it *item) bool) {
+	for k, node := range c.items {
+		if !fn(k, &node.Value.(*mqEntry).item) {
+			return
+		}
+	}
+}
+
+func (c *lruMQ) peek(key interface{}) (*item, bool) {
+	if node, ok := c.items[key]; ok {
+		return &node.Value.(*mqEntry).item, true
+	}
+	return nil, false
+}
+
+func (c *LRUMQCache) Cap() int {
+	return c.capacity
+}
+
+func (c *LRUMQCache) Range(fn func(key, value interface{}, ttl time.Duration) bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	inspectRange(c.expire, c.lruMQ, fn)
+}
+
+func (c *LRUMQCache) Peek(key interface{}) (interface{}, time.Duration, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return inspectPeek(c.expire, c.lruMQ, key)
+}
+
+// LIRS
+func (c *lirs) each(fn func(key interface{}, it *item) bool) {
+	for k, et := range c.items {
+		if et.status == lirsNonResident {
+			continue
+		}
+		if !fn(k, &et.item) {
+			return
+		}
+	}
+}
+
+func (c *lirs) peek(key interface{}) (*item, bool) {
+	if et, ok := c.items[key]; ok && et.status != lirsNonResident {
+		return &et.item, true
+	}
+	return nil, false
+}
+
+func (lc *LIRSCache) Cap() int {
+	return lc.capacity
+}
+
+func (lc *LIRSCache) Range(fn func(key, value interface{}, ttl time.Duration) bool) {
+	lc.lock.RLock()
+	defer lc.lock.RUnlock()
+	inspectRange(lc.expire, lc.lirs, fn)
+}
+
+func (lc *LIRSCache) Peek(key interface{}) (interface{}, time.Duration, bool) {
+	lc.lock.RLock()
+	defer lc.lock.RUnlock()
+	return inspectPeek(lc.expire, lc.lirs, key)
+}
+
+// CLOCK
+func (c *clock) each(fn func(key interface{}, it *item) bool) {
+	for k, idx := range c.items {
+		if !fn(k, &c.slots[idx].item) {
+			return
+		}
+	}
+}
+
+func (c *clock) peek(key interface{}) (*item, bool) {
+	if idx, ok := c.items[key]; ok {
+		return &c.slots[idx].item, true
+	}
+	return nil, false
+}
+
+func (cc *ClockCache) Cap() int {
+	return len(cc.slots)
+}
+
+func (cc *ClockCache) Range(fn func(key, value interface{}, ttl time.Duration) bool) {
+	cc.lock.RLock()
+	defer cc.lock.RUnlock()
+	inspectRange(cc.expire, cc.clock, fn)
+}
+
+func (cc *ClockCache) Peek(key interface{}) (interface{}, time.Duration, bool) {
+	cc.lock.RLock()
+	defer cc.lock.RUnlock()
+	return inspectPeek(cc.expire, cc.clock, key)
+}
+
+// CLOCK-Pro
+func (c *clockPro) each(fn func(key interface{}, it *item) bool) {
+	for k, r := range c.items {
+		var et = r.Value.(*clockProEntry)
+		if et.ptype == clockProTest {
+			continue
+		}
+		if !fn(k, &et.item) {
+			return
+		}
+	}
+}
+
+func (c *clockPro) peek(key interface{}) (*item, bool) {
+	if r, ok := c.items[key]; ok {
+		if et := r.Value.(*clockProEntry); et.ptype != clockProTest {
+			return &et.item, true
+		}
+	}
+	return nil, false
+}
+
+func (cc *ClockProCache) Cap() int {
+	return cc.memMax
+}
+
+func (cc *ClockProCache) Range(fn func(key, value interface{}, ttl time.Duration) bool) {
+	cc.lock.RLock()
+	defer cc.lock.RUnlock()
+	inspectRange(cc.expire, cc.clockPro, fn)
+}
+
+func (cc *ClockProCache) Peek(key interface{}) (interface{}, time.Duration, bool) {
+	cc.lock.RLock()
+	defer cc.lock.RUnlock()
+	return inspectPeek(cc.expire, cc.clockPro, key)
+}
+
+// S3-FIFO
+func (c *s3fifo) each(fn func(key interface{}, it *item) bool) {
+	for k, et := range c.items {
+		if !fn(k, &et.item) {
+			return
+		}
+	}
+}
+
+func (c *s3fifo) peek(key interface{}) (*item, bool) {
+	if et, ok := c.items[key]; ok {
+		return &et.item, true
+	}
+	return nil, false
+}
+
+func (sc *S3FIFOCache) Cap() int {
+	return sc.capacity
+}
+
+func (sc *S3FIFOCache) Range(fn func(key, value interface{}, ttl time.Duration) bool) {
+	sc.lock.RLock()
+	defer sc.lock.RUnlock()
+	inspectRange(sc.expire, sc.s3fifo, fn)
+}
+
+func (sc *S3FIFOCache) Peek(key interface{}) (interface{}, time.Duration, bool) {
+	sc.lock.RLock()
+	defer sc.lock.RUnlock()
+	return inspectPeek(sc.expire, sc.s3fifo, key)
+}
+
+// Adaptive，只包含当前策略的候选缓存中的元素
+func (ac *AdaptiveCache) current() InspectCache {
+	return ac.caches[ac.mode].(InspectCache)
+}
+
+func (ac *AdaptiveCache) Cap() int {
+	ac.lock.Lock()
+	defer ac.lock.Unlock()
+	return ac.current().Cap()
+}
+
+func (ac *AdaptiveCache) Range(fn func(key, value interface{}, ttl time.Duration) bool) {
+	ac.lock.Lock()
+	defer ac.lock.Unlock()
+	ac.current().Range(func(key, value interface{}, ttl time.Duration) bool {
+		return fn(key, value.(adaptiveValue).value, ttl)
+	})
+}
+
+func (ac *AdaptiveCache) Peek(key interface{}) (interface{}, time.Duration, bool) {
+	ac.lock.Lock()
+	defer ac.lock.Unlock()
+	var value, ttl, ok = ac.current().Peek(key)
+	if !ok {
+		return nil, 0, false
+	}
+	return value.(adaptiveValue).value, ttl, true
+}
+
+// Bytes，容量为预分配的字节数，值为副本
+func (bc *BytesCache) Cap() int {
+	var n int
+	for _, sg := range bc.segments {
+		n += len(sg.buf)
+	}
+	return n
+}
+
+func (bc *BytesCache) Range(fn func(key, value interface{}, ttl time.Duration) bool) {
+	var now = bc.now()
+	for _, sg := range bc.segments {
+		sg.lock.Lock()
+		for off := sg.head; off < sg.tail; {
+			var hd = sg.header(off)
+			if hd.flags&bytesFlagDeleted == 0 && !hd.expiredAt(now) &&
+				!fn(string(sg.key(off, hd)), sg.value(off, hd), bc.ttl(hd.expiration, now)) {
+				sg.lock.Unlock()
+				return
+			}
+			off += hd.size()
+		}
+		sg.lock.Unlock()
+	}
+}
+
+func (bc *BytesCache) Peek(key interface{}) (interface{}, time.Duration, bool) {
+	var k, ok = key.(string)
+	if !ok {
+		return nil, 0, false
+	}
+	var h = shardHash(k)
+	var sg = bc.segments[h&bc.mask]
+	sg.lock.Lock()
+	defer sg.lock.Unlock()
+	var off, hd, exist = sg.lookup(h, k)
+	var now = bc.now()
+	if !exist || hd.expiredAt(now) {
+		return nil, 0, false
+	}
+	return sg.value(off, hd), bc.ttl(hd.expiration, now), true
+}
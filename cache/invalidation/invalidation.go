@@ -0,0 +1,280 @@
+// Package invalidation 多副本本地缓存的失效广播
+//
+// 每个副本持有一个本地缓存，Remove/InvalidateTag/Clear 在本地生效后通过 Transport 广播失效消息，
+// 其他副本收到后删除本地副本。消息带有发送方序号，接收方发现序号空洞（消息丢失）时清空本地缓存。
+//
+//	var hub = invalidation.NewHub()
+//	var a, _ = invalidation.New(cache.LRU, &cache.Opt{Capacity: 1000}, hub.Transport(), nil)
+//	var b, _ = invalidation.New(cache.LRU, &cache.Opt{Capacity: 1000}, hub.Transport(), nil)
+//	a.Remove("k") // b 中的 "k" 同时失效
+package invalidation
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/1005281342/basic_component/cache"
+)
+
+// Opt 失效广播配置
+type Opt struct {
+	Origin  string      // 副本标识，需要全局唯一，默认为 主机名-进程号-随机数
+	OnError func(error) // 广播失败或收到无法解析的消息时回调
+}
+
+/*
+广播失效的缓存：
+1. Put 只写入本地，数据源更新后需要调用 Remove 使其他副本失效；
+2. PutWithTags 为元素打标签，InvalidateTag 使全部副本中带该标签的元素失效；
+3. 每个发送方的序号从1开始连续递增，接收方记录每个发送方最后的序号，重复或过期的消息忽略，出现空洞时清空本地缓存；
+4. 副本重启后使用新的标识，避免序号回退被当作过期消息。
+*/
+type Cache struct {
+	cache     cache.ExpireCache
+	transport Transport
+	origin    string
+	onError   func(error)
+	seq       uint64     // 本副本最后发送的序号
+	pubLock   sync.Mutex // 串行化序号分配与广播，保证消息按序号顺序发出
+
+	lock    sync.Mutex
+	tags    map[string]map[interface{}]struct{} // 标签 -> key
+	values  map[interface{}]*taggedValue        // 带标签的key -> 当前值
+	lastSeq map[string]uint64                   // 发送方 -> 最后接收的序号
+	gaps    uint64                              // 发现的序号空洞次数
+}
+
+// 带标签的元素值，淘汰回调时比较指针判断是否为当前值
+type taggedValue struct {
+	value interface{}
+	tags  []string
+}
+
+func New(ct cache.CacheType, opt *cache.Opt, transport Transport, iopt *Opt) (*Cache, error) {
+	var c = &Cache{
+		transport: transport,
+		tags:      make(map[string]map[interface{}]struct{}),
+		values:    make(map[interface{}]*taggedValue),
+		lastSeq:   make(map[string]uint64),
+	}
+	if iopt != nil {
+		c.origin, c.onError = iopt.Origin, iopt.OnError
+	}
+	if c.origin == "" {
+		var host, _ = os.Hostname()
+		c.origin = fmt.Sprintf("%s-%d-%d", host, os.Getpid(), rand.New(rand.NewSource(time.Now().UnixNano())).Int63())
+	}
+	var onEvict = opt.Callback
+	var cacheOpt = *opt
+	cacheOpt.Callback = func(key interface{}, value interface{}) {
+		if tv, ok := value.(*taggedValue); ok {
+			c.untag(key, tv)
+			value = tv.value
+		}
+		if onEvict != nil {
+			onEvict(key, value)
+		}
+	}
+	var lc, err = cache.NewCache(ct, &cacheOpt)
+	if err != nil {
+		return nil, err
+	}
+	c.cache = lc
+	transport.Subscribe(c.receive)
+	return c, nil
+}
+
+// Origin 副本标识
+func (c *Cache) Origin() string {
+	return c.origin
+}
+
+// Gaps 发现的序号空洞次数，每次都会清空本地缓存
+func (c *Cache) Gaps() uint64 {
+	return atomic.LoadUint64(&c.gaps)
+}
+
+func (c *Cache) Get(key interface{}) (interface{}, bool) {
+	var value, ok = c.cache.Get(key)
+	if tv, tagged := value.(*taggedValue); tagged {
+		value = tv.value
+	}
+	return value, ok
+}
+
+func (c *Cache) Put(key, value interface{}) bool {
+	return c.PutWithExpire(key, value, cache.NoExpiration)
+}
+
+func (c *Cache) PutWithExpire(key interface{}, value interface{}, lifeSpan time.Duration) bool {
+	return c.PutWithTags(key, value, lifeSpan)
+}
+
+// PutWithTags 写入本地缓存并打标签，覆盖原有标签
+func (c *Cache) PutWithTags(key interface{}, value interface{}, lifeSpan time.Duration, tags ...string) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if old, ok := c.values[key]; ok {
+		c.untagLocked(key, old)
+	}
+	if len(tags) == 0 {
+		return c.cache.PutWithExpire(key, value, lifeSpan)
+	}
+	var tv = &taggedValue{value: value, tags: tags}
+	c.values[key] = tv
+	for _, tag := range tags {
+		var keys, ok = c.tags[tag]
+		if !ok {
+			keys = make(map[interface{}]struct{})
+			c.tags[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+	return c.cache.PutWithExpire(key, tv, lifeSpan)
+}
+
+// Remove 删除本地元素并广播，return 本地是否存在
+func (c *Cache) Remove(key interface{}) bool {
+	var ok = c.cache.Remove(key)
+	c.publish(Message{Kind: KindKey, Key: key})
+	return ok
+}
+
+// InvalidateTag 删除本地带该标签的元素并广播，return 本地删除的元素个数
+func (c *Cache) InvalidateTag(tag string) int {
+	var n = c.removeTag(tag)
+	c.publish(Message{Kind: KindTag, Tag: tag})
+	return n
+}
+
+// Clear 清空本地缓存并广播
+func (c *Cache) Clear() {
+	c.clearLocal()
+	c.publish(Message{Kind: KindClear})
+}
+
+func (c *Cache) Len() int {
+	return c.cache.Len()
+}
+
+func (c *Cache) DeleteExpired() {
+	c.cache.DeleteExpired()
+}
+
+// Close 关闭广播通道
+func (c *Cache) Close() error {
+	return c.transport.Close()
+}
+
+func (c *Cache) error(err error) {
+	if c.onError != nil {
+		c.onError(err)
+	}
+}
+
+func (c *Cache) publish(m Message) {
+	m.Origin = c.origin
+	// 无法编码的key不占用序号，避免接收方误判空洞
+	if m.Kind == KindKey && !validKey(m.Key) {
+		c.error(fmt.Errorf("%w: %T", ErrUnsupportedKey, m.Key))
+		return
+	}
+	c.pubLock.Lock()
+	defer c.pubLock.Unlock()
+	c.seq++
+	m.Seq = c.seq
+	var b, err = m.Encode()
+	if err == nil {
+		err = c.transport.Publish(b)
+	}
+	if err != nil {
+		c.error(err)
+	}
+}
+
+func (c *Cache) receive(b []byte) {
+	var m, err = DecodeMessage(b)
+	if err != nil {
+		c.error(err)
+		return
+	}
+	if m.Origin == c.origin {
+		return
+	}
+
+	c.lock.Lock()
+	var last, known = c.lastSeq[m.Origin]
+	if known && m.Seq <= last {
+		// 重复或过期的消息
+		c.lock.Unlock()
+		return
+	}
+	c.lastSeq[m.Origin] = m.Seq
+	var gap = known && m.Seq != last+1
+	c.lock.Unlock()
+
+	if gap {
+		atomic.AddUint64(&c.gaps, 1)
+		c.clearLocal()
+		return
+	}
+	switch m.Kind {
+	case KindKey:
+		c.cache.Remove(m.Key)
+	case KindTag:
+		c.removeTag(m.Tag)
+	case KindClear:
+		c.clearLocal()
+	}
+}
+
+func (c *Cache) removeTag(tag string) int {
+	c.lock.Lock()
+	var keys = make([]interface{}, 0, len(c.tags[tag]))
+	for key := range c.tags[tag] {
+		keys = append(keys, key)
+	}
+	c.lock.Unlock()
+	var n int
+	for _, key := range keys {
+		if c.cache.Remove(key) {
+			n++
+		}
+	}
+	return n
+}
+
+// 先在锁内替换标签索引再清空缓存，清空期间写入的元素记录在新索引中，随后被清空时由淘汰回调清理；
+// 清空缓存时同步执行淘汰回调，回调需要获取c.lock，不能持有锁清空
+func (c *Cache) clearLocal() {
+	c.lock.Lock()
+	c.tags = make(map[string]map[interface{}]struct{})
+	c.values = make(map[interface{}]*taggedValue)
+	c.lock.Unlock()
+	c.cache.Clear()
+}
+
+// 淘汰回调，只清理仍为当前值的标签
+func (c *Cache) untag(key interface{}, tv *taggedValue) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.values[key] == tv {
+		c.untagLocked(key, tv)
+	}
+}
+
+func (c *Cache) untagLocked(key interface{}, tv *taggedValue) {
+	delete(c.values, key)
+	for _, tag := range tv.tags {
+		if keys, ok := c.tags[tag]; ok {
+			delete(keys, key)
+			if len(keys) == 0 {
+				delete(c.tags, tag)
+			}
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package invalidation_test
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/1005281342/basic_component/cache"
+	"github.com/1005281342/basic_component/cache/invalidation"
+)
+
+func newReplica(t *testing.T, tr invalidation.Transport, origin string) *invalidation.Cache {
+	var c, err = invalidation.New(cache.LRU, &cache.Opt{Capacity: 100, Interval: time.Hour}, tr,
+		&invalidation.Opt{Origin: origin, OnError: func(err error) { t.Errorf("%s: %v", origin, err) }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func mustMiss(t *testing.T, c *invalidation.Cache, key interface{}) {
+	t.Helper()
+	if v, ok := c.Get(key); ok {
+		t.Fatalf("%s Get(%v) = %v, want miss", c.Origin(), key, v)
+	}
+}
+
+func mustHit(t *testing.T, c *invalidation.Cache, key interface{}) {
+	t.Helper()
+	if _, ok := c.Get(key); !ok {
+		t.Fatalf("%s Get(%v) miss", c.Origin(), key)
+	}
+}
+
+func TestMessage(t *testing.T) {
+	for _, m := range []invalidation.Message{
+		{Origin: "a", Seq: 1, Kind: invalidation.KindKey, Key: "k"},
+		{Origin: "a", Seq: 2, Kind: invalidation.KindKey, Key: -3},
+		{Origin: "a", Seq: 3, Kind: invalidation.KindKey, Key: int64(4)},
+		{Origin: "a", Seq: 4, Kind: invalidation.KindKey, Key: uint64(5)},
+		{Origin: "a", Seq: 5, Kind: invalidation.KindTag, Tag: "user:1"},
+		{Origin: "a", Seq: 6, Kind: invalidation.KindClear},
+	} {
+		var b, err = m.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got invalidation.Message
+		if got, err = invalidation.DecodeMessage(b); err != nil || got != m {
+			t.Fatalf("DecodeMessage = %+v, %v, want %+v", got, err, m)
+		}
+		if _, err = invalidation.DecodeMessage(b[:len(b)-1]); err == nil && m.Kind != invalidation.KindClear {
+			t.Fatalf("DecodeMessage(truncated %+v) succeeded", m)
+		}
+	}
+	if _, err := (invalidation.Message{Kind: invalidation.KindKey, Key: 1.5}).Encode(); err == nil {
+		t.Fatalf("Encode(float key) succeeded")
+	}
+}
+
+func TestInvalidation(t *testing.T) {
+	var hub = invalidation.NewHub()
+	var a = newReplica(t, hub.Transport(), "a")
+	var b = newReplica(t, hub.Transport(), "b")
+	for _, c := range []*invalidation.Cache{a, b} {
+		c.Put("k", 1)
+		c.PutWithTags("u1", 1, cache.NoExpiration, "user")
+		c.PutWithTags("u2", 2, cache.NoExpiration, "user", "vip")
+		c.Put(7, 7)
+	}
+
+	a.Remove("k")
+	mustMiss(t, b, "k")
+	a.Remove(7)
+	mustMiss(t, b, 7)
+
+	if n := b.InvalidateTag("vip"); n != 1 {
+		t.Fatalf("InvalidateTag(vip) = %d, want 1", n)
+	}
+	mustMiss(t, a, "u2")
+	mustHit(t, a, "u1")
+
+	// 重新写入后标签被覆盖
+	a.PutWithTags("u1", 1, cache.NoExpiration)
+	b.InvalidateTag("user")
+	mustHit(t, a, "u1")
+	mustMiss(t, b, "u1")
+
+	b.Clear()
+	mustMiss(t, a, "u1")
+	if a.Gaps() != 0 || b.Gaps() != 0 {
+		t.Fatalf("unexpected gaps: %d, %d", a.Gaps(), b.Gaps())
+	}
+}
+
+// 清空期间写入的带标签元素仍能按标签失效
+func TestInvalidationClearTags(t *testing.T) {
+	var (
+		c    *invalidation.Cache
+		once sync.Once
+		wg   sync.WaitGroup
+	)
+	var onEvict = func(key, value interface{}) {
+		once.Do(func() {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				c.PutWithTags("b", 2, cache.NoExpiration, "t")
+			}()
+			// 等待写入持有标签索引锁后阻塞在缓存锁上
+			time.Sleep(10 * time.Millisecond)
+		})
+	}
+	var err error
+	c, err = invalidation.New(cache.LRU, &cache.Opt{Capacity: 100, Interval: time.Hour, Callback: onEvict},
+		invalidation.NewHub().Transport(), &invalidation.Opt{Origin: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.PutWithTags("a", 1, cache.NoExpiration, "t")
+	c.Clear()
+	wg.Wait()
+	mustHit(t, c, "b")
+	if n := c.InvalidateTag("t"); n != 1 {
+		t.Fatalf("InvalidateTag(t) = %d, want 1", n)
+	}
+	mustMiss(t, c, "b")
+}
+
+// 序号空洞时清空本地缓存，重复消息忽略
+func TestInvalidationGap(t *testing.T) {
+	var hub = invalidation.NewHub()
+	var a = newReplica(t, hub.Transport(), "a")
+	var raw = hub.Transport()
+	var send = func(seq uint64, key string) {
+		var b, _ = invalidation.Message{Origin: "x", Seq: seq, Kind: invalidation.KindKey, Key: key}.Encode()
+		_ = raw.Publish(b)
+	}
+	a.Put("k1", 1)
+	a.Put("k2", 2)
+	send(5, "k1")
+	mustMiss(t, a, "k1")
+	mustHit(t, a, "k2")
+
+	send(5, "k2")
+	mustHit(t, a, "k2")
+
+	send(7, "none")
+	mustMiss(t, a, "k2")
+	if a.Gaps() != 1 {
+		t.Fatalf("Gaps() = %d, want 1", a.Gaps())
+	}
+}
+
+// 并发广播按序号顺序到达，不会误判为空洞
+func TestInvalidationConcurrentPublish(t *testing.T) {
+	var hub = invalidation.NewHub()
+	var a = newReplica(t, hub.Transport(), "a")
+	var b = newReplica(t, hub.Transport(), "b")
+	var wg sync.WaitGroup
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				a.Remove(fmt.Sprintf("k%d-%d", g, i))
+			}
+		}(g)
+	}
+	wg.Wait()
+	if n := b.Gaps(); n != 0 {
+		t.Fatalf("Gaps() = %d, want 0", n)
+	}
+}
+
+func TestTCPTransport(t *testing.T) {
+	var ta, err = invalidation.NewTCPTransport("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tb *invalidation.TCPTransport
+	if tb, err = invalidation.NewTCPTransport("127.0.0.1:0", ta.Addr().String()); err != nil {
+		t.Fatal(err)
+	}
+	ta.AddPeer(tb.Addr().String())
+	var a = newReplica(t, ta, "a")
+	var b = newReplica(t, tb, "b")
+	defer a.Close()
+	defer b.Close()
+
+	a.Put("k", 1)
+	b.Put("k", 1)
+	b.Remove("k")
+	var deadline = time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if _, ok := a.Get("k"); !ok {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("k not invalidated over TCP")
+}
+
+// 对端不可用时 Publish 不阻塞，退避期间不重复连接
+func TestTCPTransportDeadPeer(t *testing.T) {
+	var ln, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dead = ln.Addr().String()
+	_ = ln.Close()
+
+	var tr *invalidation.TCPTransport
+	if tr, err = invalidation.NewTCPTransport("127.0.0.1:0", dead); err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Close()
+	var dials int32
+	tr.OnError = func(error) { atomic.AddInt32(&dials, 1) }
+	var start = time.Now()
+	for i := 0; i < 100; i++ {
+		if err = tr.Publish([]byte("msg")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d > 100*time.Millisecond {
+		t.Fatalf("100 publishes took %v", d)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt32(&dials); n < 1 || n > 2 {
+		t.Fatalf("dial failures = %d, want 1 within backoff", n)
+	}
+}
@@ -0,0 +1,182 @@
+package invalidation
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Kind 失效消息类型
+type Kind uint8
+
+const (
+	KindKey   Kind = iota + 1 // 按key失效
+	KindTag                   // 按标签失效
+	KindClear                 // 清空
+)
+
+const messageVersion = 1
+
+var (
+	ErrUnsupportedKey = errors.New("unsupported key type")
+	ErrBadMessage     = errors.New("malformed invalidation message")
+)
+
+// key类型编码
+const (
+	keyString byte = iota + 1
+	keyInt
+	keyInt64
+	keyUint64
+)
+
+// Message 失效消息
+//
+// 编码格式：版本(1) 类型(1) 来源长度(uvarint) 来源 序号(uvarint) 载荷
+// 载荷：KindKey 为 key类型(1) 与 key；KindTag 为标签长度(uvarint) 与标签；KindClear 为空
+type Message struct {
+	Origin string      // 发送方标识
+	Seq    uint64      // 发送方内单调递增的序号，从1开始
+	Kind   Kind        // 消息类型
+	Key    interface{} // KindKey 时有效，支持string、int、int64、uint64
+	Tag    string      // KindTag 时有效
+}
+
+func (m Message) Encode() ([]byte, error) {
+	var buf = make([]byte, 0, 16+len(m.Origin)+len(m.Tag))
+	buf = append(buf, messageVersion, byte(m.Kind))
+	buf = appendString(buf, m.Origin)
+	buf = appendUvarint(buf, m.Seq)
+	switch m.Kind {
+	case KindKey:
+		switch k := m.Key.(type) {
+		case string:
+			buf = appendString(append(buf, keyString), k)
+		case int:
+			buf = appendVarint(append(buf, keyInt), int64(k))
+		case int64:
+			buf = appendVarint(append(buf, keyInt64), k)
+		case uint64:
+			buf = appendUvarint(append(buf, keyUint64), k)
+		default:
+			return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, m.Key)
+		}
+	case KindTag:
+		buf = appendString(buf, m.Tag)
+	case KindClear:
+	default:
+		return nil, fmt.Errorf("unknown message kind %d", m.Kind)
+	}
+	return buf, nil
+}
+
+func DecodeMessage(b []byte) (Message, error) {
+	var m Message
+	var d = decoder{b: b}
+	if d.byte() != messageVersion {
+		return m, ErrBadMessage
+	}
+	m.Kind = Kind(d.byte())
+	m.Origin = d.string()
+	m.Seq = d.uvarint()
+	switch m.Kind {
+	case KindKey:
+		switch d.byte() {
+		case keyString:
+			m.Key = d.string()
+		case keyInt:
+			m.Key = int(d.varint())
+		case keyInt64:
+			m.Key = d.varint()
+		case keyUint64:
+			m.Key = d.uvarint()
+		default:
+			return m, ErrBadMessage
+		}
+	case KindTag:
+		m.Tag = d.string()
+	case KindClear:
+	default:
+		return m, ErrBadMessage
+	}
+	if d.err || len(d.b) != 0 {
+		return m, ErrBadMessage
+	}
+	return m, nil
+}
+
+// 是否为支持广播的key类型
+func validKey(key interface{}) bool {
+	switch key.(type) {
+	case string, int, int64, uint64:
+		return true
+	}
+	return false
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	return append(buf, b[:binary.PutUvarint(b[:], v)]...)
+}
+
+func appendVarint(buf []byte, v int64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	return append(buf, b[:binary.PutVarint(b[:], v)]...)
+}
+
+func appendString(buf []byte, s string) []byte {
+	return append(appendUvarint(buf, uint64(len(s))), s...)
+}
+
+// 解码器，出错后后续读取均返回零值
+type decoder struct {
+	b   []byte
+	err bool
+}
+
+func (d *decoder) byte() byte {
+	if d.err || len(d.b) == 0 {
+		d.err = true
+		return 0
+	}
+	var c = d.b[0]
+	d.b = d.b[1:]
+	return c
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err {
+		return 0
+	}
+	var v, n = binary.Uvarint(d.b)
+	if n <= 0 {
+		d.err = true
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	if d.err {
+		return 0
+	}
+	var v, n = binary.Varint(d.b)
+	if n <= 0 {
+		d.err = true
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) string() string {
+	var n = d.uvarint()
+	if d.err || uint64(len(d.b)) < n {
+		d.err = true
+		return ""
+	}
+	var s = string(d.b[:n])
+	d.b = d.b[n:]
+	return s
+}
@@ -0,0 +1,271 @@
+package invalidation
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// 单条消息最大字节数
+	maxFrameSize = 1 << 20
+	// 默认连接超时
+	DefaultDialTimeout = time.Second
+	// 默认写超时
+	DefaultWriteTimeout = time.Second
+	// 默认每个对端的发送队列长度
+	DefaultQueueSize = 1024
+	// 连接失败后的初始重连间隔，每次失败翻倍
+	DefaultRedialBackoff = 100 * time.Millisecond
+	// 最大重连间隔
+	DefaultMaxRedialBackoff = 10 * time.Second
+)
+
+var (
+	ErrClosed = errors.New("transport closed")
+	// 对端发送队列已满，消息被丢弃
+	ErrQueueFull = errors.New("peer queue full")
+)
+
+/*
+TCPTransport TCP扇出广播：
+1. 每个副本监听一个地址，并与全部对端维持长连接，消息以4字节长度前缀分帧；
+2. 每个对端一个发送队列与发送协程，Publish 只入队不阻塞，连接与写入在发送协程中进行；
+3. 发送失败时关闭连接，连接失败后按指数退避重连，退避期间的消息直接丢弃，由接收方通过序号空洞发现；
+4. 对端地址通过 AddPeer/RemovePeer 维护；超时、队列长度与 OnError 需要在首次 Publish 前设置。
+*/
+type TCPTransport struct {
+	ln           net.Listener
+	DialTimeout  time.Duration
+	WriteTimeout time.Duration
+	QueueSize    int         // 每个对端的发送队列长度
+	OnError      func(error) // 发送协程中连接或写入失败时回调
+
+	handler atomic.Value // func([]byte)
+
+	lock   sync.Mutex
+	peers  map[string]*tcpPeer   // 对端地址 -> 发送协程，nil表示尚未启动
+	conns  map[net.Conn]struct{} // 接收连接
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// 对端的发送队列
+type tcpPeer struct {
+	addr  string
+	queue chan []byte
+	stop  chan struct{}
+}
+
+// NewTCPTransport 监听addr并连接对端peers
+func NewTCPTransport(addr string, peers ...string) (*TCPTransport, error) {
+	var ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	var t = &TCPTransport{
+		ln:           ln,
+		DialTimeout:  DefaultDialTimeout,
+		WriteTimeout: DefaultWriteTimeout,
+		QueueSize:    DefaultQueueSize,
+		peers:        make(map[string]*tcpPeer),
+		conns:        make(map[net.Conn]struct{}),
+	}
+	for _, p := range peers {
+		t.peers[p] = nil
+	}
+	t.wg.Add(1)
+	go t.accept()
+	return t, nil
+}
+
+// Addr 监听地址
+func (t *TCPTransport) Addr() net.Addr {
+	return t.ln.Addr()
+}
+
+func (t *TCPTransport) AddPeer(addr string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if _, ok := t.peers[addr]; !ok {
+		t.peers[addr] = nil
+	}
+}
+
+func (t *TCPTransport) RemovePeer(addr string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if p := t.peers[addr]; p != nil {
+		close(p.stop)
+	}
+	delete(t.peers, addr)
+}
+
+func (t *TCPTransport) Subscribe(handler func([]byte)) {
+	t.handler.Store(handler)
+}
+
+// Publish 放入全部对端的发送队列，return 队列已满的错误
+func (t *TCPTransport) Publish(msg []byte) error {
+	if len(msg) > maxFrameSize {
+		return ErrBadMessage
+	}
+	var frame = make([]byte, 4+len(msg))
+	binary.BigEndian.PutUint32(frame, uint32(len(msg)))
+	copy(frame[4:], msg)
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.closed {
+		return ErrClosed
+	}
+	var lastErr error
+	for addr, p := range t.peers {
+		if p == nil {
+			p = t.startPeer(addr)
+		}
+		select {
+		case p.queue <- frame:
+		default:
+			lastErr = ErrQueueFull
+		}
+	}
+	return lastErr
+}
+
+// 启动对端的发送协程，需要持有t.lock
+func (t *TCPTransport) startPeer(addr string) *tcpPeer {
+	var size = t.QueueSize
+	if size <= 0 {
+		size = DefaultQueueSize
+	}
+	var p = &tcpPeer{addr: addr, queue: make(chan []byte, size), stop: make(chan struct{})}
+	t.peers[addr] = p
+	t.wg.Add(1)
+	go t.write(p)
+	return p
+}
+
+func (t *TCPTransport) error(err error) {
+	if t.OnError != nil {
+		t.OnError(err)
+	}
+}
+
+// 发送协程，按需连接，连接失败后退避期间丢弃消息
+func (t *TCPTransport) write(p *tcpPeer) {
+	defer t.wg.Done()
+	var (
+		conn     net.Conn
+		backoff  = DefaultRedialBackoff
+		nextDial time.Time
+	)
+	defer func() {
+		if conn != nil {
+			_ = conn.Close()
+		}
+	}()
+	for {
+		var frame []byte
+		select {
+		case <-p.stop:
+			return
+		case frame = <-p.queue:
+		}
+		if conn == nil {
+			if time.Now().Before(nextDial) {
+				continue
+			}
+			var err error
+			if conn, err = net.DialTimeout("tcp", p.addr, t.DialTimeout); err != nil {
+				t.error(err)
+				nextDial = time.Now().Add(backoff)
+				if backoff *= 2; backoff > DefaultMaxRedialBackoff {
+					backoff = DefaultMaxRedialBackoff
+				}
+				continue
+			}
+			backoff = DefaultRedialBackoff
+		}
+		_ = conn.SetWriteDeadline(time.Now().Add(t.WriteTimeout))
+		if _, err := conn.Write(frame); err != nil {
+			t.error(err)
+			_ = conn.Close()
+			conn = nil
+		}
+	}
+}
+
+func (t *TCPTransport) accept() {
+	defer t.wg.Done()
+	for {
+		var conn, err = t.ln.Accept()
+		if err != nil {
+			return
+		}
+		t.lock.Lock()
+		if t.closed {
+			t.lock.Unlock()
+			_ = conn.Close()
+			return
+		}
+		t.conns[conn] = struct{}{}
+		t.lock.Unlock()
+		t.wg.Add(1)
+		go t.read(conn)
+	}
+}
+
+func (t *TCPTransport) read(conn net.Conn) {
+	defer t.wg.Done()
+	defer func() {
+		_ = conn.Close()
+		t.lock.Lock()
+		delete(t.conns, conn)
+		t.lock.Unlock()
+	}()
+	var r = bufio.NewReader(conn)
+	var head [4]byte
+	for {
+		if _, err := io.ReadFull(r, head[:]); err != nil {
+			return
+		}
+		var n = binary.BigEndian.Uint32(head[:])
+		if n > maxFrameSize {
+			return
+		}
+		var msg = make([]byte, n)
+		if _, err := io.ReadFull(r, msg); err != nil {
+			return
+		}
+		if handler, ok := t.handler.Load().(func([]byte)); ok {
+			handler(msg)
+		}
+	}
+}
+
+func (t *TCPTransport) Close() error {
+	t.lock.Lock()
+	if t.closed {
+		t.lock.Unlock()
+		return nil
+	}
+	t.closed = true
+	for _, p := range t.peers {
+		if p != nil {
+			close(p.stop)
+		}
+	}
+	for conn := range t.conns {
+		_ = conn.Close()
+	}
+	t.lock.Unlock()
+	var err = t.ln.Close()
+	t.wg.Wait()
+	return err
+}
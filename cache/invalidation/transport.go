@@ -0,0 +1,82 @@
+package invalidation
+
+import (
+	"sync"
+)
+
+// Transport 失效消息的广播通道，需要并发安全
+type Transport interface {
+	// 广播消息到其他副本，msg在返回后可能被复用，需要复制
+	Publish(msg []byte) error
+	// 注册接收回调，只调用一次，handler可能被并发调用
+	Subscribe(handler func(msg []byte))
+	Close() error
+}
+
+// Hub 进程内广播，用于测试与单进程多缓存实例
+type Hub struct {
+	lock sync.RWMutex
+	subs []*hubTransport
+}
+
+func NewHub() *Hub {
+	return &Hub{}
+}
+
+// Transport 创建连接到该Hub的通道，消息同步投递给其他通道
+func (h *Hub) Transport() Transport {
+	var t = &hubTransport{hub: h}
+	h.lock.Lock()
+	h.subs = append(h.subs, t)
+	h.lock.Unlock()
+	return t
+}
+
+type hubTransport struct {
+	hub     *Hub
+	lock    sync.RWMutex
+	handler func([]byte)
+	closed  bool
+}
+
+func (t *hubTransport) Publish(msg []byte) error {
+	t.hub.lock.RLock()
+	var subs = append([]*hubTransport(nil), t.hub.subs...)
+	t.hub.lock.RUnlock()
+	for _, s := range subs {
+		if s != t {
+			s.deliver(append([]byte(nil), msg...))
+		}
+	}
+	return nil
+}
+
+func (t *hubTransport) deliver(msg []byte) {
+	t.lock.RLock()
+	var handler, closed = t.handler, t.closed
+	t.lock.RUnlock()
+	if handler != nil && !closed {
+		handler(msg)
+	}
+}
+
+func (t *hubTransport) Subscribe(handler func([]byte)) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.handler = handler
+}
+
+func (t *hubTransport) Close() error {
+	t.lock.Lock()
+	t.closed = true
+	t.lock.Unlock()
+	t.hub.lock.Lock()
+	defer t.hub.lock.Unlock()
+	for i, s := range t.hub.subs {
+		if s == t {
+			t.hub.subs = append(t.hub.subs[:i], t.hub.subs[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
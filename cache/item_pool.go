@@ -32,4 +32,28 @@ var (
 			return new(mqEntry)
 		},
 	}
+
+	lirsEntryPool = sync.Pool{
+		New: func() interface{} {
+			return new(lirsEntry)
+		},
+	}
+
+	clockEntryPool = sync.Pool{
+		New: func() interface{} {
+			return new(clockEntry)
+		},
+	}
+
+	clockProEntryPool = sync.Pool{
+		New: func() interface{} {
+			return new(clockProEntry)
+		},
+	}
+
+	s3fifoEntryPool = sync.Pool{
+		New: func() interface{} {
+			return new(s3fifoEntry)
+		},
+	}
 )
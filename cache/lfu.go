@@ -2,7 +2,7 @@ package cache
 
 import (
 	"container/list"
-	"runtime"
+	"math/rand"
 	"sync"
 	"time"
 )
@@ -10,10 +10,14 @@ import (
 type LFUCache struct {
 	*lfu
 	lock sync.RWMutex
+	atomicOp
 }
 
 func NewLFUCache(opt *Opt) *LFUCache {
-	return &LFUCache{lfu: newLFU(opt)}
+	var lc = &LFUCache{lfu: newLFU(opt)}
+	lc.atomicOp = atomicOp{locker: &lc.lock, op: lc.lfu}
+	startWatchdog(lc.expire, lc)
+	return lc
 }
 
 func (lc *LFUCache) Get(key interface{}) (interface{}, bool) {
@@ -34,6 +38,12 @@ func (lc *LFUCache) PutWithExpire(key interface{}, value interface{}, lifeSpan t
 	return lc.lfu.PutWithExpire(key, value, lifeSpan)
 }
 
+func (lc *LFUCache) PutWithExpireOpt(key interface{}, value interface{}, lifeSpan time.Duration, o ExpireOpt) bool {
+	lc.lock.Lock()
+	defer lc.lock.Unlock()
+	return lc.lfu.PutWithExpireOpt(key, value, lifeSpan, o)
+}
+
 func (lc *LFUCache) Remove(key interface{}) bool {
 	lc.lock.Lock()
 	defer lc.lock.Unlock()
@@ -52,34 +62,104 @@ func (lc *LFUCache) Clear() {
 	lc.lfu.Clear()
 }
 
+func (lc *LFUCache) DeleteExpired() {
+	lc.lock.Lock()
+	defer lc.lock.Unlock()
+	lc.lfu.DeleteExpired()
+}
+
+func (lc *LFUCache) Pin(key interface{}) error {
+	lc.lock.Lock()
+	defer lc.lock.Unlock()
+	return lc.lfu.Pin(key)
+}
+
+func (lc *LFUCache) Unpin(key interface{}) bool {
+	lc.lock.Lock()
+	defer lc.lock.Unlock()
+	return lc.lfu.Unpin(key)
+}
+
+func (lc *LFUCache) PutPinned(key interface{}, value interface{}, lifeSpan time.Duration) error {
+	lc.lock.Lock()
+	defer lc.lock.Unlock()
+	return lc.lfu.PutPinned(key, value, lifeSpan)
+}
+
+// LFUTieBreak LFU同频次元素的淘汰顺序
+type LFUTieBreak int
+
+const (
+	// 同频次淘汰最久未被访问的元素
+	LFUTieLRU LFUTieBreak = iota
+	// 同频次淘汰最早进入该频次的元素，访问未改变频次时不调整顺序
+	LFUTieFIFO
+)
+
+/*
+O(1) LFU：
+1. 频次节点按频次升序组成双向链表，链表头部即为最小频次；
+2. 每个频次节点持有该频次下的元素链表，新进入的元素插入头部，淘汰时从尾部淘汰；
+3. 元素访问后移动到相邻的更高频次节点，不存在则在其后插入新节点，频次节点为空时移除；
+4. 频次衰减等跨越多个频次的移动需要沿频次链表查找目标节点，耗时与跨越的频次节点数相关。
+*/
 type lfu struct {
-	// 缓存存储
+	// 缓存存储，绑定元素key与其所在的元素链表节点
 	cache map[interface{}]*list.Element
-	// 存储每个频次对应的双向链表
-	freqMap map[int]*list.List
+	// 频次链表，节点为*freqNode，按频次升序排列
+	freqList *list.List
 	// 缓存大小
 	size int
 	// 缓存容量
 	capacity int
-	// 当前缓存中的最小频次
-	min int
+	// 同频次元素的淘汰顺序
+	tieBreak LFUTieBreak
 	// 淘汰元素时执行的回调
 	onEvict EvictCallback
+	// 准入策略
+	admission AdmissionPolicy
+	// 每访问halveEvery次将所有元素频次减半，0表示不减半
+	halveEvery int
+	// 距离上次减半的访问次数
+	ops int
+	// 对数计数因子，0表示线性计数
+	logFactor int
+	// 频次衰减周期，0表示不衰减
+	decayTime time.Duration
+	// 对数计数使用的随机数
+	rand *rand.Rand
+	// 固定的元素，不在频次链表中
+	pinned *pinList
 	// 过期属性
 	*expire
 }
 
+// 频次节点
+type freqNode struct {
+	freq  int
+	items *list.List // 该频次下的元素，节点为*entryWithFreq
+}
+
 func newLFU(opt *Opt) *lfu {
+	// 减半间隔不小于容量，保证均摊O(1)
+	if opt.LFUHalveEvery > 0 && opt.LFUHalveEvery < opt.Capacity {
+		opt.LFUHalveEvery = opt.Capacity
+	}
 	var c = &lfu{
-		capacity: opt.Capacity,
-		onEvict:  opt.Callback,
-		expire:   newExpire(opt),
-		cache:    make(map[interface{}]*list.Element),
-		freqMap:  make(map[int]*list.List),
+		capacity:   opt.Capacity,
+		tieBreak:   opt.LFUTieBreak,
+		pinned:     newPinList(opt),
+		onEvict:    opt.Callback,
+		admission:  opt.Admission,
+		halveEvery: opt.LFUHalveEvery,
+		logFactor:  opt.LFULogFactor,
+		decayTime:  opt.LFUDecayTime,
+		expire:     newExpire(opt),
+		cache:      make(map[interface{}]*list.Element),
+		freqList:   list.New(),
 	}
-	if c.expire.interval > 0 {
-		go c.expire.run(c)
-		runtime.SetFinalizer(c.expire, stopWatchdog)
+	if c.logFactor > 0 {
+		c.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
 	}
 	return c
 }
@@ -95,50 +175,170 @@ func (c *lfu) Get(key interface{}) (interface{}, bool) {
 
 	var et = node.Value.(*entryWithFreq)
 	var value = et.item.value
-	if et.Expired() {
+	var now = c.now()
+	if et.expiredAt(now) {
 		// 惰性回收
-		// 1. 查询所在频次链表
-		// 2. 从Cache中移除
-		// 3. 从所在频次链表移除
-		// 4. 执行回调
-		c.remove(et, c.freqMap[et.freq], node)
+		c.remove(node)
 		return nil, false
 	}
+	et.touch(now)
 
-	c.freqInc(node)
+	// 固定的元素不参与频次统计
+	if !et.pinned {
+		c.freqInc(node)
+	}
 	return value, true
 }
 
+// 当前缓存中的最小频次，缓存为空时为0
+func (c *lfu) minFreq() int {
+	if front := c.freqList.Front(); front != nil {
+		return front.Value.(*freqNode).freq
+	}
+	return 0
+}
+
+// 访问节点：按衰减和计数策略计算新频次，并移动到对应频次节点
 func (c *lfu) freqInc(node *list.Element) {
 	var (
-		deNodeList *list.List
-		ok         bool
-		v          interface{}
+		et  = node.Value.(*entryWithFreq)
+		now = c.now()
+	)
+	var freq = c.incr(c.decay(et.freq, et.accessTime, now))
+	et.accessTime = now
+	c.moveTo(node, freq)
+	c.tick()
+}
+
+// 查找频次为freq的节点，不存在则在from附近插入，from为nil时从链表头部开始查找
+func (c *lfu) freqNodeOf(from *list.Element, freq int) *list.Element {
+	if from == nil {
+		from = c.freqList.Front()
+		if from == nil {
+			return c.freqList.PushFront(&freqNode{freq: freq, items: list.New()})
+		}
+	}
+
+	var fn = from.Value.(*freqNode)
+	if fn.freq == freq {
+		return from
+	}
+	// 向高频次方向查找
+	if fn.freq < freq {
+		for next := from.Next(); next != nil && next.Value.(*freqNode).freq <= freq; next = from.Next() {
+			from = next
+		}
+		if from.Value.(*freqNode).freq == freq {
+			return from
+		}
+		return c.freqList.InsertAfter(&freqNode{freq: freq, items: list.New()}, from)
+	}
+	// 向低频次方向查找
+	for prev := from.Prev(); prev != nil && prev.Value.(*freqNode).freq >= freq; prev = from.Prev() {
+		from = prev
+	}
+	if from.Value.(*freqNode).freq == freq {
+		return from
+	}
+	return c.freqList.InsertBefore(&freqNode{freq: freq, items: list.New()}, from)
+}
+
+// 将节点移动到频次为freq的频次节点
+func (c *lfu) moveTo(node *list.Element, freq int) {
+	var (
+		et     = node.Value.(*entryWithFreq)
+		parent = et.parent
 	)
+	if et.freq == freq {
+		if c.tieBreak == LFUTieLRU {
+			parent.Value.(*freqNode).items.MoveToFront(node)
+		}
+		return
+	}
+
+	var target = c.freqNodeOf(parent, freq)
+	c.unlink(node)
+	et.freq = freq
+	et.parent = target
+	c.cache[et.key] = target.Value.(*freqNode).items.PushFront(et)
+}
 
-	var freq = node.Value.(*entryWithFreq).freq
-	//fmt.Println("node.freq: ", node.freq)
-	v, ok = c.freqMap[freq]
-	if !ok || v == nil {
+// 将节点从所在频次节点移除，频次节点为空时一并移除；固定的元素从固定链表移除
+func (c *lfu) unlink(node *list.Element) {
+	if node.Value.(*entryWithFreq).pinned {
+		c.pinned.list.Remove(node)
 		return
 	}
-	deNodeList = v.(*list.List)
+	var (
+		parent = node.Value.(*entryWithFreq).parent
+		fn     = parent.Value.(*freqNode)
+	)
+	fn.items.Remove(node)
+	if fn.items.Len() == 0 {
+		c.freqList.Remove(parent)
+	}
+}
 
-	// 从双端链表中移除旧节点
-	deNodeList.Remove(node)
-	if freq == c.min && deNodeList.Len() == 0 {
-		c.min = 1
+// 按空闲时长衰减频次，每空闲一个衰减周期频次减1，最小为1
+func (c *lfu) decay(freq int, accessTime, now int64) int {
+	if c.decayTime <= 0 || now <= accessTime {
+		return freq
 	}
+	freq -= int((now - accessTime) / int64(c.decayTime))
+	if freq < 1 {
+		freq = 1
+	}
+	return freq
+}
 
-	// 将新节点插入到对应频次的链表中
-	freq++
-	// 更新频率
-	node.Value.(*entryWithFreq).freq = freq
-	if deNodeList, ok = c.freqMap[freq]; !ok {
-		deNodeList = list.New()
-		c.freqMap[freq] = deNodeList
+// 频次自增，对数计数时以 1/((freq-1)*logFactor+1) 的概率自增
+func (c *lfu) incr(freq int) int {
+	if c.logFactor <= 0 {
+		return freq + 1
+	}
+	var p = 1 / float64((freq-1)*c.logFactor+1)
+	if c.rand.Float64() < p {
+		freq++
+	}
+	return freq
+}
+
+// 记录一次访问，达到间隔后将所有元素频次减半
+func (c *lfu) tick() {
+	if c.halveEvery <= 0 {
+		return
+	}
+	c.ops++
+	if c.ops >= c.halveEvery {
+		c.ops = 0
+		c.halve()
+	}
+}
+
+// 所有元素频次减半（最小为1）
+// 减半后频次仍保持升序，只需顺序合并相邻的频次节点；同一频次节点中来自较高频次的元素排在前面
+func (c *lfu) halve() {
+	var old = c.freqList
+	c.freqList = list.New()
+	for fe := old.Front(); fe != nil; fe = fe.Next() {
+		var fn = fe.Value.(*freqNode)
+		var freq = fn.freq / 2
+		if freq < 1 {
+			freq = 1
+		}
+
+		var target = c.freqList.Back()
+		if target == nil || target.Value.(*freqNode).freq != freq {
+			target = c.freqList.PushBack(&freqNode{freq: freq, items: list.New()})
+		}
+		var items = target.Value.(*freqNode).items
+		for node := fn.items.Back(); node != nil; node = node.Prev() {
+			var et = node.Value.(*entryWithFreq)
+			et.freq = freq
+			et.parent = target
+			c.cache[et.key] = items.PushFront(et)
+		}
 	}
-	deNodeList.PushFront(node)
 }
 
 func (c *lfu) Put(key interface{}, value interface{}) bool {
@@ -146,56 +346,88 @@ func (c *lfu) Put(key interface{}, value interface{}) bool {
 }
 
 func (c *lfu) PutWithExpire(key interface{}, value interface{}, lifeSpan time.Duration) bool {
+	return c.PutWithExpireOpt(key, value, lifeSpan, c.expireOpt)
+}
+
+func (c *lfu) PutWithExpireOpt(key interface{}, value interface{}, lifeSpan time.Duration, o ExpireOpt) bool {
 	if c.capacity == 0 {
 		return false
 	}
 	// 对象已存在缓存则进行更新
 	if node, ok := c.cache[key]; ok {
 		node.Value.(*entryWithFreq).item.value = value
-		node.Value.(*entryWithFreq).item.expiration = c.absoluteTime(lifeSpan)
-		node.Value.(*entryWithFreq).freq++
+		c.setExpire(&node.Value.(*entryWithFreq).item, lifeSpan, o)
+		if !node.Value.(*entryWithFreq).pinned {
+			c.freqInc(node)
+		}
 		return false
 	}
 
+	// 不满足准入策略则不写入
+	if !admit(c.admission, key) {
+		return false
+	}
+	return c.put(key, value, lifeSpan, o)
+}
+
+// 添加新元素；return 是否淘汰元素
+func (c *lfu) put(key interface{}, value interface{}, lifeSpan time.Duration, o ExpireOpt) bool {
+	// 若缓存容量已满, 则剔除频次最小的对象
+	var evict = c.evictNode()
+
 	var (
-		// 若缓存容量已满, 则剔除频次最小的对象
-		evict       = c.evictNode()
-		oneFreqList *list.List
-		ok          bool
+		et     = c.newEntryWithFreq(key, value, lifeSpan, o)
+		target = c.freqNodeOf(nil, et.freq)
 	)
-
-	if oneFreqList, ok = c.freqMap[1]; !ok {
-		oneFreqList = list.New()
-		c.freqMap[1] = oneFreqList
-	}
-	c.cache[key] = oneFreqList.PushFront(c.newEntryWithFreq(key, value, lifeSpan))
+	et.parent = target
+	c.cache[key] = target.Value.(*freqNode).items.PushFront(et)
 	c.size++
-	c.min = 1
+	c.tick()
 	return evict
 }
 
+// 回收过期的元素，同时对空闲元素的频次进行衰减
 func (c *lfu) DeleteExpired() {
-	var now = time.Now().UnixNano() // 减少系统调用
+	var now = c.now() // 减少系统调用
 	for _, node := range c.cache {
 		var et = node.Value.(*entryWithFreq)
 		// 未过期
-		if et.expiration <= 0 || now < et.expiration {
+		if !et.expiredAt(now) {
+			if !et.pinned {
+				c.decayIdle(node, now)
+			}
 			continue
 		}
-		c.remove(et, c.freqMap[et.freq], node)
+		c.remove(node)
+	}
+}
+
+// 衰减空闲元素的频次，保留不足一个周期的空闲时长
+func (c *lfu) decayIdle(node *list.Element, now int64) {
+	var et = node.Value.(*entryWithFreq)
+	if c.decayTime <= 0 || now-et.accessTime < int64(c.decayTime) {
+		return
+	}
+	var freq = c.decay(et.freq, et.accessTime, now)
+	et.accessTime += (now - et.accessTime) / int64(c.decayTime) * int64(c.decayTime)
+	if freq != et.freq {
+		c.moveTo(node, freq)
 	}
 }
 
-func (c *lfu) remove(et *entryWithFreq, nodeList *list.List, node *list.Element) {
-	// 移除节点
-	nodeList.Remove(node)
+func (c *lfu) remove(node *list.Element) {
+	var et = node.Value.(*entryWithFreq)
+	// 1. 从频次节点中移除
+	c.unlink(node)
 	// 2. 从Cache中移除
 	delete(c.cache, et.entry.key)
 	c.size--
 	// 3. 执行回调
 	if c.onEvict != nil {
+		// entry 会被放回对象池复用，回调只能捕获其副本
+		var key, value = et.entry.key, et.entry.item.value
 		_ = c.goroutinePool.Submit(func() {
-			c.onEvict(et.entry.key, et.entry.item.value)
+			c.onEvict(key, value)
 		})
 	}
 	entryWithFreqPool.Put(et)
@@ -206,20 +438,12 @@ func (c *lfu) evictNode() bool {
 		return false
 	}
 
-	var minFreqList *list.List
-
-	var (
-		v  interface{}
-		ok bool
-	)
-	if v, ok = c.freqMap[c.min]; !ok {
+	var front = c.freqList.Front()
+	if front == nil {
 		return false
 	}
-
-	minFreqList = v.(*list.List)
-	// 从最小频次链表中获取最后一个节点对象
-	var elem = minFreqList.Back().Value.(*entryWithFreq)
-	c.remove(elem, minFreqList, minFreqList.Back())
+	// 从最小频次节点中淘汰尾部元素
+	c.remove(front.Value.(*freqNode).items.Back())
 	return true
 }
 
@@ -235,31 +459,25 @@ func (c *lfu) Remove(key interface{}) bool {
 		// 查询的节点不存在
 		return false
 	}
-	// 2. 根据元素节点的频率查询其所在节点链表，移除节点
-	var (
-		freq     = node.Value.(*entryWithFreq).freq
-		nodeList *list.List
-	)
-	if nodeList, ok = c.freqMap[freq]; !ok {
-		return false
-	}
-
-	var et = node.Value.(*entryWithFreq)
-	var expired = et.item.Expired()
 
-	c.remove(et, nodeList, node)
+	var expired = node.Value.(*entryWithFreq).expiredAt(c.now())
+	c.remove(node)
 	return !expired
 }
 
 // LFU
 type entryWithFreq struct {
 	entry
-	freq int
+	freq       int
+	accessTime int64         // 最后访问时间
+	parent     *list.Element // 所在频次节点
 }
 
 func (e *entryWithFreq) Reset() {
 	e.entry.Reset()
 	e.freq = 0
+	e.accessTime = 0
+	e.parent = nil
 }
 
 func (c *lfu) Clear() {
@@ -270,13 +488,10 @@ func (c *lfu) Clear() {
 		}
 		delete(c.cache, k)
 	}
-	// 2. 清空频次链表Map
-	for k, v := range c.freqMap {
-		v.Init()
-		delete(c.freqMap, k)
-	}
+	// 2. 清空频次链表
+	c.freqList.Init()
+	c.pinned.list.Init()
 	c.size = 0
-	c.min = 0
 }
 
 // 过期了但是未被回收也会统计在内
@@ -284,12 +499,62 @@ func (c *lfu) Len() int {
 	return c.size
 }
 
-func (c *lfu) newEntryWithFreq(key, value interface{}, lifeSpan time.Duration) *entryWithFreq {
+func (c *lfu) newEntryWithFreq(key, value interface{}, lifeSpan time.Duration, o ExpireOpt) *entryWithFreq {
 	var et = entryWithFreqPool.Get().(*entryWithFreq)
 	et.Reset()
 	et.freq = 1
+	et.accessTime = c.now()
 	et.entry.key = key
 	et.entry.item.value = value
-	et.entry.item.expiration = c.absoluteTime(lifeSpan)
+	c.setExpire(&et.entry.item, lifeSpan, o)
 	return et
 }
+
+// 固定元素，将其从频次链表移到固定链表，频次保持不变
+func (c *lfu) Pin(key interface{}) error {
+	var node, ok = c.cache[key]
+	if !ok || node.Value.(*entryWithFreq).expiredAt(c.now()) {
+		return ErrNotFound
+	}
+	var et = node.Value.(*entryWithFreq)
+	if et.pinned {
+		return nil
+	}
+	if c.pinned.full() {
+		return ErrPinLimit
+	}
+	c.unlink(node)
+	et.pinned = true
+	et.parent = nil
+	c.cache[key] = c.pinned.list.PushFront(et)
+	return nil
+}
+
+// 取消固定，按固定前的频次放回频次链表
+func (c *lfu) Unpin(key interface{}) bool {
+	var node, ok = c.cache[key]
+	if !ok || !node.Value.(*entryWithFreq).pinned {
+		return false
+	}
+	var et = node.Value.(*entryWithFreq)
+	c.pinned.list.Remove(node)
+	et.pinned = false
+	et.accessTime = c.now()
+	et.parent = c.freqNodeOf(nil, et.freq)
+	c.cache[key] = et.parent.Value.(*freqNode).items.PushFront(et)
+	return true
+}
+
+func (c *lfu) PutPinned(key interface{}, value interface{}, lifeSpan time.Duration) error {
+	var node, ok = c.cache[key]
+	if (!ok || !node.Value.(*entryWithFreq).pinned) && c.pinned.full() {
+		return ErrPinLimit
+	}
+	if ok {
+		c.PutWithExpire(key, value, lifeSpan)
+	} else {
+		// 不经过准入策略
+		c.put(key, value, lifeSpan, c.expireOpt)
+	}
+	return c.Pin(key)
+}
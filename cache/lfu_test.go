@@ -0,0 +1,182 @@
+package cache
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func TestLFU_Halve(t *testing.T) {
+	for _, halveEvery := range []int{0, 10} {
+		var c = newLFU(&Opt{Capacity: 2, LFUHalveEvery: halveEvery})
+		c.Put("a", 1)
+		for i := 0; i < 20; i++ {
+			c.Get("a")
+		}
+		c.Put("b", 1)
+		for i := 0; i < 15; i++ {
+			c.Get("b")
+		}
+		c.Put("c", 1)
+
+		// 不减半时历史热点a保留，减半后a被淘汰
+		var _, ok = c.Get("a")
+		if ok != (halveEvery == 0) {
+			t.Errorf("halveEvery %d: Get(a) ok = %v", halveEvery, ok)
+		}
+		if _, ok = c.Get("c"); !ok {
+			t.Errorf("halveEvery %d: Get(c) miss", halveEvery)
+		}
+	}
+}
+
+func TestLFU_LogFactor(t *testing.T) {
+	var c = newLFU(&Opt{Capacity: 2, LFULogFactor: 10})
+	c.Put("a", 1)
+	for i := 0; i < 1000; i++ {
+		c.Get("a")
+	}
+	var freq = c.cache["a"].Value.(*entryWithFreq).freq
+	if freq <= 1 || freq >= 100 {
+		t.Errorf("freq = %d, want logarithmic growth", freq)
+	}
+	if min := c.minFreq(); min != freq {
+		t.Errorf("min = %d, want %d", min, freq)
+	}
+}
+
+func TestLFU_Decay(t *testing.T) {
+	var clock = &testClock{now: time.Unix(1000, 0)}
+	var c = newLFU(&Opt{Capacity: 2, LFUDecayTime: time.Minute, Clock: clock})
+	c.Put("a", 1)
+	for i := 0; i < 10; i++ {
+		c.Get("a")
+	}
+	c.Put("b", 1)
+
+	// 空闲一小时后a的频次衰减到1
+	clock.now = clock.now.Add(time.Hour)
+	c.DeleteExpired()
+	if freq := c.cache["a"].Value.(*entryWithFreq).freq; freq != 1 {
+		t.Fatalf("freq(a) = %d, want 1", freq)
+	}
+	c.Get("b")
+	c.Put("c", 1)
+	if _, ok := c.Get("a"); ok {
+		t.Errorf("idle key a not evicted")
+	}
+	if _, ok := c.Get("b"); !ok {
+		t.Errorf("key b evicted")
+	}
+}
+
+// 校验频次链表升序且非空、元素归属正确
+func (c *lfu) check(t *testing.T) {
+	t.Helper()
+	var prev, count int
+	for fe := c.freqList.Front(); fe != nil; fe = fe.Next() {
+		var fn = fe.Value.(*freqNode)
+		if fn.freq <= prev {
+			t.Fatalf("freq list not ascending: %d after %d", fn.freq, prev)
+		}
+		if fn.items.Len() == 0 {
+			t.Fatalf("empty freq node %d", fn.freq)
+		}
+		for node := fn.items.Front(); node != nil; node = node.Next() {
+			var et = node.Value.(*entryWithFreq)
+			if et.freq != fn.freq || et.parent != fe || c.cache[et.key] != node {
+				t.Fatalf("entry %v misplaced: freq %d in node %d", et.key, et.freq, fn.freq)
+			}
+			count++
+		}
+		prev = fn.freq
+	}
+	if count != c.size || count != len(c.cache) {
+		t.Fatalf("count %d, size %d, cache %d", count, c.size, len(c.cache))
+	}
+}
+
+func TestLFU_Churn(t *testing.T) {
+	var clock = &testClock{now: time.Unix(1000, 0)}
+	for _, opt := range []*Opt{
+		{Capacity: 16, Clock: clock},
+		{Capacity: 16, Clock: clock, LFUHalveEvery: 50, LFULogFactor: 2, LFUDecayTime: time.Second},
+		{Capacity: 16, Clock: clock, LFUTieBreak: LFUTieFIFO, LFULogFactor: 1},
+	} {
+		var (
+			c = newLFU(opt)
+			r = rand.New(rand.NewSource(1))
+		)
+		for i := 0; i < 20000; i++ {
+			var key = r.Intn(40)
+			switch r.Intn(6) {
+			case 0, 1:
+				c.Put(key, i)
+			case 2:
+				c.PutWithExpire(key, i, time.Duration(r.Intn(3))*time.Second)
+			case 3:
+				c.Remove(key)
+			case 4:
+				clock.now = clock.now.Add(time.Duration(r.Intn(500)) * time.Millisecond)
+				c.DeleteExpired()
+			default:
+				c.Get(key)
+			}
+			c.check(t)
+			if c.size > opt.Capacity {
+				t.Fatalf("size %d exceeds capacity", c.size)
+			}
+		}
+	}
+}
+
+func TestLFU_TieBreak(t *testing.T) {
+	for _, tt := range []struct {
+		tieBreak LFUTieBreak
+		evicted  string
+	}{
+		{LFUTieLRU, "b"},
+		{LFUTieFIFO, "a"},
+	} {
+		// 对数计数因子极大，频次2之后几乎不再自增
+		var c = newLFU(&Opt{Capacity: 3, LFUTieBreak: tt.tieBreak, LFULogFactor: 1 << 30})
+		for _, key := range []string{"a", "b", "c"} {
+			c.Put(key, key)
+		}
+		for _, key := range []string{"a", "b", "c", "a"} {
+			c.Get(key)
+		}
+		c.Put("d", "d")
+		c.Get("d")
+		if _, ok := c.cache[tt.evicted]; ok {
+			t.Errorf("tieBreak %d: %s not evicted", tt.tieBreak, tt.evicted)
+		}
+	}
+}
+
+func TestLFU_AtomicPeek(t *testing.T) {
+	var c = NewLFUCache(&Opt{Capacity: 2})
+	c.Put("a", 1)
+	c.Put("b", 1)
+	c.Get("b")
+	c.Get("b")
+	// 原子操作的存在性检查不增加频次
+	for i := 0; i < 5; i++ {
+		c.PutIfAbsent("a", 2)
+	}
+	c.Put("c", 1)
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("Get(a) hit, PutIfAbsent should not raise its frequency")
+	}
+	if _, ok := c.Get("b"); !ok {
+		t.Fatal("Get(b) miss")
+	}
+}
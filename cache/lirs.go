@@ -0,0 +1,380 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+/*
+LIRS (Low Inter-reference Recency Set)：
+1. 驻留元素分为LIR与HIR两类，LIR元素占容量的大部分，HIR驻留元素占其余部分；
+2. 栈S按最近访问顺序记录LIR元素、HIR驻留元素以及非驻留HIR元素，栈底始终为LIR元素（栈剪枝）；
+3. 队列Q记录HIR驻留元素，需要淘汰时淘汰Q头部元素，若其仍在S中则变为非驻留HIR元素；
+4. 访问LIR元素：移动到S栈顶；
+5. 访问S中的HIR元素：变为LIR元素，S栈底的LIR元素变为HIR元素并移动到Q尾部；
+6. 访问不在S中的HIR元素：压入S栈顶，并移动到Q尾部；
+7. 非驻留HIR元素数量不超过容量，超出时遗忘最早的非驻留元素。
+*/
+
+type LIRSCache struct {
+	*lirs
+	lock sync.RWMutex
+	atomicOp
+}
+
+func NewLIRSCache(opt *Opt) (*LIRSCache, error) {
+	var (
+		l   *lirs
+		err error
+	)
+	if l, err = newLIRS(opt); err != nil {
+		return nil, err
+	}
+	var c = &LIRSCache{lirs: l}
+	c.atomicOp = atomicOp{locker: &c.lock, op: c.lirs}
+	startWatchdog(c.expire, c)
+	return c, nil
+}
+
+func (c *LIRSCache) Get(key interface{}) (interface{}, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.lirs.Get(key)
+}
+
+func (c *LIRSCache) Put(key, value interface{}) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.lirs.Put(key, value)
+}
+
+func (c *LIRSCache) PutWithExpire(key interface{}, value interface{}, lifeSpan time.Duration) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.lirs.PutWithExpire(key, value, lifeSpan)
+}
+
+func (c *LIRSCache) Remove(key interface{}) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.lirs.Remove(key)
+}
+
+func (c *LIRSCache) DeleteExpired() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.lirs.DeleteExpired()
+}
+
+func (c *LIRSCache) Len() int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.lirs.Len()
+}
+
+func (c *LIRSCache) Clear() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.lirs.Clear()
+}
+
+type lirsStatus int
+
+const (
+	lirsLIR         lirsStatus = iota // LIR元素
+	lirsHIR                           // HIR驻留元素
+	lirsNonResident                   // 非驻留HIR元素，只保留key
+)
+
+const (
+	DefaultLIRSHIRRatio = 0.01
+)
+
+type lirs struct {
+	items     map[interface{}]*lirsEntry // 全部元素，包含非驻留HIR元素
+	stack     *list.List                 // 栈S，头部为栈顶
+	queue     *list.List                 // 队列Q，头部最先淘汰
+	ghost     *list.List                 // 非驻留HIR元素，头部最早
+	capacity  int                        // 缓存容量
+	maxLIR    int                        // LIR元素容量
+	lirCount  int                        // LIR元素个数
+	onEvict   EvictCallback              // 淘汰元素时执行的回调
+	admission AdmissionPolicy            // 准入策略
+	*expire                              // 过期属性
+}
+
+type lirsEntry struct {
+	entry
+	status lirsStatus
+	sNode  *list.Element // 在栈S中的节点
+	qNode  *list.Element // 在队列Q中的节点
+	gNode  *list.Element // 在非驻留列表中的节点
+}
+
+func (e *lirsEntry) Reset() {
+	e.entry.Reset()
+	e.status = lirsLIR
+	e.sNode = nil
+	e.qNode = nil
+	e.gNode = nil
+}
+
+func newLIRS(opt *Opt) (*lirs, error) {
+	if opt.Capacity <= 0 {
+		return nil, ErrSize
+	}
+	if opt.LIRSHIRRatio <= 0 || opt.LIRSHIRRatio >= 1 {
+		opt.LIRSHIRRatio = DefaultLIRSHIRRatio
+	}
+	// HIR驻留元素至少占1个位置
+	var maxHIR = int(float64(opt.Capacity) * opt.LIRSHIRRatio)
+	if maxHIR < 1 {
+		maxHIR = 1
+	}
+	return &lirs{
+		items:     make(map[interface{}]*lirsEntry),
+		stack:     list.New(),
+		queue:     list.New(),
+		ghost:     list.New(),
+		capacity:  opt.Capacity,
+		maxLIR:    opt.Capacity - maxHIR,
+		onEvict:   opt.Callback,
+		admission: opt.Admission,
+		expire:    newExpire(opt),
+	}, nil
+}
+
+func (c *lirs) Get(key interface{}) (interface{}, bool) {
+	var et, ok = c.items[key]
+	if !ok || et.status == lirsNonResident {
+		return nil, false
+	}
+	if et.expiredAt(c.now()) {
+		c.remove(et)
+		return nil, false
+	}
+	c.hit(et)
+	return et.value, true
+}
+
+func (c *lirs) Put(key, value interface{}) bool {
+	return c.PutWithExpire(key, value, NoExpiration)
+}
+
+// 不存在则添加，存在则更新；return 是否淘汰元素
+func (c *lirs) PutWithExpire(key interface{}, value interface{}, lifeSpan time.Duration) bool {
+	var et, ok = c.items[key]
+	// 驻留元素直接更新
+	if ok && et.status != lirsNonResident {
+		et.value = value
+		et.expiration = c.renewTime(et.expiration, lifeSpan)
+		c.hit(et)
+		return false
+	}
+
+	// 不满足准入策略则不写入
+	if !admit(c.admission, key) {
+		return false
+	}
+
+	var evict = c.Len() >= c.capacity
+	if evict {
+		c.evict()
+		// 淘汰过程中可能遗忘了该非驻留元素
+		et, ok = c.items[key]
+	}
+
+	if !ok {
+		et = lirsEntryPool.Get().(*lirsEntry)
+		et.Reset()
+		et.key = key
+		c.items[key] = et
+	} else {
+		// 非驻留HIR元素重新被访问
+		c.ghost.Remove(et.gNode)
+		et.gNode = nil
+	}
+	et.value = value
+	et.expiration = c.absoluteTime(lifeSpan)
+
+	switch {
+	case c.lirCount < c.maxLIR:
+		// LIR元素未满时直接作为LIR元素
+		et.status = lirsLIR
+		c.lirCount++
+		c.toTop(et)
+	case et.sNode != nil:
+		// 在栈S中说明其近期被访问过，提升为LIR元素
+		et.status = lirsLIR
+		c.lirCount++
+		c.toTop(et)
+		c.demote()
+	default:
+		et.status = lirsHIR
+		c.toTop(et)
+		et.qNode = c.queue.PushBack(et)
+	}
+	c.prune()
+	return evict
+}
+
+// 命中驻留元素
+func (c *lirs) hit(et *lirsEntry) {
+	switch {
+	case et.status == lirsLIR:
+		c.toTop(et)
+	case et.sNode != nil:
+		// S中的HIR元素提升为LIR元素
+		c.queue.Remove(et.qNode)
+		et.qNode = nil
+		et.status = lirsLIR
+		c.lirCount++
+		c.toTop(et)
+		c.demote()
+	default:
+		// 不在S中的HIR元素仍为HIR元素
+		c.toTop(et)
+		c.queue.MoveToBack(et.qNode)
+	}
+	c.prune()
+}
+
+// 移动到栈顶
+func (c *lirs) toTop(et *lirsEntry) {
+	if et.sNode != nil {
+		c.stack.MoveToFront(et.sNode)
+		return
+	}
+	et.sNode = c.stack.PushFront(et)
+}
+
+// LIR元素超出容量时，栈底的LIR元素变为HIR元素并移动到Q尾部
+func (c *lirs) demote() {
+	for c.lirCount > c.maxLIR {
+		c.prune()
+		var bottom = c.stack.Back().Value.(*lirsEntry)
+		c.stack.Remove(bottom.sNode)
+		bottom.sNode = nil
+		bottom.status = lirsHIR
+		bottom.qNode = c.queue.PushBack(bottom)
+		c.lirCount--
+	}
+}
+
+// 栈剪枝：移除栈底的HIR元素，直到栈底为LIR元素
+func (c *lirs) prune() {
+	for node := c.stack.Back(); node != nil; node = c.stack.Back() {
+		var et = node.Value.(*lirsEntry)
+		if et.status == lirsLIR {
+			return
+		}
+		c.stack.Remove(node)
+		et.sNode = nil
+		if et.status == lirsNonResident {
+			c.forget(et)
+		}
+	}
+}
+
+// 淘汰Q头部的HIR驻留元素
+func (c *lirs) evict() {
+	var node = c.queue.Front()
+	if node == nil {
+		return
+	}
+	var et = node.Value.(*lirsEntry)
+	c.queue.Remove(node)
+	et.qNode = nil
+	c.callEvict(et)
+
+	// 不在栈S中则直接遗忘
+	if et.sNode == nil {
+		c.forget(et)
+		return
+	}
+	// 在栈S中则变为非驻留HIR元素
+	et.status = lirsNonResident
+	et.item.Reset()
+	et.gNode = c.ghost.PushBack(et)
+	for c.ghost.Len() > c.capacity {
+		var oldest = c.ghost.Front().Value.(*lirsEntry)
+		c.stack.Remove(oldest.sNode)
+		oldest.sNode = nil
+		c.forget(oldest)
+	}
+}
+
+// 遗忘元素，调用前需要将其从栈S和队列Q中移除
+func (c *lirs) forget(et *lirsEntry) {
+	if et.gNode != nil {
+		c.ghost.Remove(et.gNode)
+	}
+	delete(c.items, et.key)
+	lirsEntryPool.Put(et)
+}
+
+func (c *lirs) callEvict(et *lirsEntry) {
+	if c.onEvict != nil {
+		// entry 会被放回对象池复用，回调只能捕获其副本
+		var key, value = et.key, et.value
+		_ = c.goroutinePool.Submit(func() {
+			c.onEvict(key, value)
+		})
+	}
+}
+
+// 移除驻留元素
+func (c *lirs) remove(et *lirsEntry) {
+	if et.status == lirsLIR {
+		c.lirCount--
+	}
+	if et.qNode != nil {
+		c.queue.Remove(et.qNode)
+		et.qNode = nil
+	}
+	if et.sNode != nil {
+		c.stack.Remove(et.sNode)
+		et.sNode = nil
+	}
+	c.callEvict(et)
+	c.forget(et)
+	c.prune()
+}
+
+func (c *lirs) Remove(key interface{}) bool {
+	var et, ok = c.items[key]
+	if !ok || et.status == lirsNonResident {
+		return false
+	}
+	var expired = et.expiredAt(c.now())
+	c.remove(et)
+	return !expired
+}
+
+func (c *lirs) DeleteExpired() {
+	var now = c.now() // 减少系统调用
+	for _, et := range c.items {
+		if et.status != lirsNonResident && et.expiredAt(now) {
+			c.remove(et)
+		}
+	}
+}
+
+// 驻留元素个数
+func (c *lirs) Len() int {
+	return c.lirCount + c.queue.Len()
+}
+
+func (c *lirs) Clear() {
+	for k, et := range c.items {
+		if c.onEvict != nil && et.status != lirsNonResident {
+			c.onEvict(k, et.value)
+		}
+		delete(c.items, k)
+	}
+	c.stack.Init()
+	c.queue.Init()
+	c.ghost.Init()
+	c.lirCount = 0
+}
@@ -0,0 +1,38 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/1005281342/basic_component/cache"
+)
+
+// 循环访问热点数据的同时进行一次性扫描，LIRS应保留热点数据
+func TestLIRS_ScanResistance(t *testing.T) {
+	var hits = make(map[cache.CacheType]int)
+	for _, ct := range []cache.CacheType{cache.LRU, cache.LIRS} {
+		var c, err = cache.NewCache(ct, &cache.Opt{Capacity: 100, Interval: time.Hour})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var scan = 1000
+		for round := 0; round < 20; round++ {
+			for i := 0; i < 80; i++ {
+				if _, ok := c.Get(i); ok {
+					hits[ct]++
+				} else {
+					c.Put(i, i)
+				}
+			}
+			for i := 0; i < 100; i++ {
+				scan++
+				if _, ok := c.Get(scan); !ok {
+					c.Put(scan, scan)
+				}
+			}
+		}
+	}
+	if hits[cache.LIRS] <= hits[cache.LRU] {
+		t.Errorf("LIRS hits %d, LRU hits %d", hits[cache.LIRS], hits[cache.LRU])
+	}
+}
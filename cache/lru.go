@@ -2,7 +2,6 @@ package cache
 
 import (
 	"container/list"
-	"runtime"
 	"sync"
 	"time"
 )
@@ -10,6 +9,7 @@ import (
 type LRUCache struct {
 	*lru
 	lock sync.RWMutex
+	atomicOp
 }
 
 func NewLRUCache(opt *Opt) (*LRUCache, error) {
@@ -20,7 +20,10 @@ func NewLRUCache(opt *Opt) (*LRUCache, error) {
 	if lru, err = newLRU(opt); err != nil {
 		return nil, err
 	}
-	return &LRUCache{lru: lru}, nil
+	var lc = &LRUCache{lru: lru}
+	lc.atomicOp = atomicOp{locker: &lc.lock, op: lc.lru}
+	startWatchdog(lc.expire, lc)
+	return lc, nil
 }
 
 func (lc *LRUCache) Get(key interface{}) (interface{}, bool) {
@@ -41,6 +44,12 @@ func (lc *LRUCache) PutWithExpire(key interface{}, value interface{}, lifeSpan t
 	return lc.lru.PutWithExpire(key, value, lifeSpan)
 }
 
+func (lc *LRUCache) PutWithExpireOpt(key interface{}, value interface{}, lifeSpan time.Duration, o ExpireOpt) bool {
+	lc.lock.Lock()
+	defer lc.lock.Unlock()
+	return lc.lru.PutWithExpireOpt(key, value, lifeSpan, o)
+}
+
 func (lc *LRUCache) Remove(key interface{}) bool {
 	lc.lock.Lock()
 	defer lc.lock.Unlock()
@@ -59,41 +68,66 @@ func (lc *LRUCache) Clear() {
 	lc.lru.Clear()
 }
 
+func (lc *LRUCache) DeleteExpired() {
+	lc.lock.Lock()
+	defer lc.lock.Unlock()
+	lc.lru.DeleteExpired()
+}
+
+func (lc *LRUCache) Pin(key interface{}) error {
+	lc.lock.Lock()
+	defer lc.lock.Unlock()
+	return lc.lru.Pin(key)
+}
+
+func (lc *LRUCache) Unpin(key interface{}) bool {
+	lc.lock.Lock()
+	defer lc.lock.Unlock()
+	return lc.lru.Unpin(key)
+}
+
+func (lc *LRUCache) PutPinned(key interface{}, value interface{}, lifeSpan time.Duration) error {
+	lc.lock.Lock()
+	defer lc.lock.Unlock()
+	return lc.lru.PutPinned(key, value, lifeSpan)
+}
+
 type lru struct {
 	capacity  int                           // 缓存容量
 	size      int                           // 使用节点
 	evictList *list.List                    // 淘汰链表，需要进行淘汰时，淘汰链表尾部元素
 	items     map[interface{}]*list.Element // 绑定元素key和链表节点
 	onEvict   EvictCallback                 // 淘汰元素时执行的回调
+	admission AdmissionPolicy               // 准入策略
+	pinned    *pinList                      // 固定的元素，不在淘汰链表中
 	*expire                                 // 过期属性
 }
 
 type entry struct {
-	key interface{}
+	key    interface{}
+	pinned bool // 是否固定，固定的元素不会被淘汰
 	item
 }
 
 func (e *entry) Reset() {
 	e.item.Reset()
 	e.key = nil
+	e.pinned = false
 }
 
 func newLRU(opt *Opt) (*lru, error) {
 	if opt.Capacity <= 0 {
 		return nil, ErrSize
 	}
-	c := &lru{
+	return &lru{
 		capacity:  opt.Capacity,
 		evictList: list.New(),
+		pinned:    newPinList(opt),
 		items:     make(map[interface{}]*list.Element),
 		onEvict:   opt.Callback,
+		admission: opt.Admission,
 		expire:    newExpire(opt),
-	}
-	if c.expire.interval > 0 {
-		go c.expire.run(c)
-		runtime.SetFinalizer(c.expire, stopWatchdog)
-	}
-	return c, nil
+	}, nil
 }
 
 // 从LRU中查找元素，返回元素值和存在标记位
@@ -111,12 +145,16 @@ func (c *lru) Get(key interface{}) (interface{}, bool) {
 		return nil, false
 	}
 
-	if et.Expired() {
+	var now = c.now()
+	if et.expiredAt(now) {
 		c.removeElement(node)
 		return nil, false
 	}
+	et.touch(now)
 
-	c.evictList.MoveToFront(node)
+	if !et.pinned {
+		c.evictList.MoveToFront(node)
+	}
 	return et.item.value, true
 }
 
@@ -131,30 +169,39 @@ func (c *lru) Put(key, value interface{}) bool {
 }
 
 func (c *lru) PutWithExpire(key interface{}, value interface{}, lifeSpan time.Duration) bool {
+	return c.PutWithExpireOpt(key, value, lifeSpan, c.expireOpt)
+}
 
+func (c *lru) PutWithExpireOpt(key interface{}, value interface{}, lifeSpan time.Duration, o ExpireOpt) bool {
 	var (
 		node *list.Element
 		ok   bool
 	)
 	// 如果元素存在则更新
 	if node, ok = c.items[key]; ok {
-		c.evictList.MoveToFront(node)
+		if !node.Value.(*entry).pinned {
+			c.evictList.MoveToFront(node)
+		}
 		node.Value.(*entry).item.value = value
-		node.Value.(*entry).item.expiration = c.absoluteTime(lifeSpan)
+		c.setExpire(&node.Value.(*entry).item, lifeSpan, o)
 		return false
 	}
 
-	return c.put(key, value, lifeSpan)
+	// 不满足准入策略则不写入
+	if !admit(c.admission, key) {
+		return false
+	}
+	return c.put(key, value, lifeSpan, o)
 }
 
-func (c *lru) put(key, value interface{}, lifeSpan time.Duration) bool {
+func (c *lru) put(key, value interface{}, lifeSpan time.Duration, o ExpireOpt) bool {
 	// 不存在则新增
 	// 将元素值插入到链表头
 	var et = entryPool.Get().(*entry)
 	et.Reset()
 	et.key = key
 	et.item.value = value
-	et.item.expiration = c.absoluteTime(lifeSpan)
+	c.setExpire(&et.item, lifeSpan, o)
 	return c.putItem(key, et)
 }
 
@@ -164,8 +211,8 @@ func (c *lru) putItem(key, it interface{}) bool {
 	c.items[key] = node
 	c.size++
 
-	// 检查容量
-	var evict = c.evictList.Len() > c.capacity
+	// 检查容量，固定的元素同样计入容量
+	var evict = c.size > c.capacity
 	if evict {
 		c.removeOldest()
 	}
@@ -178,8 +225,8 @@ func (c *lru) putItem2(key, it interface{}) (interface{}, bool) {
 	c.items[key] = node
 	c.size++
 
-	// 检查容量
-	var evict = c.evictList.Len() > c.capacity
+	// 检查容量，固定的元素同样计入容量
+	var evict = c.size > c.capacity
 	if evict {
 		return c.removeOldest(), true
 	}
@@ -187,11 +234,17 @@ func (c *lru) putItem2(key, it interface{}) (interface{}, bool) {
 }
 
 func (c *lru) DeleteExpired() {
-	var now = time.Now().UnixNano() // 减少系统调用
-	for node := c.evictList.Front(); node != nil; node = node.Next() {
-		var it = node.Value.(*entry).item
-		if it.expiration > 0 && now > it.expiration {
-			c.removeElement(node)
+	var (
+		now  = c.now() // 减少系统调用
+		next *list.Element
+	)
+	for _, l := range []*list.List{c.evictList, c.pinned.list} {
+		for node := l.Front(); node != nil; node = next {
+			// 移除节点后无法再通过其获取后继节点
+			next = node.Next()
+			if node.Value.(*entry).expiredAt(now) {
+				c.removeElement(node)
+			}
 		}
 	}
 }
@@ -207,13 +260,20 @@ func (c *lru) removeOldest() interface{} {
 
 // 从LRU中移除节点；通过链表节点
 func (c *lru) removeElement(e *list.Element) interface{} {
-	var elem = c.evictList.Remove(e)
 	kv := e.Value.(*entry)
+	var elem interface{}
+	if kv.pinned {
+		elem = c.pinned.list.Remove(e)
+	} else {
+		elem = c.evictList.Remove(e)
+	}
 	delete(c.items, kv.key)
 	c.size--
 	if c.onEvict != nil {
+		// entry 会被放回对象池复用，回调只能捕获其副本
+		var key, value = kv.key, kv.item.value
 		_ = c.goroutinePool.Submit(func() {
-			c.onEvict(kv.key, kv.item.value)
+			c.onEvict(key, value)
 		})
 	}
 	entryPool.Put(kv)
@@ -228,7 +288,7 @@ func (c *lru) Remove(key interface{}) bool {
 	)
 	if node, ok = c.items[key]; ok {
 
-		var expired = node.Value.(*entry).Expired()
+		var expired = node.Value.(*entry).expiredAt(c.now())
 		c.removeElement(node)
 		return !expired
 	}
@@ -243,9 +303,56 @@ func (c *lru) Clear() {
 		delete(c.items, k)
 	}
 	c.evictList.Init()
+	c.pinned.list.Init()
 	c.size = 0
 }
 
 func (c *lru) Len() int {
 	return c.size
 }
+
+// 固定元素，将其从淘汰链表移到固定链表
+func (c *lru) Pin(key interface{}) error {
+	var node, ok = c.items[key]
+	if !ok || node.Value.(*entry).expiredAt(c.now()) {
+		return ErrNotFound
+	}
+	var et = node.Value.(*entry)
+	if et.pinned {
+		return nil
+	}
+	if c.pinned.full() {
+		return ErrPinLimit
+	}
+	c.evictList.Remove(node)
+	et.pinned = true
+	c.items[key] = c.pinned.list.PushFront(et)
+	return nil
+}
+
+// 取消固定，将其作为最近访问的元素放回淘汰链表
+func (c *lru) Unpin(key interface{}) bool {
+	var node, ok = c.items[key]
+	if !ok || !node.Value.(*entry).pinned {
+		return false
+	}
+	var et = node.Value.(*entry)
+	c.pinned.list.Remove(node)
+	et.pinned = false
+	c.items[key] = c.evictList.PushFront(et)
+	return true
+}
+
+func (c *lru) PutPinned(key interface{}, value interface{}, lifeSpan time.Duration) error {
+	var node, ok = c.items[key]
+	if (!ok || !node.Value.(*entry).pinned) && c.pinned.full() {
+		return ErrPinLimit
+	}
+	if ok {
+		c.PutWithExpire(key, value, lifeSpan)
+	} else {
+		// 不经过准入策略
+		c.put(key, value, lifeSpan, c.expireOpt)
+	}
+	return c.Pin(key)
+}
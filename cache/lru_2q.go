@@ -13,9 +13,10 @@ import (
 5. LRU队列淘汰末尾的数据。
 */
 type LRU2QCache struct {
-	fifo  *lru         // FIFO队列
-	cache *lru         // 缓存队列
-	lock  sync.RWMutex // lock
+	fifo     *lru         // FIFO队列
+	cache    *lru         // 缓存队列
+	lock     sync.RWMutex // lock
+	atomicOp              // 原子操作
 }
 
 func NewLRU2QCache(opt *Opt) (*LRU2QCache, error) {
@@ -24,13 +25,20 @@ func NewLRU2QCache(opt *Opt) (*LRU2QCache, error) {
 		err   error
 		cache *lru
 	)
-	if fifo, err = newLRU(opt); err != nil {
+	// FIFO队列只记录key，淘汰时不触发回调
+	var fifoOpt = *opt
+	fifoOpt.Callback = nil
+	fifoOpt.Admission = nil
+	if fifo, err = newLRU(&fifoOpt); err != nil {
 		return nil, err
 	}
 	if cache, err = newLRU(opt); err != nil {
 		return nil, err
 	}
-	return &LRU2QCache{cache: cache, fifo: fifo}, nil
+	var c = &LRU2QCache{cache: cache, fifo: fifo}
+	c.atomicOp = atomicOp{locker: &c.lock, op: lru2QOp{c: c}}
+	startWatchdog(c.cache.expire, c)
+	return c, nil
 }
 
 // 添加元素到缓存中，若存在则更新元素值 返回True
@@ -50,19 +58,33 @@ func (c *LRU2QCache) DeleteExpired() {
 // 不存在则添加，存在则更新
 // 需要注意永不过期与过期状态之间的切换
 func (c *LRU2QCache) PutWithExpire(key interface{}, value interface{}, lifeSpan time.Duration) bool {
+	return c.PutWithExpireOpt(key, value, lifeSpan, c.cache.expireOpt)
+}
+
+// 添加元素并按过期选项设置存活时长
+func (c *LRU2QCache) PutWithExpireOpt(key interface{}, value interface{}, lifeSpan time.Duration, o ExpireOpt) bool {
 	c.lock.Lock()
 	defer c.lock.Unlock()
+	return c.put(key, value, lifeSpan, o)
+}
+
+func (c *LRU2QCache) put(key interface{}, value interface{}, lifeSpan time.Duration, o ExpireOpt) bool {
+	// 已在缓存队列中则直接更新
+	if c.cache.exist(key) {
+		return c.cache.PutWithExpireOpt(key, value, lifeSpan, o)
+	}
 	// 1. 检查是否在FIFO队列中
 	if !c.fifo.exist(key) {
 		// 1.1 不存在，添加到队列中
 		// 因为FIFO队列的元素值不会被查询，因此使用空结构体即可
-		c.fifo.Put(key, struct{}{})
+		// FIFO队列的元素不会被访问，按插入顺序淘汰
+		c.fifo.put(key, struct{}{}, NoExpiration, ExpireOpt{})
 		return false
 	}
 
 	// 1.2 存在，将该元素从FIFO队列移除，然后添加元素到cache中
 	c.fifo.Remove(key)
-	return c.cache.PutWithExpire(key, value, lifeSpan)
+	return c.cache.PutWithExpireOpt(key, value, lifeSpan, o)
 }
 
 // 从缓存中获取元素，若存在则返回True
@@ -79,7 +101,7 @@ func (c *LRU2QCache) Remove(key interface{}) bool {
 	return c.cache.Remove(key)
 }
 
-// 当前缓存中元素个数
+// 当前缓存中元素个数，FIFO队列只记录key，不计算在内
 func (c *LRU2QCache) Len() int {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
@@ -93,3 +115,48 @@ func (c *LRU2QCache) Clear() {
 	c.fifo.Clear()
 	c.cache.Clear()
 }
+
+// 固定缓存队列中的元素
+func (c *LRU2QCache) Pin(key interface{}) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.cache.Pin(key)
+}
+
+func (c *LRU2QCache) Unpin(key interface{}) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.cache.Unpin(key)
+}
+
+// 添加元素并固定，无需经过FIFO队列即写入缓存队列
+func (c *LRU2QCache) PutPinned(key interface{}, value interface{}, lifeSpan time.Duration) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if err := c.cache.PutPinned(key, value, lifeSpan); err != nil {
+		return err
+	}
+	c.fifo.Remove(key)
+	return nil
+}
+
+// 未加锁的2Q基本操作
+type lru2QOp struct {
+	c *LRU2QCache
+}
+
+func (op lru2QOp) PutWithExpire(key interface{}, value interface{}, lifeSpan time.Duration) bool {
+	return op.c.put(key, value, lifeSpan, op.c.cache.expireOpt)
+}
+
+func (op lru2QOp) Remove(key interface{}) bool {
+	return op.c.cache.Remove(key)
+}
+
+func (op lru2QOp) peek(key interface{}) (*item, bool) {
+	return op.c.cache.peek(key)
+}
+
+func (op lru2QOp) now() int64 {
+	return op.c.cache.now()
+}
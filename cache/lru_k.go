@@ -23,22 +23,39 @@ type LRUkCache struct {
 	lock sync.RWMutex
 	// 写缓存频次
 	k int
+	// 历史访问节点最小更新间隔
+	minUpdateInterval time.Duration
+	// 原子操作
+	atomicOp
 }
 
 func NewLRUkCache(opt *Opt) (*LRUkCache, error) {
 	if opt.LruKMinUpdateInterval == 0 {
 		opt.LruKMinUpdateInterval = DefaultLruKMinUpdateInterval
 	}
+	if opt.LruK <= 0 {
+		opt.LruK = DefaultLruK
+	}
 
 	var (
 		history *lru
+		cache   *lru
 		err     error
 	)
-	if history, err = newLRU(opt); err != nil {
+	// 历史访问队列只记录访问频次，淘汰时不触发回调
+	var historyOpt = *opt
+	historyOpt.Callback = nil
+	historyOpt.Admission = nil
+	if history, err = newLRU(&historyOpt); err != nil {
+		return nil, err
+	}
+	if cache, err = newLRU(opt); err != nil {
 		return nil, err
 	}
-	var cache, _ = newLRU(opt)
-	return &LRUkCache{k: opt.LruK, history: history, cache: cache}, nil
+	var c = &LRUkCache{k: opt.LruK, minUpdateInterval: opt.LruKMinUpdateInterval, history: history, cache: cache}
+	c.atomicOp = atomicOp{locker: &c.lock, op: lruKOp{c: c}}
+	startWatchdog(c.cache.expire, c)
+	return c, nil
 }
 
 func (c *LRUkCache) Put(key interface{}, value interface{}) bool {
@@ -47,41 +64,46 @@ func (c *LRUkCache) Put(key interface{}, value interface{}) bool {
 
 // 添加元素到缓存中，若存在则更新元素值 返回True
 func (c *LRUkCache) PutWithExpire(key interface{}, value interface{}, lifeSpan time.Duration) bool {
+	return c.PutWithExpireOpt(key, value, lifeSpan, c.cache.expireOpt)
+}
+
+func (c *LRUkCache) PutWithExpireOpt(key interface{}, value interface{}, lifeSpan time.Duration, o ExpireOpt) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.put(key, value, lifeSpan, o)
+}
 
+func (c *LRUkCache) put(key interface{}, value interface{}, lifeSpan time.Duration, o ExpireOpt) bool {
 	var (
 		it *list.Element
 		ok bool
 	)
 
-	c.lock.Lock()
-	defer c.lock.Unlock()
-	// 1. 是否已存在于缓存中
-	if it, ok = c.cache.items[key]; ok {
-		it.Value.(*entry).item.value = value
-		c.cache.evictList.MoveToFront(it)
-		return false
+	// 1. 是否已存在于缓存中，或无需访问历史即可写入缓存
+	if c.cache.exist(key) || c.k <= 1 {
+		return c.cache.PutWithExpireOpt(key, value, lifeSpan, o)
 	}
 
-	var het = entryWithHistoryPool.Get().(*entryWithHistory)
-	het.Reset()
+	var now = c.cache.now()
 	// 2. 检查是否在历史访问队列中
 	// 2.1 节点存在
 	if it, ok = c.history.items[key]; ok {
-		het = it.Value.(*entryWithHistory)
+		var het = it.Value.(*entry).value.(*entryWithHistory)
 		// 热度削减
-		het.Hot()
+		het.Hot(now, c.minUpdateInterval)
 
 		// 访问频次自增
 		het.freq++
-		it.Value = het
+		het.updateTime = now
 
 		// 频次达到条件
 		if het.freq >= c.k {
 			// 从历史访问列表中移除
 			c.history.removeElement(it)
+			entryWithHistoryPool.Put(het)
 
 			// 添加到缓存中
-			return c.cache.put(key, value, lifeSpan)
+			return c.cache.put(key, value, lifeSpan, o)
 		}
 		// 频次未达条件, 调整历史访问列表
 		c.history.evictList.MoveToFront(it)
@@ -90,13 +112,13 @@ func (c *LRUkCache) PutWithExpire(key interface{}, value interface{}, lifeSpan t
 
 	// 2.2 不存在于历史访问列表中
 	// 记录key
+	var het = entryWithHistoryPool.Get().(*entryWithHistory)
+	het.Reset()
 	het.key = key
-	//het.entry.key = key
-	//het.entry.item.value = value
 	het.freq = 1
 	// 更新时间
-	het.updateTime = time.Now().UnixNano()
-	c.history.putItem(key, het)
+	het.updateTime = now
+	c.history.put(key, het, NoExpiration, ExpireOpt{})
 	return false
 }
 
@@ -118,8 +140,8 @@ func (c *LRUkCache) Remove(key interface{}) bool {
 func (c *LRUkCache) DeleteExpired() {
 	c.lock.Lock()
 	defer c.lock.Unlock()
+	// 历史访问队列中的元素永不过期
 	c.cache.DeleteExpired()
-	c.history.DeleteExpired()
 }
 
 func (c *LRUkCache) Len() int {
@@ -135,6 +157,55 @@ func (c *LRUkCache) Clear() {
 	c.history.Clear()
 }
 
+// 固定缓存队列中的元素
+func (c *LRUkCache) Pin(key interface{}) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.cache.Pin(key)
+}
+
+func (c *LRUkCache) Unpin(key interface{}) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.cache.Unpin(key)
+}
+
+// 添加元素并固定，无需达到K次访问即写入缓存队列
+func (c *LRUkCache) PutPinned(key interface{}, value interface{}, lifeSpan time.Duration) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if err := c.cache.PutPinned(key, value, lifeSpan); err != nil {
+		return err
+	}
+	if it, ok := c.history.items[key]; ok {
+		var het = it.Value.(*entry).value.(*entryWithHistory)
+		c.history.removeElement(it)
+		entryWithHistoryPool.Put(het)
+	}
+	return nil
+}
+
+// 未加锁的LRU-K基本操作
+type lruKOp struct {
+	c *LRUkCache
+}
+
+func (op lruKOp) PutWithExpire(key interface{}, value interface{}, lifeSpan time.Duration) bool {
+	return op.c.put(key, value, lifeSpan, op.c.cache.expireOpt)
+}
+
+func (op lruKOp) Remove(key interface{}) bool {
+	return op.c.cache.Remove(key)
+}
+
+func (op lruKOp) peek(key interface{}) (*item, bool) {
+	return op.c.cache.peek(key)
+}
+
+func (op lruKOp) now() int64 {
+	return op.c.cache.now()
+}
+
 type entryWithHistory struct {
 	key        interface{}
 	freq       int   // 频次
@@ -147,9 +218,9 @@ func (e *entryWithHistory) Reset() {
 	e.updateTime = 0
 }
 
-func (e *entryWithHistory) Hot() {
-	var now = time.Now().UnixNano()
-	var cnt = float64(now-e.updateTime) / float64(DefaultLruKMinUpdateInterval)
+// Hot 热度削减，每经过一个interval频次减1
+func (e *entryWithHistory) Hot(now int64, interval time.Duration) {
+	var cnt = float64(now-e.updateTime) / float64(interval)
 	e.freq -= int(cnt)
 	if e.freq < 0 {
 		e.freq = 0
@@ -158,4 +229,5 @@ func (e *entryWithHistory) Hot() {
 
 const (
 	DefaultLruKMinUpdateInterval = 10 * time.Second
+	DefaultLruK                  = 2
 )
@@ -1,8 +1,8 @@
 package cache
 
 import (
+	"container/list"
 	"sync"
-	"sync/atomic"
 	"time"
 )
 
@@ -17,185 +17,427 @@ Q-history按照LRU淘汰数据的索引。
 */
 
 type LRUMQCache struct {
-	cache     *lru         // MQ Cache
-	levelList []*lru       // 将历史访问队列记为levelList[0], level list
-	level     int          // 级
-	capacity  int          // 容量
-	size      int          // 使用大小
-	lock      sync.RWMutex // lock
-	k         int          // 升级
-	ik        int64        // 服务绝对自增k值
+	*lruMQ
+	lock sync.RWMutex // lock
+	atomicOp
 }
 
-// cache如何与levelList关联？？ 比如从Cache中移除一个key，如何保证正确地从对应的level lru移除节点
-
 func NewLRUMQCache(opt *Opt) (*LRUMQCache, error) {
 	var (
-		cache *lru
-		err   error
+		mq  *lruMQ
+		err error
 	)
-	if cache, err = newLRU(opt); err != nil {
+	if mq, err = newLRUMQ(opt); err != nil {
 		return nil, err
 	}
-	var levelList = make([]*lru, opt.LRUMQLevel+1, opt.LRUMQLevel+1)
-	for i := 0; i < opt.Capacity; i++ {
-		if levelList[i], err = newLRU(opt); err != nil {
-			return nil, err
-		}
-	}
-	var c = &LRUMQCache{cache: cache, levelList: levelList, level: opt.LRUMQLevel, k: opt.LruK, capacity: opt.Capacity}
-	// 绝对K值自增。
-	// 在一个自增间隔添加的元素在一个优先级队列中
-	go func(d time.Duration, c *LRUMQCache) {
-		var tc = time.NewTicker(d)
-		for range tc.C {
-			atomic.AddInt64(&c.ik, 1)
-		}
-	}(opt.LruKMinUpdateInterval, c)
-	// 定期更新等级。100ms
-	go c.updateLevel()
+	var c = &LRUMQCache{lruMQ: mq}
+	c.atomicOp = atomicOp{locker: &c.lock, op: c.lruMQ}
+	startWatchdog(c.expire, c)
 	return c, nil
 }
 
 func (c *LRUMQCache) Get(key interface{}) (interface{}, bool) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
-	var v, ok = c.cache.Get(key)
-	if !ok {
-		return nil, false
+	return c.lruMQ.Get(key)
+}
+
+func (c *LRUMQCache) Put(key, value interface{}) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.lruMQ.Put(key, value)
+}
+
+func (c *LRUMQCache) PutWithExpire(key interface{}, value interface{}, lifeSpan time.Duration) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.lruMQ.PutWithExpire(key, value, lifeSpan)
+}
+
+func (c *LRUMQCache) PutWithExpireOpt(key interface{}, value interface{}, lifeSpan time.Duration, o ExpireOpt) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.lruMQ.PutWithExpireOpt(key, value, lifeSpan, o)
+}
+
+func (c *LRUMQCache) Remove(key interface{}) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.lruMQ.Remove(key)
+}
+
+func (c *LRUMQCache) DeleteExpired() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.lruMQ.DeleteExpired()
+}
+
+func (c *LRUMQCache) Len() int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.lruMQ.Len()
+}
+
+func (c *LRUMQCache) Clear() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.lruMQ.Clear()
+}
+
+func (c *LRUMQCache) Pin(key interface{}) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.lruMQ.Pin(key)
+}
+
+func (c *LRUMQCache) Unpin(key interface{}) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.lruMQ.Unpin(key)
+}
+
+func (c *LRUMQCache) PutPinned(key interface{}, value interface{}, lifeSpan time.Duration) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.lruMQ.PutPinned(key, value, lifeSpan)
+}
+
+type lruMQ struct {
+	queues    []*list.List                  // 多级LRU队列，queues[0]优先级最低
+	items     map[interface{}]*list.Element // 绑定元素key和队列节点
+	history   *lru                          // Q-history，记录被淘汰元素的访问频次
+	capacity  int                           // 容量
+	size      int                           // 使用大小
+	k         int                           // 访问频次每达到k次提升一级
+	lifeTime  time.Duration                 // 超过lifeTime未被访问则降低一级
+	onEvict   EvictCallback                 // 淘汰元素时执行的回调
+	admission AdmissionPolicy               // 准入策略
+	pinned    *pinList                      // 固定的元素，不在各级队列中
+	*expire                                 // 过期属性
+}
+
+func newLRUMQ(opt *Opt) (*lruMQ, error) {
+	if opt.Capacity <= 0 {
+		return nil, ErrSize
+	}
+	if opt.LRUMQLevel <= 0 {
+		opt.LRUMQLevel = DefaultLRUMQLevel
+	}
+	if opt.LruK <= 0 {
+		opt.LruK = DefaultLruK
+	}
+	if opt.LruKMinUpdateInterval <= 0 {
+		opt.LruKMinUpdateInterval = DefaultLruKMinUpdateInterval
 	}
 
-	var mq = v.(*mqEntry)
-	mq.freq++
-	// 惰性检查级别
-	mq.level = c.checkLevel(key, mq.freq, mq.level)
-	return mq.value, true
-}
-
-func (c *LRUMQCache) updateLevel() {
-	var tc = time.NewTicker(100 * time.Millisecond)
-	for range tc.C {
-		for i := range c.levelList {
-			c.lock.Lock()
-			for node := c.levelList[i].evictList.Back(); node != nil; node = node.Prev() {
-				var mqe = node.Value.(*mqEntry)
-				mqe.level = c.checkLevel(mqe.key, mqe.freq, mqe.level)
-			}
-			// 如果在中间过程出现移除退出，则可能出现死锁
-			c.lock.Unlock()
-		}
+	// Q-history只记录访问频次，淘汰时不触发回调
+	var historyOpt = *opt
+	historyOpt.Callback = nil
+	historyOpt.Admission = nil
+	var history, _ = newLRU(&historyOpt)
+
+	var queues = make([]*list.List, opt.LRUMQLevel)
+	for i := range queues {
+		queues[i] = list.New()
 	}
+	return &lruMQ{
+		queues:    queues,
+		items:     make(map[interface{}]*list.Element),
+		history:   history,
+		capacity:  opt.Capacity,
+		k:         opt.LruK,
+		lifeTime:  opt.LruKMinUpdateInterval,
+		onEvict:   opt.Callback,
+		admission: opt.Admission,
+		pinned:    newPinList(opt),
+		expire:    newExpire(opt),
+	}, nil
 }
 
-// return [0, level], [0, level+1)
-func (c *LRUMQCache) checkLevel(key interface{}, freq int, level int) int {
-	var a = atomic.LoadInt64(&c.ik)
-	var newLevel = (freq - int(a)) / c.k
-	if level != newLevel {
-		// 从旧级别中移除节点
-		c.levelList[level].Remove(key)
-		// 添加到新级别中
-		c.levelList[newLevel].putItem(key, struct{}{})
+func (c *lruMQ) Get(key interface{}) (interface{}, bool) {
+	var (
+		node *list.Element
+		ok   bool
+	)
+	if node, ok = c.items[key]; !ok {
+		return nil, false
 	}
-	return newLevel
+
+	var (
+		et  = node.Value.(*mqEntry)
+		now = c.now()
+	)
+	if et.expiredAt(now) {
+		c.removeElement(node)
+		return nil, false
+	}
+	et.touch(now)
+
+	// 固定的元素不参与频次统计
+	if !et.pinned {
+		c.access(node, now)
+	}
+	c.adjust(now)
+	return et.value, true
 }
 
-func (c *LRUMQCache) PutWithExpire(key interface{}, value interface{}, lifeSpan time.Duration) bool {
-	c.lock.Lock()
-	defer c.lock.Unlock()
+func (c *lruMQ) Put(key, value interface{}) bool {
+	return c.PutWithExpire(key, value, NoExpiration)
+}
 
-	// 1. 查询是否存在，存在则更新
-	// 	1.1 检查level
-	// 2. 不存在则新增，其level为1
-	//  2.1 是否有数据被逐出
+// 不存在则添加，存在则更新；return 是否淘汰元素
+func (c *lruMQ) PutWithExpire(key interface{}, value interface{}, lifeSpan time.Duration) bool {
+	return c.PutWithExpireOpt(key, value, lifeSpan, c.expireOpt)
+}
 
+func (c *lruMQ) PutWithExpireOpt(key interface{}, value interface{}, lifeSpan time.Duration, o ExpireOpt) bool {
 	var (
-		_, ok = c.cache.Get(key)
-		elem  interface{}
-		evict bool
+		node *list.Element
+		ok   bool
+		now  = c.now()
 	)
-	if !ok {
-		var et = mqEntryPool.Get().(*mqEntry)
-		et.Reset()
-		et.key = key
-		et.level = 1
-		et.freq = int(c.ik)
-		et.value = value
-		et.expiration = c.cache.absoluteTime(lifeSpan)
-		elem, evict = c.cache.putItem2(key, et)
 
-		// 如果淘汰了元素则将等级队列中对应的元素移除
-		if evict && elem != nil {
-			var el = elem.(*mqEntry)
-			c.levelList[el.freq].Remove(key)
+	// 1. 存在则更新
+	if node, ok = c.items[key]; ok {
+		var et = node.Value.(*mqEntry)
+		et.value = value
+		c.setExpire(&et.item, lifeSpan, o)
+		if !et.pinned {
+			c.access(node, now)
 		}
-		return evict
+		c.adjust(now)
+		return false
 	}
 
-	var mqe = c.cache.items[key].Value.(*mqEntry)
-	// 移除等级队列中的元素
-	c.levelList[mqe.level].Remove(key)
-	// 更新
-	mqe.key = key
-	mqe.value = value
-	mqe.freq = int(c.ik)
-	mqe.level = 1
-	// 注册到等级队列中
-	if elem, evict = c.levelList[mqe.level].putItem2(key, struct{}{}); evict && elem != nil {
-		//如果淘汰了数据
-		c.cache.Remove(key)
+	// 不满足准入策略则不写入
+	if !admit(c.admission, key) {
+		return false
 	}
-	return false
+	return c.put(key, value, lifeSpan, o, now)
 }
 
-func (c *LRUMQCache) Put(key, value interface{}) bool {
-	return c.PutWithExpire(key, value, NoExpiration)
+// 添加新元素；return 是否淘汰元素
+func (c *lruMQ) put(key interface{}, value interface{}, lifeSpan time.Duration, o ExpireOpt, now int64) bool {
+	var (
+		node *list.Element
+		ok   bool
+	)
+
+	// 2. 不存在则新增，若在Q-history中则恢复其访问频次
+	var freq int
+	if node, ok = c.history.items[key]; ok {
+		freq = node.Value.(*entry).value.(int)
+		c.history.removeElement(node)
+	}
+
+	// 2.1 容量已满则淘汰
+	var evict = c.size >= c.capacity
+	if evict {
+		c.evict()
+	}
+
+	var et = mqEntryPool.Get().(*mqEntry)
+	et.Reset()
+	et.key = key
+	et.value = value
+	c.setExpire(&et.item, lifeSpan, o)
+	et.freq = freq + 1
+	et.level = c.levelOf(et.freq)
+	et.demoteTime = now + int64(c.lifeTime)
+	c.items[key] = c.queues[et.level].PushFront(et)
+	c.size++
+	c.adjust(now)
+	return evict
 }
 
-func (c *LRUMQCache) Remove(key interface{}) bool {
-	c.lock.Lock()
-	defer c.lock.Unlock()
+// 根据访问频次计算所在等级 [0, len(queues))
+func (c *lruMQ) levelOf(freq int) int {
+	var level = (freq - 1) / c.k
+	if level >= len(c.queues) {
+		level = len(c.queues) - 1
+	}
+	return level
+}
+
+// 访问节点：频次自增，重新计算等级并移动到对应队列头部
+func (c *lruMQ) access(node *list.Element, now int64) {
+	var et = node.Value.(*mqEntry)
+	et.freq++
+	et.demoteTime = now + int64(c.lifeTime)
+
+	var level = c.levelOf(et.freq)
+	if level == et.level {
+		c.queues[level].MoveToFront(node)
+		return
+	}
+	c.queues[et.level].Remove(node)
+	et.level = level
+	c.items[et.key] = c.queues[level].PushFront(et)
+}
+
+// 检查各级队列尾部元素，长时间未被访问则降低一级
+func (c *lruMQ) adjust(now int64) {
+	for i := 1; i < len(c.queues); i++ {
+		var node = c.queues[i].Back()
+		if node == nil {
+			continue
+		}
+		var et = node.Value.(*mqEntry)
+		if et.demoteTime >= now {
+			continue
+		}
+		c.queues[i].Remove(node)
+		et.level = i - 1
+		et.demoteTime = now + int64(c.lifeTime)
+		c.items[et.key] = c.queues[i-1].PushFront(et)
+	}
+}
+
+// 从最低一级队列开始按照LRU淘汰，并将其访问频次记录到Q-history
+func (c *lruMQ) evict() {
+	for _, queue := range c.queues {
+		var node = queue.Back()
+		if node == nil {
+			continue
+		}
+		var et = node.Value.(*mqEntry)
+		c.history.put(et.key, et.freq, NoExpiration, ExpireOpt{})
+		c.removeElement(node)
+		return
+	}
+}
+
+func (c *lruMQ) removeElement(node *list.Element) {
+	var et = node.Value.(*mqEntry)
+	if et.pinned {
+		c.pinned.list.Remove(node)
+	} else {
+		c.queues[et.level].Remove(node)
+	}
+	delete(c.items, et.key)
+	c.size--
+	if c.onEvict != nil {
+		// entry 会被放回对象池复用，回调只能捕获其副本
+		var key, value = et.key, et.value
+		_ = c.goroutinePool.Submit(func() {
+			c.onEvict(key, value)
+		})
+	}
+	mqEntryPool.Put(et)
+}
 
-	var elem, ok = c.cache.items[key]
-	if !ok {
+func (c *lruMQ) Remove(key interface{}) bool {
+	var (
+		node *list.Element
+		ok   bool
+	)
+	if node, ok = c.items[key]; !ok {
 		return false
 	}
 
-	var mqe = elem.Value.(*mqEntry)
-	// 1. 从Cache中移除
-	ok = c.cache.Remove(key)
-	// 2. 从等级队列中移除
-	c.levelList[mqe.level].Remove(key)
-	return ok
+	var expired = node.Value.(*mqEntry).expiredAt(c.now())
+	c.removeElement(node)
+	return !expired
 }
 
-func (c *LRUMQCache) DeleteExpired() {
+func (c *lruMQ) DeleteExpired() {
+	var now = c.now() // 减少系统调用
+	for _, queue := range c.queues {
+		c.deleteExpired(queue, now)
+	}
+	c.deleteExpired(c.pinned.list, now)
+}
 
+func (c *lruMQ) deleteExpired(queue *list.List, now int64) {
+	var next *list.Element
+	for node := queue.Front(); node != nil; node = next {
+		next = node.Next()
+		if node.Value.(*mqEntry).expiredAt(now) {
+			c.removeElement(node)
+		}
+	}
 }
 
-func (c *LRUMQCache) Len() int {
-	c.lock.RLock()
-	defer c.lock.RUnlock()
-	return c.cache.Len()
+func (c *lruMQ) Len() int {
+	return c.size
 }
 
-func (c *LRUMQCache) Clear() {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-	c.cache.Clear()
-	for i := range c.levelList {
-		c.levelList[i].Clear()
+func (c *lruMQ) Clear() {
+	for k, v := range c.items {
+		if c.onEvict != nil {
+			c.onEvict(k, v.Value.(*mqEntry).value)
+		}
+		delete(c.items, k)
+	}
+	for _, queue := range c.queues {
+		queue.Init()
 	}
+	c.pinned.list.Init()
+	c.history.Clear()
+	c.size = 0
 }
 
 type mqEntry struct {
 	entry
-	freq  int
-	level int
+	freq       int   // 访问频次
+	level      int   // 所在等级
+	demoteTime int64 // 降级绝对时间
 }
 
 func (m *mqEntry) Reset() {
 	m.entry.Reset()
 	m.freq = 0
 	m.level = 0
+	m.demoteTime = 0
+}
+
+const (
+	DefaultLRUMQLevel = 4
+)
+
+// 固定元素，将其从所在队列移到固定链表
+func (c *lruMQ) Pin(key interface{}) error {
+	var node, ok = c.items[key]
+	if !ok || node.Value.(*mqEntry).expiredAt(c.now()) {
+		return ErrNotFound
+	}
+	var et = node.Value.(*mqEntry)
+	if et.pinned {
+		return nil
+	}
+	if c.pinned.full() {
+		return ErrPinLimit
+	}
+	c.queues[et.level].Remove(node)
+	et.pinned = true
+	c.items[key] = c.pinned.list.PushFront(et)
+	return nil
+}
+
+// 取消固定，放回固定前所在队列的头部
+func (c *lruMQ) Unpin(key interface{}) bool {
+	var node, ok = c.items[key]
+	if !ok || !node.Value.(*mqEntry).pinned {
+		return false
+	}
+	var et = node.Value.(*mqEntry)
+	c.pinned.list.Remove(node)
+	et.pinned = false
+	et.demoteTime = c.now() + int64(c.lifeTime)
+	c.items[key] = c.queues[et.level].PushFront(et)
+	return true
+}
+
+func (c *lruMQ) PutPinned(key interface{}, value interface{}, lifeSpan time.Duration) error {
+	var node, ok = c.items[key]
+	if (!ok || !node.Value.(*mqEntry).pinned) && c.pinned.full() {
+		return ErrPinLimit
+	}
+	if ok {
+		c.PutWithExpire(key, value, lifeSpan)
+	} else {
+		// 不经过准入策略
+		c.put(key, value, lifeSpan, c.expireOpt, c.now())
+	}
+	return c.Pin(key)
 }
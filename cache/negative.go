@@ -0,0 +1,197 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+否定缓存：
+1. PutNegative 记录key不存在，Lookup 对其返回ErrNegative，对未知的key返回ErrNotFound，可区分nil值、未命中与不存在；
+2. 否定元素保存在独立的LRU中，有单独的容量与默认存活时长，不占用正常元素的容量；
+3. 写入正常元素时删除同一key的否定元素，反之亦然；
+4. GetOrLoad 未命中时调用加载函数，加载函数返回ErrNotFound时写入否定元素，同一key的加载与写入互斥。
+*/
+
+const (
+	// 默认否定元素存活时长
+	DefaultNegativeTTL = 30 * time.Second
+	// 默认否定元素容量占正常元素容量的比例
+	DefaultNegativeRatio = 0.1
+	// 正常元素容量为0（无容量限制）时的默认否定元素容量
+	DefaultNegativeCapacity = 1024
+)
+
+// LoadFunc 加载元素，不存在返回ErrNotFound
+type LoadFunc func(key interface{}) (interface{}, error)
+
+// NegativeOpt 否定缓存配置
+type NegativeOpt struct {
+	TTL      time.Duration // 否定元素默认存活时长，默认30s
+	Capacity int           // 否定元素容量，默认为正常元素容量的10%
+}
+
+// 否定元素集合，值为空结构体
+type negatives struct {
+	cache ExpireCache
+	ttl   time.Duration
+}
+
+func newNegatives(opt *Opt, no NegativeOpt) (*negatives, error) {
+	if no.TTL <= 0 {
+		no.TTL = DefaultNegativeTTL
+	}
+	if no.Capacity <= 0 {
+		no.Capacity = int(float64(opt.Capacity) * DefaultNegativeRatio)
+		if opt.Capacity <= 0 {
+			no.Capacity = DefaultNegativeCapacity
+		} else if no.Capacity < 1 {
+			no.Capacity = 1
+		}
+	}
+	var c, err = NewLRUCache(&Opt{
+		Capacity:         no.Capacity,
+		Interval:         opt.Interval,
+		Clock:            opt.Clock,
+		AntsPoolCapacity: opt.AntsPoolCapacity,
+		AntsOptionList:   opt.AntsOptionList,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &negatives{cache: c, ttl: no.TTL}, nil
+}
+
+// ttl<=0时使用默认存活时长
+func (n *negatives) put(key interface{}, ttl time.Duration) bool {
+	if ttl <= 0 {
+		ttl = n.ttl
+	}
+	return n.cache.PutWithExpire(key, struct{}{}, ttl)
+}
+
+func (n *negatives) has(key interface{}) bool {
+	var _, ok = n.cache.Get(key)
+	return ok
+}
+
+func (n *negatives) remove(key interface{}) bool {
+	return n.cache.Remove(key)
+}
+
+type NegativeCache struct {
+	cache    ExpireCache
+	neg      *negatives
+	keyLocks [storeKeyLocks]sync.Mutex // 按key分段的锁，串行化同一key的加载与写入
+}
+
+func NewNegativeCache(ct CacheType, opt *Opt, no NegativeOpt) (*NegativeCache, error) {
+	var c, err = NewCache(ct, opt)
+	if err != nil {
+		return nil, err
+	}
+	var neg *negatives
+	if neg, err = newNegatives(opt, no); err != nil {
+		return nil, err
+	}
+	return &NegativeCache{cache: c, neg: neg}, nil
+}
+
+func (nc *NegativeCache) keyLock(key interface{}) *sync.Mutex {
+	return &nc.keyLocks[shardHash(key)%storeKeyLocks]
+}
+
+// Get 否定元素视为未命中
+func (nc *NegativeCache) Get(key interface{}) (interface{}, bool) {
+	return nc.cache.Get(key)
+}
+
+// Lookup 查询元素，已知不存在返回ErrNegative，未知返回ErrNotFound
+func (nc *NegativeCache) Lookup(key interface{}) (interface{}, error) {
+	if value, ok := nc.cache.Get(key); ok {
+		return value, nil
+	}
+	if nc.neg.has(key) {
+		return nil, ErrNegative
+	}
+	return nil, ErrNotFound
+}
+
+// GetOrLoad 未命中时加载，加载函数返回ErrNotFound时写入否定元素，已知不存在返回ErrNegative
+func (nc *NegativeCache) GetOrLoad(key interface{}, loader LoadFunc) (interface{}, error) {
+	if value, err := nc.Lookup(key); err != ErrNotFound {
+		return value, err
+	}
+	var l = nc.keyLock(key)
+	l.Lock()
+	defer l.Unlock()
+	if value, err := nc.Lookup(key); err != ErrNotFound {
+		return value, err
+	}
+	var value, err = loader(key)
+	if err == ErrNotFound {
+		nc.neg.put(key, 0)
+		return nil, ErrNegative
+	}
+	if err != nil {
+		return nil, err
+	}
+	nc.cache.Put(key, value)
+	return value, nil
+}
+
+// PutNegative 记录key不存在并删除正常元素，ttl<=0时使用默认存活时长
+func (nc *NegativeCache) PutNegative(key interface{}, ttl time.Duration) bool {
+	var l = nc.keyLock(key)
+	l.Lock()
+	defer l.Unlock()
+	nc.cache.Remove(key)
+	return nc.neg.put(key, ttl)
+}
+
+// IsNegative key是否已知不存在
+func (nc *NegativeCache) IsNegative(key interface{}) bool {
+	return nc.neg.has(key)
+}
+
+func (nc *NegativeCache) Put(key, value interface{}) bool {
+	return nc.PutWithExpire(key, value, NoExpiration)
+}
+
+// PutWithExpire 写入正常元素并删除否定元素
+func (nc *NegativeCache) PutWithExpire(key interface{}, value interface{}, lifeSpan time.Duration) bool {
+	var l = nc.keyLock(key)
+	l.Lock()
+	defer l.Unlock()
+	nc.neg.remove(key)
+	return nc.cache.PutWithExpire(key, value, lifeSpan)
+}
+
+// Remove 同时删除正常元素与否定元素，return 正常元素是否存在
+func (nc *NegativeCache) Remove(key interface{}) bool {
+	var l = nc.keyLock(key)
+	l.Lock()
+	defer l.Unlock()
+	nc.neg.remove(key)
+	return nc.cache.Remove(key)
+}
+
+// Len 正常元素个数
+func (nc *NegativeCache) Len() int {
+	return nc.cache.Len()
+}
+
+// NegativeLen 否定元素个数
+func (nc *NegativeCache) NegativeLen() int {
+	return nc.neg.cache.Len()
+}
+
+func (nc *NegativeCache) Clear() {
+	nc.cache.Clear()
+	nc.neg.cache.Clear()
+}
+
+func (nc *NegativeCache) DeleteExpired() {
+	nc.cache.DeleteExpired()
+	nc.neg.cache.DeleteExpired()
+}
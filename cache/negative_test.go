@@ -0,0 +1,106 @@
+package cache_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/1005281342/basic_component/cache"
+	"github.com/1005281342/basic_component/cache/cachetest"
+)
+
+func TestNegativeCache(t *testing.T) {
+	for _, ct := range []cache.CacheType{cache.Simple, cache.LRU, cache.LFU, cache.S3FIFO, cache.Adaptive} {
+		t.Run(ct.String(), func(t *testing.T) {
+			var clock = cachetest.NewFakeClock()
+			var c, err = cache.NewNegativeCache(ct, &cache.Opt{Capacity: 100, Interval: time.Hour, Clock: clock},
+				cache.NegativeOpt{TTL: time.Second})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// nil值、未知与不存在可以区分
+			c.Put("nil", nil)
+			if v, err := c.Lookup("nil"); err != nil || v != nil {
+				t.Fatalf("Lookup(nil) = %v, %v, want nil, nil", v, err)
+			}
+			if _, err := c.Lookup("a"); err != cache.ErrNotFound {
+				t.Fatalf("Lookup(a) = %v, want ErrNotFound", err)
+			}
+			c.PutNegative("a", 0)
+			if _, err := c.Lookup("a"); err != cache.ErrNegative {
+				t.Fatalf("Lookup(a) = %v, want ErrNegative", err)
+			}
+			if _, ok := c.Get("a"); ok || c.Len() != 1 || c.NegativeLen() != 1 {
+				t.Fatalf("Get(a) hit or Len() = %d, NegativeLen() = %d, want 1, 1", c.Len(), c.NegativeLen())
+			}
+
+			// 默认存活时长
+			clock.Advance(2 * time.Second)
+			if _, err := c.Lookup("a"); err != cache.ErrNotFound {
+				t.Fatalf("Lookup(a) after ttl = %v, want ErrNotFound", err)
+			}
+
+			// 写入正常元素删除否定元素，反之亦然
+			c.PutNegative("b", time.Hour)
+			c.Put("b", 1)
+			if v, err := c.Lookup("b"); err != nil || v != 1 {
+				t.Fatalf("Lookup(b) = %v, %v, want 1", v, err)
+			}
+			c.PutNegative("b", time.Hour)
+			if _, ok := c.Get("b"); ok || !c.IsNegative("b") {
+				t.Fatalf("Get(b) hit after PutNegative")
+			}
+		})
+	}
+}
+
+func TestNegativeCacheCapacity(t *testing.T) {
+	var c, err = cache.NewNegativeCache(cache.LRU, &cache.Opt{Capacity: 100, Interval: time.Hour}, cache.NegativeOpt{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		c.Put(i, i)
+	}
+	for i := 100; i < 1000; i++ {
+		c.PutNegative(i, 0)
+	}
+	if c.Len() != 100 || c.NegativeLen() != 10 {
+		t.Fatalf("Len() = %d, NegativeLen() = %d, want 100, 10", c.Len(), c.NegativeLen())
+	}
+}
+
+func TestNegativeCacheGetOrLoad(t *testing.T) {
+	var c, err = cache.NewNegativeCache(cache.LRU, &cache.Opt{Capacity: 100, Interval: time.Hour}, cache.NegativeOpt{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loads int
+	var errLoad = errors.New("backend down")
+	var loader = func(key interface{}) (interface{}, error) {
+		loads++
+		switch key {
+		case "missing":
+			return nil, cache.ErrNotFound
+		case "broken":
+			return nil, errLoad
+		}
+		return fmt.Sprint(key), nil
+	}
+	for i := 0; i < 3; i++ {
+		if v, err := c.GetOrLoad("k", loader); err != nil || v != "k" {
+			t.Fatalf("GetOrLoad(k) = %v, %v, want k", v, err)
+		}
+		if _, err := c.GetOrLoad("missing", loader); err != cache.ErrNegative {
+			t.Fatalf("GetOrLoad(missing) = %v, want ErrNegative", err)
+		}
+		// 其他错误不缓存
+		if _, err := c.GetOrLoad("broken", loader); err != errLoad {
+			t.Fatalf("GetOrLoad(broken) = %v, want %v", err, errLoad)
+		}
+	}
+	if loads != 5 {
+		t.Fatalf("loads = %d, want 5", loads)
+	}
+}
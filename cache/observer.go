@@ -0,0 +1,198 @@
+package cache
+
+import (
+	"time"
+)
+
+/*
+可观察缓存：
+1. 在任意淘汰策略的缓存外层统计查询命中、写入与淘汰事件，通知给 Observer；
+2. 淘汰事件包装在淘汰回调中，包括淘汰、过期、移除与清空；
+3. 转发底层缓存的 ExpireOptCache、AtomicCache、PinnedCache 与 InspectCache 方法，写入成功时统计写入，Peek、Range 不统计查询；
+4. 底层缓存不支持时按次设置过期选项的写入与原子操作返回false，固定操作返回 ErrNotSupported。
+*/
+
+// Observer 缓存事件观察者，方法会被并发调用，需要并发安全且尽快返回
+type Observer interface {
+	// 查询元素，hit表示是否命中
+	OnGet(hit bool)
+	// 写入元素
+	OnPut()
+	// 元素被淘汰回调
+	OnEvict()
+}
+
+type ObservedCache struct {
+	ExpireCache
+	observer Observer
+}
+
+func NewObservedCache(ct CacheType, opt *Opt, o Observer) (*ObservedCache, error) {
+	var onEvict = opt.Callback
+	var cacheOpt = *opt
+	cacheOpt.Callback = func(key interface{}, value interface{}) {
+		o.OnEvict()
+		callEvict(onEvict, key, value)
+	}
+	var c, err = NewCache(ct, &cacheOpt)
+	if err != nil {
+		return nil, err
+	}
+	return &ObservedCache{ExpireCache: c, observer: o}, nil
+}
+
+func (oc *ObservedCache) Get(key interface{}) (interface{}, bool) {
+	var value, ok = oc.ExpireCache.Get(key)
+	oc.observer.OnGet(ok)
+	return value, ok
+}
+
+func (oc *ObservedCache) Put(key, value interface{}) bool {
+	return oc.observePut(key, oc.ExpireCache.Put(key, value))
+}
+
+func (oc *ObservedCache) PutWithExpire(key interface{}, value interface{}, lifeSpan time.Duration) bool {
+	return oc.observePut(key, oc.ExpireCache.PutWithExpire(key, value, lifeSpan))
+}
+
+func (oc *ObservedCache) PutWithExpireOpt(key interface{}, value interface{}, lifeSpan time.Duration, o ExpireOpt) bool {
+	var ec, ok = oc.ExpireCache.(ExpireOptCache)
+	if !ok {
+		return false
+	}
+	return oc.observePut(key, ec.PutWithExpireOpt(key, value, lifeSpan, o))
+}
+
+// 写入生效时统计写入，被准入策略拒绝或只记录了访问历史的key不统计
+func (oc *ObservedCache) observePut(key interface{}, ok bool) bool {
+	if _, resident := peekValue(oc.ExpireCache, key); ok || resident {
+		oc.observer.OnPut()
+	}
+	return ok
+}
+
+// Unwrap 返回底层缓存
+func (oc *ObservedCache) Unwrap() ExpireCache {
+	return oc.ExpireCache
+}
+
+// 原子操作，写入成功时统计写入
+func (oc *ObservedCache) PutIfAbsent(key, value interface{}) bool {
+	return oc.PutIfAbsentWithExpire(key, value, NoExpiration)
+}
+
+func (oc *ObservedCache) PutIfAbsentWithExpire(key, value interface{}, lifeSpan time.Duration) bool {
+	var ac, ok = oc.ExpireCache.(AtomicCache)
+	if !ok || !ac.PutIfAbsentWithExpire(key, value, lifeSpan) {
+		return false
+	}
+	oc.observer.OnPut()
+	return true
+}
+
+func (oc *ObservedCache) Replace(key, value interface{}) bool {
+	var ac, ok = oc.ExpireCache.(AtomicCache)
+	if !ok || !ac.Replace(key, value) {
+		return false
+	}
+	oc.observer.OnPut()
+	return true
+}
+
+func (oc *ObservedCache) ReplaceWithExpire(key, value interface{}, lifeSpan time.Duration) bool {
+	var ac, ok = oc.ExpireCache.(AtomicCache)
+	if !ok || !ac.ReplaceWithExpire(key, value, lifeSpan) {
+		return false
+	}
+	oc.observer.OnPut()
+	return true
+}
+
+func (oc *ObservedCache) CompareAndSwap(key, old, new interface{}) bool {
+	var ac, ok = oc.ExpireCache.(AtomicCache)
+	if !ok || !ac.CompareAndSwap(key, old, new) {
+		return false
+	}
+	oc.observer.OnPut()
+	return true
+}
+
+func (oc *ObservedCache) CompareAndSwapWithExpire(key, old, new interface{}, lifeSpan time.Duration) bool {
+	var ac, ok = oc.ExpireCache.(AtomicCache)
+	if !ok || !ac.CompareAndSwapWithExpire(key, old, new, lifeSpan) {
+		return false
+	}
+	oc.observer.OnPut()
+	return true
+}
+
+func (oc *ObservedCache) Compute(key interface{}, fn ComputeFunc) (interface{}, bool) {
+	var ac, ok = oc.ExpireCache.(AtomicCache)
+	if !ok {
+		return nil, false
+	}
+	var value, exists = ac.Compute(key, fn)
+	if exists {
+		oc.observer.OnPut()
+	}
+	return value, exists
+}
+
+func (oc *ObservedCache) ComputeWithExpire(key interface{}, fn ComputeFunc, lifeSpan time.Duration) (interface{}, bool) {
+	var ac, ok = oc.ExpireCache.(AtomicCache)
+	if !ok {
+		return nil, false
+	}
+	var value, exists = ac.ComputeWithExpire(key, fn, lifeSpan)
+	if exists {
+		oc.observer.OnPut()
+	}
+	return value, exists
+}
+
+// 固定元素
+func (oc *ObservedCache) Pin(key interface{}) error {
+	var pc, ok = oc.ExpireCache.(PinnedCache)
+	if !ok {
+		return ErrNotSupported
+	}
+	return pc.Pin(key)
+}
+
+func (oc *ObservedCache) Unpin(key interface{}) bool {
+	var pc, ok = oc.ExpireCache.(PinnedCache)
+	return ok && pc.Unpin(key)
+}
+
+func (oc *ObservedCache) PutPinned(key interface{}, value interface{}, lifeSpan time.Duration) error {
+	var pc, ok = oc.ExpireCache.(PinnedCache)
+	if !ok {
+		return ErrNotSupported
+	}
+	if err := pc.PutPinned(key, value, lifeSpan); err != nil {
+		return err
+	}
+	oc.observer.OnPut()
+	return nil
+}
+
+// 查看内部元素，不统计查询
+func (oc *ObservedCache) Cap() int {
+	if ic, ok := oc.ExpireCache.(InspectCache); ok {
+		return ic.Cap()
+	}
+	return 0
+}
+
+func (oc *ObservedCache) Range(fn func(key, value interface{}, ttl time.Duration) bool) {
+	if ic, ok := oc.ExpireCache.(InspectCache); ok {
+		ic.Range(fn)
+	}
+}
+
+func (oc *ObservedCache) Peek(key interface{}) (interface{}, time.Duration, bool) {
+	if ic, ok := oc.ExpireCache.(InspectCache); ok {
+		return ic.Peek(key)
+	}
+	return nil, 0, false
+}
@@ -0,0 +1,98 @@
+package cache_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/1005281342/basic_component/cache"
+)
+
+type countObserver struct {
+	gets, puts, evicts int64
+}
+
+func (o *countObserver) OnGet(bool) { atomic.AddInt64(&o.gets, 1) }
+func (o *countObserver) OnPut()     { atomic.AddInt64(&o.puts, 1) }
+func (o *countObserver) OnEvict()   { atomic.AddInt64(&o.evicts, 1) }
+
+func TestObservedCache_Forward(t *testing.T) {
+	var o = &countObserver{}
+	var c, err = cache.NewObservedCache(cache.LRU, &cache.Opt{Capacity: 10, Interval: time.Hour}, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ec cache.ExpireCache = c
+	if _, ok := ec.(cache.AtomicCache); !ok {
+		t.Fatal("ObservedCache is not an AtomicCache")
+	}
+	if _, ok := ec.(cache.PinnedCache); !ok {
+		t.Fatal("ObservedCache is not a PinnedCache")
+	}
+	if _, ok := ec.(cache.InspectCache); !ok {
+		t.Fatal("ObservedCache is not an InspectCache")
+	}
+
+	// 原子操作写入成功时统计写入
+	if !c.PutIfAbsent("a", 1) || c.PutIfAbsent("a", 2) {
+		t.Fatal("PutIfAbsent")
+	}
+	if v, ok := c.Compute("a", func(old interface{}, exists bool) (interface{}, bool) {
+		return old.(int) + 1, true
+	}); !ok || v != 2 {
+		t.Fatalf("Compute = %v, %v, want 2, true", v, ok)
+	}
+	if n := atomic.LoadInt64(&o.puts); n != 2 {
+		t.Fatalf("puts = %d, want 2", n)
+	}
+	if err = c.Pin("a"); err != nil {
+		t.Fatal(err)
+	}
+	if !c.Unpin("a") {
+		t.Fatal("Unpin = false, want true")
+	}
+	// 查看不统计查询
+	if v, _, ok := c.Peek("a"); !ok || v != 2 {
+		t.Fatalf("Peek = %v, %v", v, ok)
+	}
+	if n := atomic.LoadInt64(&o.gets); n != 0 {
+		t.Fatalf("gets = %d, want 0", n)
+	}
+
+	// 底层缓存不支持固定
+	var cc, _ = cache.NewObservedCache(cache.Clock, &cache.Opt{Capacity: 10, Interval: time.Hour}, o)
+	cc.Put("a", 1)
+	if err = cc.Pin("a"); err != cache.ErrNotSupported {
+		t.Fatalf("Pin = %v, want ErrNotSupported", err)
+	}
+}
+
+func TestObservedCache_Put(t *testing.T) {
+	var o = &countObserver{}
+	var c, err = cache.NewObservedCache(cache.LRUk, &cache.Opt{Capacity: 10, LruK: 2, Interval: time.Hour}, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 首次写入只记录访问历史，不统计写入
+	c.Put("a", 1)
+	if n := atomic.LoadInt64(&o.puts); n != 0 {
+		t.Fatalf("puts = %d after history-only put, want 0", n)
+	}
+	c.PutWithExpire("a", 1, time.Minute)
+	if n := atomic.LoadInt64(&o.puts); n != 1 {
+		t.Fatalf("puts = %d, want 1", n)
+	}
+
+	var ec cache.ExpireCache = c
+	var oc, ok = ec.(cache.ExpireOptCache)
+	if !ok {
+		t.Fatal("ObservedCache is not an ExpireOptCache")
+	}
+	oc.PutWithExpireOpt("a", 2, time.Minute, cache.ExpireOpt{AfterAccess: true})
+	if v, ok := c.Get("a"); !ok || v != 2 {
+		t.Fatalf("Get = %v, %v, want 2", v, ok)
+	}
+	if n := atomic.LoadInt64(&o.puts); n != 2 {
+		t.Fatalf("puts = %d, want 2", n)
+	}
+}
@@ -0,0 +1,28 @@
+package cache
+
+import (
+	"container/list"
+)
+
+// 固定元素链表，固定的元素从淘汰结构中移出，不会被淘汰，但仍计入容量并会过期
+type pinList struct {
+	list  *list.List // 固定的元素，节点与所在缓存的淘汰结构节点类型一致
+	limit int        // 固定元素个数上限
+}
+
+// 固定元素个数上限默认为容量的一半，且至少保留一个可淘汰的位置
+func newPinList(opt *Opt) *pinList {
+	var limit = opt.PinLimit
+	if limit <= 0 {
+		limit = opt.Capacity / 2
+	}
+	if limit >= opt.Capacity {
+		limit = opt.Capacity - 1
+	}
+	return &pinList{list: list.New(), limit: limit}
+}
+
+// 固定元素个数是否已达上限
+func (p *pinList) full() bool {
+	return p.list.Len() >= p.limit
+}
@@ -0,0 +1,81 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/1005281342/basic_component/cache"
+	"github.com/1005281342/basic_component/cache/cachetest"
+)
+
+func TestPin(t *testing.T) {
+	for _, tt := range expireOptTypes {
+		if tt.ct == cache.Simple {
+			continue
+		}
+		t.Run(tt.ct.String(), func(t *testing.T) {
+			var clock = cachetest.NewFakeClock()
+			var ec, err = cache.NewCache(tt.ct, &cache.Opt{Capacity: 4, PinLimit: 2, Clock: clock, Interval: time.Hour})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var c = ec.(cache.PinnedCache)
+			var churn = func(from int) {
+				for i := from; i < from+100; i++ {
+					for j := 0; j < tt.puts; j++ {
+						c.Put(i, i)
+					}
+				}
+			}
+
+			if err := c.Pin("missing"); err != cache.ErrNotFound {
+				t.Fatalf("Pin(missing) = %v, want ErrNotFound", err)
+			}
+			if err := c.PutPinned("p1", 1, cache.NoExpiration); err != nil {
+				t.Fatal(err)
+			}
+			for j := 0; j < tt.puts; j++ {
+				c.Put("p2", 2)
+			}
+			if err := c.Pin("p2"); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.PutPinned("p3", 3, cache.NoExpiration); err != cache.ErrPinLimit {
+				t.Fatalf("PutPinned over limit = %v, want ErrPinLimit", err)
+			}
+
+			// 固定的元素不会被淘汰，且计入容量
+			churn(0)
+			for key, want := range map[string]int{"p1": 1, "p2": 2} {
+				if v, ok := c.Get(key); !ok || v != want {
+					t.Fatalf("Get(%s) = %v, %v after churn", key, v, ok)
+				}
+			}
+			if n := c.Len(); n != 4 {
+				t.Fatalf("Len() = %d, want 4", n)
+			}
+
+			// 取消固定后参与淘汰
+			if !c.Unpin("p1") || c.Unpin("p1") {
+				t.Fatal("Unpin should succeed exactly once")
+			}
+			churn(1000)
+			if _, ok := c.Get("p1"); ok {
+				t.Fatal("unpinned entry survived churn")
+			}
+
+			// 固定的元素仍会过期
+			if err := c.PutPinned("ttl", 1, time.Second); err != nil {
+				t.Fatal(err)
+			}
+			clock.Advance(2 * time.Second)
+			if _, ok := c.Get("ttl"); ok {
+				t.Fatal("pinned entry did not expire")
+			}
+			c.DeleteExpired()
+			if err := c.PutPinned("p3", 3, cache.NoExpiration); err != nil {
+				t.Fatalf("PutPinned after expiry = %v", err)
+			}
+		})
+	}
+}
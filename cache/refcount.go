@@ -0,0 +1,171 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+引用计数缓存：
+1. 元素值包装为带引用计数的refValue写入底层缓存，底层缓存可以是任意淘汰策略；
+2. Acquire 在元素未被回调前增加引用计数，Handle.Release 减少引用计数；
+3. 元素被淘汰、移除、覆盖或过期时，若仍有引用则推迟淘汰回调，由最后一个 Release 执行；
+4. Get 返回的值不持有引用，元素可能在使用期间被回调回收。
+*/
+
+// Handle 持有元素引用，使用完毕后必须调用 Release
+type Handle interface {
+	// 元素值
+	Value() interface{}
+	// 释放引用，重复释放无效
+	Release()
+}
+
+type RefCache struct {
+	cache   ExpireCache   // 底层缓存，值为*refValue
+	peeker  itemPeeker    // 查询底层缓存中被覆盖的元素，包括已过期但未回收的元素
+	onEvict EvictCallback // 淘汰元素时执行的回调
+	lock    sync.Mutex    // 串行化写入，覆盖时取得被覆盖的值
+}
+
+func NewRefCache(ct CacheType, opt *Opt) (*RefCache, error) {
+	// Bytes 只接受[]byte值，无法保存*refValue
+	if ct == Bytes {
+		return nil, fmt.Errorf("%v is not supported by RefCache", ct)
+	}
+	var rc = &RefCache{onEvict: opt.Callback}
+	var cacheOpt = *opt
+	cacheOpt.Callback = rc.evict
+	var c, err = NewCache(ct, &cacheOpt)
+	if err != nil {
+		return nil, err
+	}
+	var peeker, ok = c.(itemPeeker)
+	if !ok {
+		return nil, fmt.Errorf("%v is not supported by RefCache", ct)
+	}
+	rc.cache, rc.peeker = c, peeker
+	return rc, nil
+}
+
+type refValue struct {
+	key     interface{}
+	value   interface{}
+	lock    sync.Mutex
+	refs    int  // 引用计数
+	evicted bool // 已从底层缓存淘汰
+	done    bool // 已执行淘汰回调
+}
+
+type refHandle struct {
+	rv       *refValue
+	cache    *RefCache
+	released int32
+}
+
+func (h *refHandle) Value() interface{} {
+	return h.rv.value
+}
+
+func (h *refHandle) Release() {
+	if !atomic.CompareAndSwapInt32(&h.released, 0, 1) {
+		return
+	}
+	var rv = h.rv
+	rv.lock.Lock()
+	rv.refs--
+	var fire = rv.refs == 0 && rv.evicted && !rv.done
+	if fire {
+		rv.done = true
+	}
+	rv.lock.Unlock()
+	if fire {
+		callEvict(h.cache.onEvict, rv.key, rv.value)
+	}
+}
+
+// 底层缓存的淘汰回调，仍有引用时推迟到最后一个 Release
+func (rc *RefCache) evict(key interface{}, value interface{}) {
+	var rv = value.(*refValue)
+	rv.lock.Lock()
+	rv.evicted = true
+	var fire = rv.refs == 0 && !rv.done
+	if fire {
+		rv.done = true
+	}
+	rv.lock.Unlock()
+	if fire {
+		callEvict(rc.onEvict, key, rv.value)
+	}
+}
+
+// Acquire 获取元素并持有引用，return 元素不存在或已执行淘汰回调时为false
+func (rc *RefCache) Acquire(key interface{}) (Handle, bool) {
+	var v, ok = rc.cache.Get(key)
+	if !ok {
+		return nil, false
+	}
+	var rv = v.(*refValue)
+	rv.lock.Lock()
+	defer rv.lock.Unlock()
+	// 淘汰回调已执行，值可能已被回收
+	if rv.done {
+		return nil, false
+	}
+	rv.refs++
+	return &refHandle{rv: rv, cache: rc}, true
+}
+
+func (rc *RefCache) Get(key interface{}) (interface{}, bool) {
+	var v, ok = rc.cache.Get(key)
+	if !ok {
+		return nil, false
+	}
+	return v.(*refValue).value, true
+}
+
+func (rc *RefCache) Put(key, value interface{}) bool {
+	var rv = &refValue{key: key, value: value}
+	return rc.put(key, rv, func() bool {
+		return rc.cache.Put(key, rv)
+	})
+}
+
+func (rc *RefCache) PutWithExpire(key interface{}, value interface{}, lifeSpan time.Duration) bool {
+	var rv = &refValue{key: key, value: value}
+	return rc.put(key, rv, func() bool {
+		return rc.cache.PutWithExpire(key, rv, lifeSpan)
+	})
+}
+
+// 覆盖元素视为淘汰旧值：无引用时执行回调，否则推迟到最后一个 Release
+// 已过期但未回收的元素被原地更新时底层缓存不执行回调，同样需要淘汰旧值
+func (rc *RefCache) put(key interface{}, rv *refValue, put func() bool) bool {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	var old, exists = rc.peeker.peekItem(key)
+	var ok = put()
+	// 写入被拒绝时旧值仍然驻留
+	if cur, _ := peekValue(rc.cache, key); exists && cur == rv {
+		rc.evict(key, old.value)
+	}
+	return ok
+}
+
+func (rc *RefCache) Remove(key interface{}) bool {
+	return rc.cache.Remove(key)
+}
+
+func (rc *RefCache) DeleteExpired() {
+	rc.cache.DeleteExpired()
+}
+
+func (rc *RefCache) Len() int {
+	return rc.cache.Len()
+}
+
+func (rc *RefCache) Clear() {
+	rc.cache.Clear()
+}
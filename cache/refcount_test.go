@@ -0,0 +1,173 @@
+package cache_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/1005281342/basic_component/cache"
+	"github.com/1005281342/basic_component/cache/cachetest"
+)
+
+var refCacheTypes = []cache.CacheType{
+	cache.Simple, cache.LRU, cache.LFU, cache.LRUk, cache.LRU2q, cache.LRUmq,
+	cache.LIRS, cache.Clock, cache.ClockPro, cache.S3FIFO, cache.Adaptive,
+}
+
+// LRU-K、2Q等策略首次写入只记录历史，写入直到元素驻留
+func putResident(c *cache.RefCache, key, value interface{}) {
+	for i := 0; i < 2; i++ {
+		c.Put(key, value)
+		if h, ok := c.Acquire(key); ok {
+			h.Release()
+			return
+		}
+	}
+}
+
+func TestRefCache(t *testing.T) {
+	for _, ct := range refCacheTypes {
+		t.Run(ct.String(), func(t *testing.T) {
+			var evicted int32
+			var waitEvicted = func(want int32) {
+				t.Helper()
+				var deadline = time.Now().Add(5 * time.Second)
+				for atomic.LoadInt32(&evicted) != want {
+					if time.Now().After(deadline) {
+						t.Fatalf("callback ran %d times, want %d", atomic.LoadInt32(&evicted), want)
+					}
+					time.Sleep(time.Millisecond)
+				}
+			}
+			var c, err = cache.NewRefCache(ct, &cache.Opt{
+				Capacity: 4,
+				Interval: time.Hour,
+				Callback: func(key, value interface{}) { atomic.AddInt32(&evicted, 1) },
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			putResident(c, "k", "v")
+			var h1, ok1 = c.Acquire("k")
+			var h2, ok2 = c.Acquire("k")
+			if !ok1 || !ok2 || h1.Value() != "v" {
+				t.Fatalf("Acquire = %v, %v", ok1, ok2)
+			}
+
+			// 仍有引用时推迟回调
+			c.Remove("k")
+			time.Sleep(20 * time.Millisecond)
+			if n := atomic.LoadInt32(&evicted); n != 0 {
+				t.Fatalf("callback ran %d times while referenced", n)
+			}
+			h1.Release()
+			h1.Release()
+			if n := atomic.LoadInt32(&evicted); n != 0 {
+				t.Fatalf("callback ran %d times before the last release", n)
+			}
+			h2.Release()
+			waitEvicted(1)
+			if _, ok := c.Acquire("k"); ok {
+				t.Fatal("Acquire after eviction succeeded")
+			}
+
+			// 无引用时立即回调
+			putResident(c, "k2", "v2")
+			c.Remove("k2")
+			waitEvicted(2)
+		})
+	}
+}
+
+func TestRefCache_Bytes(t *testing.T) {
+	if _, err := cache.NewRefCache(cache.Bytes, &cache.Opt{Capacity: 1024, Interval: time.Hour}); err == nil {
+		t.Fatal("want error for Bytes, it cannot store wrapped values")
+	}
+}
+
+func TestRefCache_Overwrite(t *testing.T) {
+	for _, ct := range refCacheTypes {
+		t.Run(ct.String(), func(t *testing.T) {
+			var evicted = make(chan interface{}, 4)
+			var c, err = cache.NewRefCache(ct, &cache.Opt{
+				Capacity: 4,
+				Interval: time.Hour,
+				Callback: func(key, value interface{}) { evicted <- value },
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			putResident(c, "k", "v1")
+			var h, ok = c.Acquire("k")
+			if !ok {
+				t.Fatal("Acquire miss")
+			}
+
+			// 覆盖持有引用的元素，旧值的回调推迟到 Release
+			c.Put("k", "v2")
+			if v, _ := c.Get("k"); v != "v2" {
+				t.Fatalf("Get = %v, want v2", v)
+			}
+			select {
+			case v := <-evicted:
+				t.Fatalf("callback ran for %v while referenced", v)
+			case <-time.After(20 * time.Millisecond):
+			}
+			h.Release()
+			select {
+			case v := <-evicted:
+				if v != "v1" {
+					t.Fatalf("callback value = %v, want v1", v)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("callback for the overwritten value did not run")
+			}
+
+			// 无引用时覆盖立即回调
+			c.PutWithExpire("k", "v3", time.Hour)
+			select {
+			case v := <-evicted:
+				if v != "v2" {
+					t.Fatalf("callback value = %v, want v2", v)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("callback for the overwritten value did not run")
+			}
+		})
+	}
+}
+
+// 覆盖已过期但未回收的元素同样对旧值执行回调
+func TestRefCache_OverwriteExpired(t *testing.T) {
+	for _, ct := range refCacheTypes {
+		t.Run(ct.String(), func(t *testing.T) {
+			var clock = cachetest.NewFakeClock()
+			var evicted = make(chan interface{}, 4)
+			var c, err = cache.NewRefCache(ct, &cache.Opt{
+				Capacity: 4,
+				Interval: time.Hour,
+				Clock:    clock,
+				Callback: func(key, value interface{}) { evicted <- value },
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 2; i++ {
+				c.PutWithExpire("k", "v1", time.Second)
+				if _, ok := c.Get("k"); ok {
+					break
+				}
+			}
+			clock.Advance(2 * time.Second)
+			c.Put("k", "v2")
+			select {
+			case v := <-evicted:
+				if v != "v1" {
+					t.Fatalf("callback value = %v, want v1", v)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("callback for the expired value did not run")
+			}
+		})
+	}
+}
@@ -0,0 +1,405 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/1005281342/basic_component/ring_queue"
+)
+
+/*
+S3-FIFO：
+1. 使用小FIFO队列S（约10%容量）、主FIFO队列M（其余容量）以及只记录key的幽灵队列G；
+2. 每个元素带有2位访问频次，命中时在读锁下原子自增，最大为3；
+3. 新元素若在G中则写入M，否则写入S；
+4. 淘汰S队首元素时：频次大于1则移入M，否则淘汰并将key记录到G；
+5. 淘汰M队首元素时：频次大于0则频次减1并重新插入M队尾，否则淘汰。
+*/
+
+type S3FIFOCache struct {
+	*s3fifo
+	lock sync.RWMutex
+	atomicOp
+}
+
+func NewS3FIFOCache(opt *Opt) (*S3FIFOCache, error) {
+	var (
+		c   *s3fifo
+		err error
+	)
+	if c, err = newS3FIFO(opt); err != nil {
+		return nil, err
+	}
+	var sc = &S3FIFOCache{s3fifo: c}
+	sc.atomicOp = atomicOp{locker: &sc.lock, op: sc.s3fifo}
+	startWatchdog(sc.expire, sc)
+	return sc, nil
+}
+
+// Get 在读锁下查询并增加访问频次，只有命中过期元素时才获取写锁进行惰性回收
+func (sc *S3FIFOCache) Get(key interface{}) (interface{}, bool) {
+	sc.lock.RLock()
+	var value, ok, expired = sc.s3fifo.get(key)
+	sc.lock.RUnlock()
+	if expired {
+		sc.lock.Lock()
+		sc.s3fifo.removeExpired(key)
+		sc.lock.Unlock()
+	}
+	return value, ok
+}
+
+func (sc *S3FIFOCache) Put(key, value interface{}) bool {
+	sc.lock.Lock()
+	defer sc.lock.Unlock()
+	return sc.s3fifo.Put(key, value)
+}
+
+func (sc *S3FIFOCache) PutWithExpire(key interface{}, value interface{}, lifeSpan time.Duration) bool {
+	sc.lock.Lock()
+	defer sc.lock.Unlock()
+	return sc.s3fifo.PutWithExpire(key, value, lifeSpan)
+}
+
+func (sc *S3FIFOCache) Remove(key interface{}) bool {
+	sc.lock.Lock()
+	defer sc.lock.Unlock()
+	return sc.s3fifo.Remove(key)
+}
+
+func (sc *S3FIFOCache) DeleteExpired() {
+	sc.lock.Lock()
+	defer sc.lock.Unlock()
+	sc.s3fifo.DeleteExpired()
+}
+
+func (sc *S3FIFOCache) Len() int {
+	sc.lock.RLock()
+	defer sc.lock.RUnlock()
+	return sc.s3fifo.Len()
+}
+
+func (sc *S3FIFOCache) Clear() {
+	sc.lock.Lock()
+	defer sc.lock.Unlock()
+	sc.s3fifo.Clear()
+}
+
+const (
+	s3fifoMaxFreq = 3 // 2位访问频次
+)
+
+type s3fifo struct {
+	items     map[interface{}]*s3fifoEntry // 驻留元素
+	small     *ring_queue.RingQueue        // 小FIFO队列S，节点为*s3fifoEntry
+	main      *ring_queue.RingQueue        // 主FIFO队列M，节点为*s3fifoEntry
+	ghost     *ring_queue.RingQueue        // 幽灵队列G，节点为ghostKey
+	ghostKeys map[interface{}]uint64       // 幽灵队列中的key及其写入序号
+	ghostSeq  uint64                       // 幽灵队列写入序号
+	capacity  int                          // 缓存容量
+	smallCap  int                          // S容量
+	mainCap   int                          // M容量
+	smallSize int                          // S中驻留元素个数
+	mainSize  int                          // M中驻留元素个数
+	onEvict   EvictCallback                // 淘汰元素时执行的回调
+	admission AdmissionPolicy              // 准入策略
+	*expire                                // 过期属性
+}
+
+type s3fifoEntry struct {
+	entry
+	freq    uint32 // 访问频次 [0, 3]
+	inMain  bool   // 是否在M中
+	removed bool   // 已被移除，队列中的节点在出队时跳过
+}
+
+func (e *s3fifoEntry) Reset() {
+	e.entry.Reset()
+	atomic.StoreUint32(&e.freq, 0)
+	e.inMain = false
+	e.removed = false
+}
+
+type ghostKey struct {
+	key interface{}
+	seq uint64
+}
+
+func newS3FIFO(opt *Opt) (*s3fifo, error) {
+	if opt.Capacity <= 0 {
+		return nil, ErrSize
+	}
+	var smallCap = opt.Capacity / 10
+	if smallCap < 1 {
+		smallCap = 1
+	}
+	var mainCap = opt.Capacity - smallCap
+	if mainCap < 1 {
+		mainCap = 1
+	}
+	var c = &s3fifo{
+		capacity:  opt.Capacity,
+		smallCap:  smallCap,
+		mainCap:   mainCap,
+		onEvict:   opt.Callback,
+		admission: opt.Admission,
+		expire:    newExpire(opt),
+	}
+	c.reset()
+	return c, nil
+}
+
+func (c *s3fifo) reset() {
+	// 队列中可能残留已移除的节点，容量取缓存容量，写满时进行压缩
+	c.items = make(map[interface{}]*s3fifoEntry)
+	c.small = ring_queue.NewRingQueue(c.capacity)
+	c.main = ring_queue.NewRingQueue(c.capacity)
+	c.ghost = ring_queue.NewRingQueue(c.mainCap)
+	c.ghostKeys = make(map[interface{}]uint64)
+	c.smallSize, c.mainSize = 0, 0
+}
+
+// 只读查询，命中时原子增加访问频次；expired表示命中了过期元素
+func (c *s3fifo) get(key interface{}) (value interface{}, ok bool, expired bool) {
+	var et, exist = c.items[key]
+	if !exist {
+		return nil, false, false
+	}
+	if et.expiredAt(c.now()) {
+		return nil, false, true
+	}
+	c.touch(et)
+	return et.value, true, false
+}
+
+// 访问频次自增，最大为3
+func (c *s3fifo) touch(et *s3fifoEntry) {
+	for {
+		var freq = atomic.LoadUint32(&et.freq)
+		if freq >= s3fifoMaxFreq || atomic.CompareAndSwapUint32(&et.freq, freq, freq+1) {
+			return
+		}
+	}
+}
+
+// 惰性回收过期元素，获取写锁后需要重新检查
+func (c *s3fifo) removeExpired(key interface{}) {
+	if et, ok := c.items[key]; ok && et.expiredAt(c.now()) {
+		c.remove(et)
+	}
+}
+
+func (c *s3fifo) Get(key interface{}) (interface{}, bool) {
+	var value, ok, expired = c.get(key)
+	if expired {
+		c.removeExpired(key)
+	}
+	return value, ok
+}
+
+func (c *s3fifo) Put(key, value interface{}) bool {
+	return c.PutWithExpire(key, value, NoExpiration)
+}
+
+// 不存在则添加，存在则更新；return 是否淘汰元素
+func (c *s3fifo) PutWithExpire(key interface{}, value interface{}, lifeSpan time.Duration) bool {
+	if et, ok := c.items[key]; ok {
+		et.value = value
+		et.expiration = c.renewTime(et.expiration, lifeSpan)
+		c.touch(et)
+		return false
+	}
+
+	// 不满足准入策略则不写入
+	if !admit(c.admission, key) {
+		return false
+	}
+
+	var evict bool
+	for c.Len() >= c.capacity {
+		c.evict()
+		evict = true
+	}
+
+	var et = s3fifoEntryPool.Get().(*s3fifoEntry)
+	et.Reset()
+	et.key = key
+	et.value = value
+	et.expiration = c.absoluteTime(lifeSpan)
+	c.items[key] = et
+
+	// 在G中说明近期被淘汰过，直接写入M
+	if _, ok := c.ghostKeys[key]; ok {
+		delete(c.ghostKeys, key)
+		c.pushMain(et)
+		return evict
+	}
+	c.pushSmall(et)
+	return evict
+}
+
+func (c *s3fifo) pushSmall(et *s3fifoEntry) {
+	if c.small.IsFull() {
+		c.compact(c.small)
+	}
+	et.inMain = false
+	c.small.Insert(et)
+	c.smallSize++
+}
+
+func (c *s3fifo) pushMain(et *s3fifoEntry) {
+	if c.main.IsFull() {
+		c.compact(c.main)
+	}
+	et.inMain = true
+	c.main.Insert(et)
+	c.mainSize++
+}
+
+// 压缩队列，移除已被移除的节点
+func (c *s3fifo) compact(q *ring_queue.RingQueue) {
+	for n := q.Len(); n > 0; n-- {
+		var et = q.Head().(*s3fifoEntry)
+		q.LPop()
+		if et.removed {
+			s3fifoEntryPool.Put(et)
+			continue
+		}
+		q.Insert(et)
+	}
+}
+
+// 弹出队首的驻留元素，跳过已被移除的节点
+func (c *s3fifo) pop(q *ring_queue.RingQueue) *s3fifoEntry {
+	for !q.Empty() {
+		var et = q.Head().(*s3fifoEntry)
+		q.LPop()
+		if !et.removed {
+			return et
+		}
+		s3fifoEntryPool.Put(et)
+	}
+	return nil
+}
+
+func (c *s3fifo) evict() {
+	if c.smallSize >= c.smallCap || c.mainSize == 0 {
+		c.evictSmall()
+		return
+	}
+	c.evictMain()
+}
+
+// 淘汰S中的一个元素：频次大于1的元素移入M，否则淘汰并记录到G
+func (c *s3fifo) evictSmall() {
+	var now = c.now()
+	for c.smallSize > 0 {
+		var et = c.pop(c.small)
+		c.smallSize--
+		if atomic.LoadUint32(&et.freq) > 1 && !et.expiredAt(now) {
+			c.pushMain(et)
+			if c.mainSize > c.mainCap {
+				c.evictMain()
+				return
+			}
+			continue
+		}
+		if !et.expiredAt(now) {
+			c.pushGhost(et.key)
+		}
+		c.drop(et)
+		return
+	}
+}
+
+// 淘汰M中的一个元素：频次大于0的元素频次减1后重新插入队尾，否则淘汰
+func (c *s3fifo) evictMain() {
+	var now = c.now()
+	for c.mainSize > 0 {
+		var et = c.pop(c.main)
+		c.mainSize--
+		if freq := atomic.LoadUint32(&et.freq); freq > 0 && !et.expiredAt(now) {
+			atomic.StoreUint32(&et.freq, freq-1)
+			c.pushMain(et)
+			continue
+		}
+		c.drop(et)
+		return
+	}
+}
+
+// 记录被淘汰的key，G写满时遗忘最早的key
+func (c *s3fifo) pushGhost(key interface{}) {
+	if c.ghost.IsFull() {
+		var oldest = c.ghost.Head().(ghostKey)
+		c.ghost.LPop()
+		if seq, ok := c.ghostKeys[oldest.key]; ok && seq == oldest.seq {
+			delete(c.ghostKeys, oldest.key)
+		}
+	}
+	c.ghostSeq++
+	c.ghostKeys[key] = c.ghostSeq
+	c.ghost.Insert(ghostKey{key: key, seq: c.ghostSeq})
+}
+
+// 淘汰已出队的元素
+func (c *s3fifo) drop(et *s3fifoEntry) {
+	delete(c.items, et.key)
+	c.callEvict(et)
+	s3fifoEntryPool.Put(et)
+}
+
+func (c *s3fifo) callEvict(et *s3fifoEntry) {
+	if c.onEvict != nil {
+		// entry 会被放回对象池复用，回调只能捕获其副本
+		var key, value = et.key, et.value
+		_ = c.goroutinePool.Submit(func() {
+			c.onEvict(key, value)
+		})
+	}
+}
+
+// 移除仍在队列中的元素，队列节点在出队时回收
+func (c *s3fifo) remove(et *s3fifoEntry) {
+	if et.inMain {
+		c.mainSize--
+	} else {
+		c.smallSize--
+	}
+	et.removed = true
+	delete(c.items, et.key)
+	c.callEvict(et)
+}
+
+func (c *s3fifo) Remove(key interface{}) bool {
+	var et, ok = c.items[key]
+	if !ok {
+		return false
+	}
+	var expired = et.expiredAt(c.now())
+	c.remove(et)
+	return !expired
+}
+
+func (c *s3fifo) DeleteExpired() {
+	var now = c.now() // 减少系统调用
+	for _, et := range c.items {
+		if et.expiredAt(now) {
+			c.remove(et)
+		}
+	}
+}
+
+func (c *s3fifo) Len() int {
+	return c.smallSize + c.mainSize
+}
+
+func (c *s3fifo) Clear() {
+	for k, et := range c.items {
+		if c.onEvict != nil {
+			c.onEvict(k, et.value)
+		}
+	}
+	c.reset()
+}
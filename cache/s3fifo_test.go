@@ -0,0 +1,53 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/1005281342/basic_component/cache"
+)
+
+// 小队列容量为1，主队列容量为9
+func TestS3FIFO_Queues(t *testing.T) {
+	var c, err = cache.NewCache(cache.S3FIFO, &cache.Opt{Capacity: 10, Interval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ic = c.(cache.InspectCache)
+	var resident = func(key int) bool {
+		var _, _, ok = ic.Peek(key)
+		return ok
+	}
+	for i := 0; i < 10; i++ {
+		c.Put(i, i)
+	}
+
+	// 0 在小队列中被再次访问，淘汰时晋升到主队列；1 只访问过一次，从小队列淘汰并记录到幽灵队列
+	c.Get(0)
+	c.Get(0)
+	c.Put(10, 10)
+	if !resident(0) {
+		t.Fatal("re-accessed key 0 was evicted, want promoted to main")
+	}
+	if resident(1) {
+		t.Fatal("one-hit key 1 is resident, want evicted from small")
+	}
+
+	// 幽灵队列命中，1 直接写入主队列
+	c.Put(1, 1)
+	// 小队列按FIFO顺序淘汰只访问过一次的元素，主队列中的0与1保留
+	for i := 20; i < 29; i++ {
+		c.Put(i, i)
+	}
+	for i := 2; i <= 10; i++ {
+		if resident(i) {
+			t.Fatalf("one-hit key %d is resident, want evicted from small", i)
+		}
+	}
+	if !resident(0) || !resident(1) {
+		t.Fatalf("main keys resident = %v, %v, want true, true", resident(0), resident(1))
+	}
+	if resident(20) || !resident(21) {
+		t.Fatalf("small queue order broken: 20 resident %v, 21 resident %v", resident(20), resident(21))
+	}
+}
@@ -1,204 +1,301 @@
 package cache
 
 import (
-	"runtime"
 	"sync"
 	"time"
 )
 
+/*
+SimpleCache：
+1. 按key的哈希值分片，每个分片持有独立的读写锁，写操作只锁定所在分片；
+2. 读操作只持有分片的读锁，滑动过期的元素需要延长过期时间时才获取写锁；
+3. 读到过期元素时直接返回不存在，过期元素统一由看门狗调用 DeleteExpired 回收；
+4. 过期但未被回收的元素也会统计在 Len 内。
+*/
 type SimpleCache struct {
 	*simple
-	lock sync.RWMutex
+	atomicOp
 }
 
+const (
+	// 默认分片数
+	DefaultSimpleShards = 32
+)
+
 func NewSimpleCache(opt *Opt) *SimpleCache {
-	return &SimpleCache{simple: newSimple(opt)}
+	var sc = &SimpleCache{simple: newSimple(opt)}
+	sc.atomicOp = atomicOp{lockerOf: sc.lockerOf, op: simpleOp{sc.simple}}
+	startWatchdog(sc.expire, sc)
+	return sc
 }
 
 func (sc *SimpleCache) Put(key interface{}, value interface{}) bool {
-	sc.lock.Lock()
-	defer sc.lock.Unlock()
-	return sc.simple.Put(key, value)
+	return sc.PutWithExpire(key, value, NoExpiration)
 }
 
 func (sc *SimpleCache) PutWithExpire(key interface{}, value interface{}, lifeSpan time.Duration) bool {
-	sc.lock.Lock()
-	defer sc.lock.Unlock()
-	return sc.simple.PutWithExpire(key, value, lifeSpan)
+	return sc.PutWithExpireOpt(key, value, lifeSpan, sc.expireOpt)
+}
+
+func (sc *SimpleCache) PutWithExpireOpt(key interface{}, value interface{}, lifeSpan time.Duration, o ExpireOpt) bool {
+	var sh = sc.shardOf(key)
+	sh.lock.Lock()
+	defer sh.lock.Unlock()
+	return sc.put(sh, key, value, lifeSpan, o)
 }
 
 func (sc *SimpleCache) Get(key interface{}) (interface{}, bool) {
-	sc.lock.Lock()
-	defer sc.lock.Unlock()
-	return sc.simple.Get(key)
+	var sh = sc.shardOf(key)
+	sh.lock.RLock()
+	var it, ok = sh.items[key]
+	if !ok {
+		sh.lock.RUnlock()
+		return nil, false
+	}
+	// 永不过期的元素无需读取时钟
+	if it.expiration == 0 {
+		var value = it.value
+		sh.lock.RUnlock()
+		return value, true
+	}
+	var now = sc.now()
+	// 过期元素由看门狗回收
+	if it.expiredAt(now) {
+		sh.lock.RUnlock()
+		return nil, false
+	}
+	var value, idle = it.value, it.idle
+	sh.lock.RUnlock()
+
+	// 滑动过期的元素获取写锁延长过期时间，期间可能已被更新或移除
+	if idle > 0 {
+		sh.lock.Lock()
+		if cur, exist := sh.items[key]; exist && cur == it && !it.expiredAt(now) {
+			it.touch(now)
+		}
+		sh.lock.Unlock()
+	}
+	return value, true
 }
 
 func (sc *SimpleCache) Remove(key interface{}) bool {
-	sc.lock.Lock()
-	defer sc.lock.Unlock()
-	return sc.simple.Remove(key)
+	var sh = sc.shardOf(key)
+	sh.lock.Lock()
+	defer sh.lock.Unlock()
+	return sc.remove(sh, key)
 }
 
 func (sc *SimpleCache) Len() int {
-	sc.lock.RLock()
-	defer sc.lock.RUnlock()
-	return sc.simple.Len()
+	var n int
+	for _, sh := range sc.shards {
+		sh.lock.RLock()
+		n += len(sh.items)
+		sh.lock.RUnlock()
+	}
+	return n
 }
 
 func (sc *SimpleCache) Clear() {
-	sc.lock.RLock()
-	defer sc.lock.RUnlock()
-	sc.simple.Clear()
+	for _, sh := range sc.shards {
+		sh.lock.Lock()
+		for k, it := range sh.items {
+			sc.evict(sh, k, it)
+		}
+		sh.lock.Unlock()
+	}
+}
+
+// 回收过期的元素，逐个分片加锁，避免长时间阻塞全部读写
+func (sc *SimpleCache) DeleteExpired() {
+	var now = sc.now() // 减少系统调用
+	for _, sh := range sc.shards {
+		sh.lock.Lock()
+		for k, it := range sh.items {
+			if it.expiredAt(now) {
+				sc.evict(sh, k, it)
+			}
+		}
+		sh.lock.Unlock()
+	}
+}
+
+// 原子操作锁定key所在分片
+func (sc *SimpleCache) lockerOf(key interface{}) sync.Locker {
+	return &sc.shardOf(key).lock
 }
 
 type simple struct {
-	size    int
-	items   map[interface{}]*item
-	onEvict EvictCallback // 淘汰元素时执行的回调
-	*expire               // 过期属性
+	shards    []*simpleShard  // 分片，个数为2的幂
+	mask      uint64          // 分片掩码
+	onEvict   EvictCallback   // 淘汰元素时执行的回调
+	admission AdmissionPolicy // 准入策略
+	*expire                   // 过期属性
+}
+
+type simpleShard struct {
+	lock  sync.RWMutex
+	items map[interface{}]*item
 }
 
 func newSimple(opt *Opt) *simple {
+	var n = 1
+	for n < opt.SimpleShards {
+		n <<= 1
+	}
+	if opt.SimpleShards <= 0 {
+		n = DefaultSimpleShards
+	}
 	var s = &simple{
-		items:   make(map[interface{}]*item),
-		onEvict: opt.Callback,
-		expire:  newExpire(opt),
+		shards:    make([]*simpleShard, n),
+		mask:      uint64(n - 1),
+		onEvict:   opt.Callback,
+		admission: opt.Admission,
+		expire:    newExpire(opt),
 	}
-	if s.expire.interval > 0 {
-		go s.expire.run(s)
-		runtime.SetFinalizer(s.expire, stopWatchdog)
+	for i := range s.shards {
+		s.shards[i] = &simpleShard{items: make(map[interface{}]*item)}
 	}
 	return s
 }
 
-// Put 添加元素到缓存中，若存在则更新元素值
-func (s *simple) Put(key interface{}, value interface{}) bool {
-	return s.PutWithExpire(key, value, NoExpiration)
+func (s *simple) shardOf(key interface{}) *simpleShard {
+	return s.shards[shardHash(key)&s.mask]
 }
-func (s *simple) PutWithExpire(k interface{}, v interface{}, lifeSpan time.Duration) bool {
-	var (
-		it *item
-		ok bool
-	)
 
-	// 过期阈值校验
-	if lifeSpan == DefaultExpirationThreshold {
-		lifeSpan = s.defaultExpiration
+// 分片哈希，常见类型的key不分配内存
+func shardHash(key interface{}) uint64 {
+	switch k := key.(type) {
+	case string:
+		// 内联FNV-1a
+		var h uint64 = 14695981039346656037
+		for i := 0; i < len(k); i++ {
+			h ^= uint64(k[i])
+			h *= 1099511628211
+		}
+		return h
+	case int:
+		return mix64(uint64(k))
+	case int64:
+		return mix64(uint64(k))
+	case int32:
+		return mix64(uint64(k))
+	case uint64:
+		return mix64(k)
+	case uint32:
+		return mix64(uint64(k))
+	case uint:
+		return mix64(uint64(k))
+	default:
+		var h, _ = hashKey(key)
+		return h
 	}
+}
 
+// splitmix64 混淆，使连续整数均匀分布到各分片
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// 写入分片，调用方持有分片写锁；return 是否新增元素
+func (s *simple) put(sh *simpleShard, k interface{}, v interface{}, lifeSpan time.Duration, o ExpireOpt) bool {
 	// 存在于缓存中
-	if it, ok = s.items[k]; ok {
-		var add = it.Expired()
+	if it, ok := sh.items[k]; ok {
+		var add = it.expiredAt(s.now())
 		it.value = v
-		it.expiration = s.absoluteTime(lifeSpan)
+		s.setExpire(it, lifeSpan, o)
 		return add
 	}
 
+	// 不满足准入策略则不写入
+	if !admit(s.admission, k) {
+		return false
+	}
+
 	// 不存在，新增
-	it = itemPool.Get().(*item)
+	var it = itemPool.Get().(*item)
 	// 清空
 	it.Reset()
 	// 赋值
 	it.value = v
-	it.expiration = s.absoluteTime(lifeSpan)
-	s.items[k] = it
-	s.size++
+	s.setExpire(it, lifeSpan, o)
+	sh.items[k] = it
 	return true
 }
 
-// 从缓存中获取元素，若存在则bool == True
-func (s *simple) Get(key interface{}) (interface{}, bool) {
-
-	var (
-		it *item
-		ok bool
-	)
-
-	// 先查询是否存在
-	if it, ok = s.items[key]; !ok {
-		return nil, false
-	}
-
-	// 判断是否过期
-	// 过期则触发惰性回收
-	if it.Expired() {
-		// 惰性回收
-		s.remove(key, it)
-		return nil, false
-	}
-
-	// 返回值
-	return it.value, true
-}
-
-// 从缓存中移除对象，若存在则返回True
-func (s *simple) Remove(key interface{}) bool {
-
-	var (
-		it      *item
-		ok      bool
-		expired bool
-	)
-
-	// 先查询是否存在
-	if it, ok = s.items[key]; !ok {
+// 从分片中移除对象，调用方持有分片写锁；若存在且未过期则返回True
+func (s *simple) remove(sh *simpleShard, key interface{}) bool {
+	var it, ok = sh.items[key]
+	if !ok {
 		return false
 	}
-	expired = it.Expired()
-	if !expired {
-		s.size--
-	}
-	s.remove(key, it)
+	var expired = it.expiredAt(s.now())
+	s.evict(sh, key, it)
 	return !expired
 }
 
-func (s *simple) remove(key interface{}, it *item) {
-	// 惰性回收
+func (s *simple) evict(sh *simpleShard, key interface{}, it *item) {
 	var val = it.value
-	delete(s.items, key)
+	delete(sh.items, key)
 	_ = s.goroutinePool.Submit(func() {
 		callEvict(s.onEvict, key, val)
 	})
 	itemPool.Put(it)
 }
 
-func (s *simple) Len() int {
-	return s.size
+// 未加锁的基本操作，由原子操作持有分片锁后调用
+type simpleOp struct {
+	s *simple
 }
 
-func (s *simple) Clear() {
-	for k, v := range s.items {
-		s.remove(k, v)
-	}
-	s.size = 0
+func (op simpleOp) PutWithExpire(key interface{}, value interface{}, lifeSpan time.Duration) bool {
+	return op.s.put(op.s.shardOf(key), key, value, lifeSpan, op.s.expireOpt)
 }
 
-// 回收过期的元素
-func (s *simple) DeleteExpired() {
-	var now = time.Now().UnixNano() // 减少系统调用
-	for k, v := range s.items {
-		if v.expiration > 0 && now > v.expiration {
-			s.remove(k, v)
-		}
-	}
+func (op simpleOp) Remove(key interface{}) bool {
+	return op.s.remove(op.s.shardOf(key), key)
+}
+
+func (op simpleOp) peek(key interface{}) (*item, bool) {
+	var it, ok = op.s.shardOf(key).items[key]
+	return it, ok
+}
+
+func (op simpleOp) now() int64 {
+	return op.s.now()
 }
 
 type item struct {
 	value      interface{} // 元素值
 	expiration int64       // 绝对过期时间
+	idle       int64       // 滑动过期的存活时长，访问后重新计算过期时间，0表示不滑动
 }
 
 func (i *item) Reset() {
 	i.value = nil
 	i.expiration = 0
+	i.idle = 0
+}
+
+// 访问元素，滑动过期的元素延长过期时间
+func (i *item) touch(now int64) {
+	if i.idle > 0 {
+		i.expiration = now + i.idle
+	}
 }
 
 // Expired 是否过期
 func (i item) Expired() bool {
-	if i.expiration == 0 {
-		return false
-	}
-	return time.Now().UnixNano() > i.expiration
+	return i.expiredAt(time.Now().UnixNano())
+}
+
+// 相对于给定时间now是否过期
+func (i item) expiredAt(now int64) bool {
+	return i.expiration > 0 && now > i.expiration
 }
 
 // Value 查询元素值，若不存在返回 nil, false
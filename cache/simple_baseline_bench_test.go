@@ -0,0 +1,23 @@
+//go:build simplebaseline
+// +build simplebaseline
+
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/1005281342/basic_component/cache"
+)
+
+func newBenchBaselineSimple() benchCache {
+	return cache.NewBaselineSimpleCache(&cache.Opt{Interval: time.Hour})
+}
+
+func BenchmarkBaselineSimple_GetParallel(b *testing.B) {
+	benchmarkSimpleGet(b, newBenchBaselineSimple())
+}
+
+func BenchmarkBaselineSimple_MixedParallel(b *testing.B) {
+	benchmarkSimpleMixed(b, newBenchBaselineSimple())
+}
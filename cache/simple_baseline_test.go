@@ -0,0 +1,120 @@
+//go:build simplebaseline
+// +build simplebaseline
+
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+分片前的 SimpleCache，仅用于基准对照：
+1. 单个读写锁，Get 持有写锁并惰性回收过期元素；
+2. 通过 go test -tags simplebaseline -bench Simple_ 与分片后的实现对比。
+*/
+type BaselineSimpleCache struct {
+	*baselineSimple
+	lock sync.RWMutex
+}
+
+func NewBaselineSimpleCache(opt *Opt) *BaselineSimpleCache {
+	var sc = &BaselineSimpleCache{baselineSimple: &baselineSimple{
+		items:     make(map[interface{}]*item),
+		onEvict:   opt.Callback,
+		admission: opt.Admission,
+		expire:    newExpire(opt),
+	}}
+	startWatchdog(sc.expire, sc)
+	return sc
+}
+
+func (sc *BaselineSimpleCache) Put(key interface{}, value interface{}) bool {
+	sc.lock.Lock()
+	defer sc.lock.Unlock()
+	return sc.baselineSimple.PutWithExpire(key, value, NoExpiration)
+}
+
+func (sc *BaselineSimpleCache) Get(key interface{}) (interface{}, bool) {
+	sc.lock.Lock()
+	defer sc.lock.Unlock()
+	return sc.baselineSimple.Get(key)
+}
+
+func (sc *BaselineSimpleCache) DeleteExpired() {
+	sc.lock.Lock()
+	defer sc.lock.Unlock()
+	sc.baselineSimple.DeleteExpired()
+}
+
+type baselineSimple struct {
+	size      int
+	items     map[interface{}]*item
+	onEvict   EvictCallback   // 淘汰元素时执行的回调
+	admission AdmissionPolicy // 准入策略
+	*expire                   // 过期属性
+}
+
+func (s *baselineSimple) PutWithExpire(k interface{}, v interface{}, lifeSpan time.Duration) bool {
+	var (
+		it *item
+		ok bool
+	)
+
+	// 存在于缓存中
+	if it, ok = s.items[k]; ok {
+		var add = it.expiredAt(s.now())
+		it.value = v
+		s.setExpire(it, lifeSpan, s.expireOpt)
+		return add
+	}
+
+	// 不满足准入策略则不写入
+	if !admit(s.admission, k) {
+		return false
+	}
+
+	// 不存在，新增
+	it = itemPool.Get().(*item)
+	it.Reset()
+	it.value = v
+	s.setExpire(it, lifeSpan, s.expireOpt)
+	s.items[k] = it
+	s.size++
+	return true
+}
+
+// 从缓存中获取元素，过期则触发惰性回收
+func (s *baselineSimple) Get(key interface{}) (interface{}, bool) {
+	var it, ok = s.items[key]
+	if !ok {
+		return nil, false
+	}
+	var now = s.now()
+	if it.expiredAt(now) {
+		s.remove(key, it)
+		return nil, false
+	}
+	it.touch(now)
+	return it.value, true
+}
+
+func (s *baselineSimple) remove(key interface{}, it *item) {
+	var val = it.value
+	delete(s.items, key)
+	s.size--
+	_ = s.goroutinePool.Submit(func() {
+		callEvict(s.onEvict, key, val)
+	})
+	itemPool.Put(it)
+}
+
+// 回收过期的元素
+func (s *baselineSimple) DeleteExpired() {
+	var now = s.now() // 减少系统调用
+	for k, v := range s.items {
+		if v.expiredAt(now) {
+			s.remove(k, v)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/1005281342/basic_component/cache"
+	"github.com/1005281342/basic_component/cache/cachetest"
+)
+
+type benchCache interface {
+	Put(key, value interface{}) bool
+	Get(key interface{}) (interface{}, bool)
+}
+
+const benchKeys = 1 << 16
+
+func newBenchSimple(b *testing.B) benchCache {
+	var c, err = cache.NewCache(cache.Simple, &cache.Opt{Interval: time.Hour})
+	if err != nil {
+		b.Fatal(err)
+	}
+	return c
+}
+
+func benchmarkSimpleGet(b *testing.B, c benchCache) {
+	for i := 0; i < benchKeys; i++ {
+		c.Put(i, i)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var i int
+		for pb.Next() {
+			c.Get(i & (benchKeys - 1))
+			i++
+		}
+	})
+}
+
+// 读写比 9:1
+func benchmarkSimpleMixed(b *testing.B, c benchCache) {
+	for i := 0; i < benchKeys; i++ {
+		c.Put(i, i)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var i int
+		for pb.Next() {
+			var k = (i * 7919) & (benchKeys - 1)
+			if i%10 == 0 {
+				c.Put(k, i)
+			} else {
+				c.Get(k)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkSimple_GetParallel(b *testing.B)   { benchmarkSimpleGet(b, newBenchSimple(b)) }
+func BenchmarkSimple_MixedParallel(b *testing.B) { benchmarkSimpleMixed(b, newBenchSimple(b)) }
+
+// 查询不回收过期元素，滑动过期的元素仍会延长过期时间
+func TestSimpleDeferredExpire(t *testing.T) {
+	var clock = cachetest.NewFakeClock()
+	var c = cache.NewSimpleCache(&cache.Opt{Clock: clock, Interval: time.Hour})
+	c.PutWithExpire("a", 1, time.Second)
+	c.PutWithExpireOpt("b", 2, time.Second, cache.ExpireOpt{AfterAccess: true})
+
+	clock.Advance(600 * time.Millisecond)
+	if _, ok := c.Get("b"); !ok {
+		t.Fatalf("Get(b) miss")
+	}
+	clock.Advance(600 * time.Millisecond)
+	if _, ok := c.Get("a"); ok {
+		t.Fatalf("Get(a) hit after expiration")
+	}
+	if _, ok := c.Get("b"); !ok {
+		t.Fatalf("Get(b) miss after touch")
+	}
+	if n := c.Len(); n != 2 {
+		t.Fatalf("Len() = %d, want 2", n)
+	}
+	c.DeleteExpired()
+	if n := c.Len(); n != 1 {
+		t.Fatalf("Len() = %d, want 1", n)
+	}
+}
@@ -0,0 +1,463 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+带后端存储的缓存：
+1. 查询未命中时从 Store 加载并写入缓存（读穿透），同一key的加载与写入互斥，避免旧值覆盖新值；
+2. 写穿透（WriteThrough）：Put/Remove 先同步写入 Store，成功后再更新缓存；
+3. 写回（WriteBehind）：Put/Remove 只更新缓存并记录脏key，同一key的多次写入合并为最后一次；
+   脏key按间隔或数量阈值批量刷新到 Store，失败按次数重试，仍失败则放回待刷新队列；
+4. 脏key在刷新完成前被淘汰或过期时立即触发刷新，刷新完成前查询仍能读到未刷新的值；
+5. Close 停止后台刷新并刷新全部脏key；
+6. 设置 Negative 后 Load 返回ErrNotFound的key记录为否定元素，存活期间不再访问 Store，写入时删除。
+*/
+
+// Store 缓存的后端存储，需要并发安全
+type Store interface {
+	// 加载元素，不存在返回ErrNotFound
+	Load(key interface{}) (interface{}, error)
+	// 批量加载，返回值不包含不存在的key
+	LoadBatch(keys []interface{}) (map[interface{}]interface{}, error)
+	Store(key interface{}, value interface{}) error
+	StoreBatch(entries map[interface{}]interface{}) error
+	Delete(key interface{}) error
+	DeleteBatch(keys []interface{}) error
+}
+
+// WriteMode 写入后端存储的方式
+type WriteMode int
+
+const (
+	WriteThrough WriteMode = iota // 同步写入
+	WriteBehind                   // 异步批量写入
+)
+
+const (
+	// 默认刷新间隔
+	DefaultFlushInterval = time.Second
+	// 默认每批写入的key数，待刷新的key达到该数量时立即刷新
+	DefaultFlushSize = 100
+	// 默认重试次数
+	DefaultFlushRetries = 3
+	// 默认重试间隔
+	DefaultFlushBackoff = 100 * time.Millisecond
+
+	storeKeyLocks = 64
+)
+
+// StoreOpt 后端存储配置
+type StoreOpt struct {
+	Mode          WriteMode
+	FlushInterval time.Duration // 写回刷新间隔
+	FlushSize     int           // 写回每批key数与立即刷新阈值
+	FlushRetries  int           // 写回失败重试次数，默认3，<0表示不重试
+	FlushBackoff  time.Duration // 写回重试间隔，每次重试翻倍
+	OnError       func(error)   // 写回最终失败或读穿透加载失败时回调
+	Negative      *NegativeOpt  // 否定缓存配置，为nil时不缓存不存在的key
+}
+
+type StoreCache struct {
+	cache    ExpireCache
+	neg      *negatives // 不存在的key，未开启否定缓存时为nil
+	store    Store
+	opt      StoreOpt
+	keyLocks [storeKeyLocks]sync.Mutex // 按key分段的锁，串行化同一key的读穿透与写入
+
+	lock     sync.Mutex
+	pending  map[interface{}]storeOp // 待刷新的脏key
+	inflight map[interface{}]storeOp // 正在刷新的脏key
+	flushMu  sync.Mutex              // 串行化刷新
+
+	signal chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+	closed bool
+}
+
+// 脏key的最后一次写入
+type storeOp struct {
+	value   interface{}
+	deleted bool
+}
+
+func NewStoreCache(ct CacheType, opt *Opt, store Store, so StoreOpt) (*StoreCache, error) {
+	if so.FlushInterval <= 0 {
+		so.FlushInterval = DefaultFlushInterval
+	}
+	if so.FlushSize <= 0 {
+		so.FlushSize = DefaultFlushSize
+	}
+	if so.FlushRetries < 0 {
+		so.FlushRetries = 0
+	} else if so.FlushRetries == 0 {
+		so.FlushRetries = DefaultFlushRetries
+	}
+	if so.FlushBackoff <= 0 {
+		so.FlushBackoff = DefaultFlushBackoff
+	}
+	var sc = &StoreCache{
+		store:   store,
+		opt:     so,
+		pending: make(map[interface{}]storeOp),
+		signal:  make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	var onEvict = opt.Callback
+	var cacheOpt = *opt
+	cacheOpt.Callback = func(key interface{}, value interface{}) {
+		sc.evicted(key)
+		callEvict(onEvict, key, value)
+	}
+	var c, err = NewCache(ct, &cacheOpt)
+	if err != nil {
+		return nil, err
+	}
+	sc.cache = c
+	if so.Negative != nil {
+		if sc.neg, err = newNegatives(opt, *so.Negative); err != nil {
+			return nil, err
+		}
+	}
+	if so.Mode == WriteBehind {
+		go sc.run()
+	} else {
+		close(sc.done)
+	}
+	return sc, nil
+}
+
+func (sc *StoreCache) keyLock(key interface{}) *sync.Mutex {
+	return &sc.keyLocks[shardHash(key)%storeKeyLocks]
+}
+
+// Get 未命中时依次查找未刷新的脏key与后端存储
+func (sc *StoreCache) Get(key interface{}) (interface{}, bool) {
+	if value, ok := sc.cache.Get(key); ok {
+		return value, true
+	}
+	var l = sc.keyLock(key)
+	l.Lock()
+	defer l.Unlock()
+	if value, ok := sc.cache.Get(key); ok {
+		return value, true
+	}
+	if op, ok := sc.dirty(key); ok {
+		if op.deleted {
+			return nil, false
+		}
+		sc.cache.Put(key, op.value)
+		return op.value, true
+	}
+	if sc.isNegative(key) {
+		return nil, false
+	}
+	var value, err = sc.store.Load(key)
+	if err != nil {
+		if err != ErrNotFound {
+			sc.onError(err)
+		} else if sc.neg != nil {
+			sc.neg.put(key, 0)
+		}
+		return nil, false
+	}
+	sc.cache.Put(key, value)
+	return value, true
+}
+
+// GetBatch 批量查询，未命中的key通过一次LoadBatch加载
+func (sc *StoreCache) GetBatch(keys []interface{}) map[interface{}]interface{} {
+	var result = make(map[interface{}]interface{}, len(keys))
+	var misses []interface{}
+	for _, key := range keys {
+		if value, ok := sc.cache.Get(key); ok {
+			result[key] = value
+		} else if op, ok := sc.dirty(key); ok {
+			if !op.deleted {
+				result[key] = op.value
+			}
+		} else if !sc.isNegative(key) {
+			misses = append(misses, key)
+		}
+	}
+	if len(misses) == 0 {
+		return result
+	}
+	var loaded, err = sc.store.LoadBatch(misses)
+	if err != nil {
+		sc.onError(err)
+		return result
+	}
+	if sc.neg != nil {
+		for _, key := range misses {
+			if _, ok := loaded[key]; !ok {
+				sc.putNegative(key)
+			}
+		}
+	}
+	for key, value := range loaded {
+		var l = sc.keyLock(key)
+		l.Lock()
+		// 加载期间可能已被写入
+		if v, ok := sc.cache.Get(key); ok {
+			value = v
+		} else if _, ok = sc.dirty(key); !ok {
+			sc.cache.Put(key, value)
+		}
+		l.Unlock()
+		result[key] = value
+	}
+	return result
+}
+
+func (sc *StoreCache) Put(key, value interface{}) bool {
+	return sc.PutWithExpire(key, value, NoExpiration)
+}
+
+// PutWithExpire 写穿透模式下写入后端存储失败时不更新缓存，返回false
+func (sc *StoreCache) PutWithExpire(key interface{}, value interface{}, lifeSpan time.Duration) bool {
+	var l = sc.keyLock(key)
+	l.Lock()
+	defer l.Unlock()
+	if sc.neg != nil {
+		sc.neg.remove(key)
+	}
+	if sc.opt.Mode == WriteThrough {
+		if err := sc.store.Store(key, value); err != nil {
+			sc.onError(err)
+			return false
+		}
+		return sc.cache.PutWithExpire(key, value, lifeSpan)
+	}
+	sc.markDirty(key, storeOp{value: value})
+	return sc.cache.PutWithExpire(key, value, lifeSpan)
+}
+
+// Remove 同时从后端存储删除，return 缓存中是否存在
+func (sc *StoreCache) Remove(key interface{}) bool {
+	var l = sc.keyLock(key)
+	l.Lock()
+	defer l.Unlock()
+	if sc.opt.Mode == WriteThrough {
+		if err := sc.store.Delete(key); err != nil {
+			sc.onError(err)
+			return false
+		}
+		return sc.cache.Remove(key)
+	}
+	sc.markDirty(key, storeOp{deleted: true})
+	return sc.cache.Remove(key)
+}
+
+func (sc *StoreCache) Len() int {
+	return sc.cache.Len()
+}
+
+// Clear 只清空缓存与否定元素，不影响后端存储与待刷新的脏key
+func (sc *StoreCache) Clear() {
+	sc.cache.Clear()
+	if sc.neg != nil {
+		sc.neg.cache.Clear()
+	}
+}
+
+func (sc *StoreCache) DeleteExpired() {
+	sc.cache.DeleteExpired()
+	if sc.neg != nil {
+		sc.neg.cache.DeleteExpired()
+	}
+}
+
+// Flush 立即刷新全部脏key，return 最终失败的错误
+func (sc *StoreCache) Flush() error {
+	return sc.flush()
+}
+
+// Close 停止后台刷新并刷新全部脏key，之后写入的脏key需要调用 Flush 刷新
+func (sc *StoreCache) Close() error {
+	sc.lock.Lock()
+	if sc.closed {
+		sc.lock.Unlock()
+		return nil
+	}
+	sc.closed = true
+	sc.lock.Unlock()
+	if sc.opt.Mode == WriteBehind {
+		close(sc.stop)
+		<-sc.done
+	}
+	return sc.flush()
+}
+
+// Dirty 待刷新的脏key个数
+func (sc *StoreCache) Dirty() int {
+	sc.lock.Lock()
+	defer sc.lock.Unlock()
+	return len(sc.pending) + len(sc.inflight)
+}
+
+func (sc *StoreCache) onError(err error) {
+	if sc.opt.OnError != nil {
+		sc.opt.OnError(err)
+	}
+}
+
+func (sc *StoreCache) isNegative(key interface{}) bool {
+	return sc.neg != nil && sc.neg.has(key)
+}
+
+// 批量加载期间可能已被写入，持有key锁后再次确认
+func (sc *StoreCache) putNegative(key interface{}) {
+	var l = sc.keyLock(key)
+	l.Lock()
+	defer l.Unlock()
+	if _, ok := sc.cache.Get(key); ok {
+		return
+	}
+	if _, ok := sc.dirty(key); !ok {
+		sc.neg.put(key, 0)
+	}
+}
+
+func (sc *StoreCache) dirty(key interface{}) (storeOp, bool) {
+	sc.lock.Lock()
+	defer sc.lock.Unlock()
+	if op, ok := sc.pending[key]; ok {
+		return op, true
+	}
+	var op, ok = sc.inflight[key]
+	return op, ok
+}
+
+func (sc *StoreCache) markDirty(key interface{}, op storeOp) {
+	sc.lock.Lock()
+	sc.pending[key] = op
+	var full = len(sc.pending) >= sc.opt.FlushSize
+	sc.lock.Unlock()
+	if full {
+		sc.trigger()
+	}
+}
+
+// 淘汰回调，脏key被淘汰时立即刷新
+func (sc *StoreCache) evicted(key interface{}) {
+	if sc.opt.Mode != WriteBehind {
+		return
+	}
+	sc.lock.Lock()
+	var op, ok = sc.pending[key]
+	sc.lock.Unlock()
+	if ok && !op.deleted {
+		sc.trigger()
+	}
+}
+
+func (sc *StoreCache) trigger() {
+	select {
+	case sc.signal <- struct{}{}:
+	default:
+	}
+}
+
+func (sc *StoreCache) run() {
+	defer close(sc.done)
+	var ticker = time.NewTicker(sc.opt.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-sc.stop:
+			return
+		case <-ticker.C:
+		case <-sc.signal:
+		}
+		if err := sc.flush(); err != nil {
+			sc.onError(err)
+		}
+	}
+}
+
+// 刷新全部脏key，失败的key放回待刷新队列，期间被再次写入的除外
+func (sc *StoreCache) flush() error {
+	sc.flushMu.Lock()
+	defer sc.flushMu.Unlock()
+
+	sc.lock.Lock()
+	if len(sc.pending) == 0 {
+		sc.lock.Unlock()
+		return nil
+	}
+	var batch = sc.pending
+	sc.inflight = batch
+	sc.pending = make(map[interface{}]storeOp)
+	sc.lock.Unlock()
+
+	var (
+		failed  = make(map[interface{}]storeOp)
+		lastErr error
+		stores  = make(map[interface{}]interface{})
+		deletes []interface{}
+	)
+	var flushStores = func() {
+		if err := sc.retry(func() error { return sc.store.StoreBatch(stores) }); err != nil {
+			lastErr = err
+			for k, v := range stores {
+				failed[k] = storeOp{value: v}
+			}
+		}
+		stores = make(map[interface{}]interface{})
+	}
+	var flushDeletes = func() {
+		if err := sc.retry(func() error { return sc.store.DeleteBatch(deletes) }); err != nil {
+			lastErr = err
+			for _, k := range deletes {
+				failed[k] = storeOp{deleted: true}
+			}
+		}
+		deletes = nil
+	}
+	for k, op := range batch {
+		if op.deleted {
+			if deletes = append(deletes, k); len(deletes) >= sc.opt.FlushSize {
+				flushDeletes()
+			}
+		} else {
+			if stores[k] = op.value; len(stores) >= sc.opt.FlushSize {
+				flushStores()
+			}
+		}
+	}
+	if len(stores) > 0 {
+		flushStores()
+	}
+	if len(deletes) > 0 {
+		flushDeletes()
+	}
+
+	sc.lock.Lock()
+	for k, op := range failed {
+		if _, ok := sc.pending[k]; !ok {
+			sc.pending[k] = op
+		}
+	}
+	sc.inflight = nil
+	sc.lock.Unlock()
+	return lastErr
+}
+
+func (sc *StoreCache) retry(fn func() error) error {
+	var (
+		err     error
+		backoff = sc.opt.FlushBackoff
+	)
+	for i := 0; ; i++ {
+		if err = fn(); err == nil || i >= sc.opt.FlushRetries {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
@@ -0,0 +1,268 @@
+package cache_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/1005281342/basic_component/cache"
+)
+
+// 内存后端存储，failures>0时写入失败并递减
+type memStore struct {
+	lock     sync.Mutex
+	data     map[interface{}]interface{}
+	writes   int // 写入的key数
+	batches  int // 批量写入次数
+	failures int
+}
+
+func newMemStore() *memStore {
+	return &memStore{data: make(map[interface{}]interface{})}
+}
+
+var errStore = errors.New("store unavailable")
+
+func (s *memStore) fail() error {
+	if s.failures > 0 {
+		s.failures--
+		return errStore
+	}
+	return nil
+}
+
+func (s *memStore) Load(key interface{}) (interface{}, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if v, ok := s.data[key]; ok {
+		return v, nil
+	}
+	return nil, cache.ErrNotFound
+}
+
+func (s *memStore) LoadBatch(keys []interface{}) (map[interface{}]interface{}, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var result = make(map[interface{}]interface{})
+	for _, k := range keys {
+		if v, ok := s.data[k]; ok {
+			result[k] = v
+		}
+	}
+	return result, nil
+}
+
+func (s *memStore) Store(key interface{}, value interface{}) error {
+	return s.StoreBatch(map[interface{}]interface{}{key: value})
+}
+
+func (s *memStore) StoreBatch(entries map[interface{}]interface{}) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := s.fail(); err != nil {
+		return err
+	}
+	s.batches++
+	for k, v := range entries {
+		s.data[k] = v
+		s.writes++
+	}
+	return nil
+}
+
+func (s *memStore) Delete(key interface{}) error {
+	return s.DeleteBatch([]interface{}{key})
+}
+
+func (s *memStore) DeleteBatch(keys []interface{}) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := s.fail(); err != nil {
+		return err
+	}
+	for _, k := range keys {
+		delete(s.data, k)
+	}
+	return nil
+}
+
+func (s *memStore) setFailures(n int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.failures = n
+}
+
+func (s *memStore) get(key interface{}) (interface{}, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var v, ok = s.data[key]
+	return v, ok
+}
+
+func newStoreCache(t *testing.T, store cache.Store, capacity int, so cache.StoreOpt) *cache.StoreCache {
+	var c, err = cache.NewStoreCache(cache.LRU, &cache.Opt{Capacity: capacity, Interval: time.Hour}, store, so)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestStoreCacheWriteThrough(t *testing.T) {
+	var store = newMemStore()
+	store.data["loaded"] = 1
+	var errs int
+	var c = newStoreCache(t, store, 10, cache.StoreOpt{OnError: func(error) { errs++ }})
+
+	// 读穿透
+	if v, ok := c.Get("loaded"); !ok || v != 1 {
+		t.Fatalf("Get(loaded) = %v, %v, want 1", v, ok)
+	}
+	if _, ok := c.Get("missing"); ok {
+		t.Fatalf("Get(missing) hit")
+	}
+
+	c.Put("a", 1)
+	if v, ok := store.get("a"); !ok || v != 1 {
+		t.Fatalf("store[a] = %v, %v, want 1", v, ok)
+	}
+	// 写入失败时不更新缓存
+	store.setFailures(1)
+	if c.Put("a", 2) {
+		t.Fatalf("Put with failing store = true")
+	}
+	if v, _ := c.Get("a"); v != 1 || errs != 1 {
+		t.Fatalf("Get(a) = %v, errors = %d, want 1, 1", v, errs)
+	}
+	c.Remove("a")
+	if _, ok := store.get("a"); ok {
+		t.Fatalf("store[a] not deleted")
+	}
+}
+
+func TestStoreCacheWriteBehind(t *testing.T) {
+	var store = newMemStore()
+	var c = newStoreCache(t, store, 2, cache.StoreOpt{
+		Mode:          cache.WriteBehind,
+		FlushInterval: time.Hour,
+		FlushSize:     100,
+		FlushBackoff:  time.Millisecond,
+	})
+
+	// 多次写入合并
+	for i := 0; i < 10; i++ {
+		c.Put("a", i)
+	}
+	c.Put("b", 1)
+	c.Remove("b")
+	if _, ok := store.get("a"); ok {
+		t.Fatalf("store written before flush")
+	}
+	if n := c.Dirty(); n != 2 {
+		t.Fatalf("Dirty() = %d, want 2", n)
+	}
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := store.get("a"); v != 9 || store.writes != 1 {
+		t.Fatalf("store[a] = %v, writes = %d, want 9, 1", v, store.writes)
+	}
+
+	// 重试后成功
+	store.setFailures(2)
+	c.Put("c", 1)
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	// 重试耗尽则放回待刷新队列
+	store.setFailures(10)
+	c.Put("d", 1)
+	if err := c.Flush(); err == nil || c.Dirty() != 1 {
+		t.Fatalf("Flush() = %v, Dirty() = %d, want error and 1 dirty key", err, c.Dirty())
+	}
+	store.setFailures(0)
+
+	// 淘汰的脏key刷新前仍可读到，淘汰后触发刷新
+	c.Put("e", 1)
+	c.Put("f", 1)
+	c.Put("g", 1)
+	if v, ok := c.Get("d"); !ok || v != 1 {
+		t.Fatalf("Get(d) = %v, %v, want 1", v, ok)
+	}
+	var deadline = time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if _, ok := store.get("d"); ok {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, ok := store.get("d"); !ok {
+		t.Fatalf("store[d] missing after eviction")
+	}
+
+	c.Put("h", 1)
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"c", "d", "e", "f", "g", "h"} {
+		if _, ok := store.get(k); !ok {
+			t.Fatalf("store[%s] missing after Close", k)
+		}
+	}
+}
+
+func TestStoreCacheFlushSize(t *testing.T) {
+	var store = newMemStore()
+	var c = newStoreCache(t, store, 100, cache.StoreOpt{Mode: cache.WriteBehind, FlushInterval: time.Hour, FlushSize: 3})
+	defer c.Close()
+	for i := 0; i < 3; i++ {
+		c.Put(i, i)
+	}
+	var deadline = time.Now().Add(time.Second)
+	for c.Dirty() > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, ok := store.get(2); !ok {
+		t.Fatalf("not flushed on size threshold")
+	}
+	var got = c.GetBatch([]interface{}{0, 1, 2, 3})
+	if len(got) != 3 {
+		t.Fatalf("GetBatch = %v, want 3 entries", got)
+	}
+}
+
+func TestStoreCacheNegative(t *testing.T) {
+	var store = &countingStore{memStore: newMemStore()}
+	var c = newStoreCache(t, store, 10, cache.StoreOpt{Negative: &cache.NegativeOpt{TTL: time.Hour, Capacity: 10}})
+	for i := 0; i < 3; i++ {
+		if _, ok := c.Get("missing"); ok {
+			t.Fatalf("Get(missing) hit")
+		}
+		c.GetBatch([]interface{}{"batch"})
+	}
+	if store.loads != 2 {
+		t.Fatalf("loads = %d, want 2", store.loads)
+	}
+	// 写入后不再视为不存在
+	c.Put("missing", 1)
+	c.Clear()
+	if v, ok := c.Get("missing"); !ok || v != 1 {
+		t.Fatalf("Get(missing) = %v, %v, want 1", v, ok)
+	}
+}
+
+// 记录加载次数的存储
+type countingStore struct {
+	*memStore
+	loads int
+}
+
+func (s *countingStore) Load(key interface{}) (interface{}, error) {
+	s.loads++
+	return s.memStore.Load(key)
+}
+
+func (s *countingStore) LoadBatch(keys []interface{}) (map[interface{}]interface{}, error) {
+	s.loads++
+	return s.memStore.LoadBatch(keys)
+}
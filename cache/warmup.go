@@ -0,0 +1,279 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/panjf2000/ants/v2"
+)
+
+/*
+缓存预热：
+1. Warmup 依次从 KeyIterator 取出key，在大小为并发数的独立协程池中调用加载函数并写入缓存；
+   不使用缓存自身的协程池，写入时淘汰回调会提交到该协程池，共用会在持有缓存锁时互相等待；
+2. 并发数与每秒加载的key数受限，ctx取消后不再加载新的key，等待进行中的加载完成后返回；
+3. 运行期间用 HotKeyTracker（如 HotKeyCache）统计热点key，WriteAccessLog 按热度降序写出访问日志，
+   下次启动时 ReadAccessLog 按相同顺序返回key，优先加载最热的key。
+*/
+
+const (
+	// 默认预热并发数
+	DefaultWarmupConcurrency = 8
+	// 默认每加载多少个key报告一次进度
+	DefaultWarmupProgressEvery = 100
+
+	accessLogMagic = "CAL1"
+	// 访问日志中string类型key的最大长度，超过的key不写出，读取时视为日志损坏
+	accessLogMaxKeyLen = 1 << 20
+)
+
+// KeyIterator 依次返回key，没有更多key时ok为false，只会在一个协程中调用
+type KeyIterator func() (key interface{}, ok bool)
+
+// SliceKeys 按顺序返回切片中的key
+func SliceKeys(keys []interface{}) KeyIterator {
+	var i int
+	return func() (interface{}, bool) {
+		if i >= len(keys) {
+			return nil, false
+		}
+		i++
+		return keys[i-1], true
+	}
+}
+
+// WarmupOpt 预热配置
+type WarmupOpt struct {
+	Concurrency   int                              // 并发数，默认8
+	Rate          float64                          // 每秒最多加载的key数，0表示不限制
+	LifeSpan      time.Duration                    // 写入元素的存活时长，0表示使用缓存的默认过期时间
+	ProgressEvery int                              // 每完成多少个key调用一次 Progress，默认100，结束时总会调用
+	Progress      func(WarmupStats)                // 进度回调，串行调用
+	OnError       func(key interface{}, err error) // 加载失败回调，ErrNotFound不视为失败
+}
+
+// WarmupStats 预热进度
+type WarmupStats struct {
+	Loaded  int // 已写入缓存
+	Missing int // 加载函数返回ErrNotFound
+	Failed  int // 加载函数返回其他错误
+}
+
+// Done 已完成的key数
+func (s WarmupStats) Done() int {
+	return s.Loaded + s.Missing + s.Failed
+}
+
+// Warmup 预热缓存，return 最终进度，ctx取消时返回ctx.Err()
+// c 为 *NegativeCache 时不存在的key写入否定元素
+func Warmup(ctx context.Context, c ExpireCache, keys KeyIterator, loader LoadFunc, opt WarmupOpt) (WarmupStats, error) {
+	if opt.Concurrency <= 0 {
+		opt.Concurrency = DefaultWarmupConcurrency
+	}
+	if opt.ProgressEvery <= 0 {
+		opt.ProgressEvery = DefaultWarmupProgressEvery
+	}
+	var pool, err = ants.NewPool(opt.Concurrency)
+	if err != nil {
+		return WarmupStats{}, err
+	}
+	defer pool.Release()
+
+	var (
+		lock  sync.Mutex
+		stats WarmupStats
+		wg    sync.WaitGroup
+		sem   = make(chan struct{}, opt.Concurrency)
+	)
+	var finish = func(key interface{}, value interface{}, err error) {
+		var nc, negative = c.(*NegativeCache)
+		switch {
+		case err == nil:
+			c.PutWithExpire(key, value, opt.LifeSpan)
+		case err == ErrNotFound && negative:
+			nc.PutNegative(key, 0)
+		case err != ErrNotFound && opt.OnError != nil:
+			opt.OnError(key, err)
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		switch {
+		case err == nil:
+			stats.Loaded++
+		case err == ErrNotFound:
+			stats.Missing++
+		default:
+			stats.Failed++
+		}
+		if opt.Progress != nil && stats.Done()%opt.ProgressEvery == 0 {
+			opt.Progress(stats)
+		}
+	}
+
+	var (
+		interval time.Duration
+		next     = time.Now()
+		ctxErr   error
+	)
+	if opt.Rate > 0 {
+		interval = time.Duration(float64(time.Second) / opt.Rate)
+	}
+	for ctxErr == nil {
+		if ctxErr = ctx.Err(); ctxErr != nil {
+			break
+		}
+		var key, ok = keys()
+		if !ok {
+			break
+		}
+		if interval > 0 {
+			if d := time.Until(next); d > 0 {
+				var timer = time.NewTimer(d)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					ctxErr = ctx.Err()
+					continue
+				case <-timer.C:
+				}
+			}
+			next = next.Add(interval)
+			if now := time.Now(); next.Before(now) {
+				// 不累积空闲期间的配额
+				next = now
+			}
+		}
+		select {
+		case <-ctx.Done():
+			ctxErr = ctx.Err()
+			continue
+		case sem <- struct{}{}:
+		}
+		wg.Add(1)
+		var task = func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			var value, err = loader(key)
+			finish(key, value, err)
+		}
+		if err := pool.Submit(task); err != nil {
+			// 协程池已关闭，直接执行
+			task()
+		}
+	}
+	wg.Wait()
+
+	lock.Lock()
+	defer lock.Unlock()
+	if opt.Progress != nil && stats.Done()%opt.ProgressEvery != 0 {
+		opt.Progress(stats)
+	}
+	return stats, ctxErr
+}
+
+// WriteAccessLog 按热度降序写出最多n个热点key，支持string、int、int64、uint64类型的key，其他类型及超长的string忽略
+func WriteAccessLog(w io.Writer, t *HotKeyTracker, n int) error {
+	var bw = bufio.NewWriter(w)
+	if _, err := bw.WriteString(accessLogMagic); err != nil {
+		return err
+	}
+	var buf = make([]byte, 0, 64)
+	for _, hk := range t.TopKeys(n) {
+		var ok bool
+		if buf, ok = appendAccessLogKey(buf[:0], hk.Key); !ok {
+			continue
+		}
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// ReadAccessLog 读取 WriteAccessLog 写出的访问日志，return 按热度降序的key
+func ReadAccessLog(r io.Reader) ([]interface{}, error) {
+	var br = bufio.NewReader(r)
+	var magic = make([]byte, len(accessLogMagic))
+	if _, err := io.ReadFull(br, magic); err != nil {
+		return nil, fmt.Errorf("read access log: %w", err)
+	}
+	if string(magic) != accessLogMagic {
+		return nil, fmt.Errorf("read access log: bad magic %q", magic)
+	}
+	var keys []interface{}
+	for {
+		var kind, err = br.ReadByte()
+		if err == io.EOF {
+			return keys, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read access log: %w", err)
+		}
+		var key interface{}
+		switch kind {
+		case 's':
+			var n uint64
+			if n, err = binary.ReadUvarint(br); err == nil && n > accessLogMaxKeyLen {
+				err = fmt.Errorf("key length %d exceeds %d", n, accessLogMaxKeyLen)
+			} else if err == nil {
+				var b = make([]byte, n)
+				if _, err = io.ReadFull(br, b); err == nil {
+					key = string(b)
+				}
+			}
+		case 'i', 'I':
+			var v int64
+			if v, err = binary.ReadVarint(br); err == nil {
+				if key = v; kind == 'i' {
+					key = int(v)
+				}
+			}
+		case 'u':
+			var v uint64
+			if v, err = binary.ReadUvarint(br); err == nil {
+				key = v
+			}
+		default:
+			err = fmt.Errorf("bad key type %q", kind)
+		}
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, fmt.Errorf("read access log: %w", err)
+		}
+		keys = append(keys, key)
+	}
+}
+
+func appendAccessLogKey(buf []byte, key interface{}) ([]byte, bool) {
+	var tmp [binary.MaxVarintLen64]byte
+	switch k := key.(type) {
+	case string:
+		if len(k) > accessLogMaxKeyLen {
+			return buf, false
+		}
+		buf = append(buf, 's')
+		buf = append(buf, tmp[:binary.PutUvarint(tmp[:], uint64(len(k)))]...)
+		buf = append(buf, k...)
+	case int:
+		buf = append(buf, 'i')
+		buf = append(buf, tmp[:binary.PutVarint(tmp[:], int64(k))]...)
+	case int64:
+		buf = append(buf, 'I')
+		buf = append(buf, tmp[:binary.PutVarint(tmp[:], k)]...)
+	case uint64:
+		buf = append(buf, 'u')
+		buf = append(buf, tmp[:binary.PutUvarint(tmp[:], k)]...)
+	default:
+		return buf, false
+	}
+	return buf, true
+}
@@ -0,0 +1,162 @@
+package cache_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/1005281342/basic_component/cache"
+)
+
+func TestWarmup(t *testing.T) {
+	for _, ct := range []cache.CacheType{cache.LRU, cache.LRUk} {
+		t.Run(ct.String(), func(t *testing.T) {
+			var c, err = cache.NewCache(ct, &cache.Opt{Capacity: 1000, Interval: time.Hour, LruK: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var keys = make([]interface{}, 250)
+			for i := range keys {
+				keys[i] = i
+			}
+			var (
+				running, peak int32
+				progress      []int
+				errLoad       = errors.New("load failed")
+				failed        int32
+			)
+			var loader = func(key interface{}) (interface{}, error) {
+				var n = atomic.AddInt32(&running, 1)
+				defer atomic.AddInt32(&running, -1)
+				for {
+					var p = atomic.LoadInt32(&peak)
+					if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+						break
+					}
+				}
+				time.Sleep(time.Millisecond)
+				switch key.(int) % 50 {
+				case 1:
+					return nil, cache.ErrNotFound
+				case 2:
+					return nil, errLoad
+				}
+				return key, nil
+			}
+			var stats, werr = cache.Warmup(context.Background(), c, cache.SliceKeys(keys), loader, cache.WarmupOpt{
+				Concurrency: 4,
+				Progress:    func(s cache.WarmupStats) { progress = append(progress, s.Done()) },
+				OnError:     func(key interface{}, err error) { atomic.AddInt32(&failed, 1) },
+			})
+			if werr != nil {
+				t.Fatal(werr)
+			}
+			if stats.Loaded != 240 || stats.Missing != 5 || stats.Failed != 5 || failed != 5 {
+				t.Fatalf("stats = %+v, failed = %d", stats, failed)
+			}
+			if c.Len() != 240 {
+				t.Fatalf("Len() = %d, want 240", c.Len())
+			}
+			if peak > 4 {
+				t.Fatalf("peak concurrency = %d, want <= 4", peak)
+			}
+			if fmt.Sprint(progress) != "[100 200 250]" {
+				t.Fatalf("progress = %v, want [100 200 250]", progress)
+			}
+		})
+	}
+}
+
+// 容量有限的协程池与淘汰回调：加载任务不能占用淘汰回调的协程池
+func TestWarmupBoundedPool(t *testing.T) {
+	var evicted int32
+	var c, err = cache.NewCache(cache.LRU, &cache.Opt{
+		Capacity:         2,
+		AntsPoolCapacity: 2,
+		Interval:         time.Hour,
+		Callback:         func(key, value interface{}) { atomic.AddInt32(&evicted, 1) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys = make([]interface{}, 100)
+	for i := range keys {
+		keys[i] = i
+	}
+	var done = make(chan cache.WarmupStats, 1)
+	go func() {
+		var stats, _ = cache.Warmup(context.Background(), c, cache.SliceKeys(keys),
+			func(key interface{}) (interface{}, error) { return key, nil }, cache.WarmupOpt{Concurrency: 4})
+		done <- stats
+	}()
+	select {
+	case stats := <-done:
+		if stats.Loaded != 100 || c.Len() != 2 {
+			t.Fatalf("stats = %+v, Len() = %d", stats, c.Len())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Warmup deadlocked")
+	}
+}
+
+func TestWarmupRateAndCancel(t *testing.T) {
+	var c, err = cache.NewCache(cache.LRU, &cache.Opt{Capacity: 1000, Interval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var i int
+	var keys = func() (interface{}, bool) {
+		i++
+		return i, true
+	}
+	var loader = func(key interface{}) (interface{}, error) { return key, nil }
+	var ctx, cancel = context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	var stats, werr = cache.Warmup(ctx, c, keys, loader, cache.WarmupOpt{Rate: 100})
+	if werr != context.DeadlineExceeded {
+		t.Fatalf("Warmup() = %v, want DeadlineExceeded", werr)
+	}
+	// 200ms内限速100/s
+	if stats.Loaded < 5 || stats.Loaded > 40 {
+		t.Fatalf("Loaded = %d, want about 20", stats.Loaded)
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	var c, err = cache.NewHotKeyCache(cache.LRU, &cache.Opt{Capacity: 100, Interval: time.Hour}, cache.HotKeyOpt{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hot = []interface{}{"a", 7, int64(-3), uint64(9)}
+	for i, k := range hot {
+		for j := 0; j < 100-i*10; j++ {
+			c.Get(k)
+		}
+	}
+	c.Get(struct{}{})
+	var buf bytes.Buffer
+	if err = cache.WriteAccessLog(&buf, c.Tracker(), 10); err != nil {
+		t.Fatal(err)
+	}
+	var keys []interface{}
+	if keys, err = cache.ReadAccessLog(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprintf("%#v", keys) != fmt.Sprintf("%#v", hot) {
+		t.Fatalf("ReadAccessLog() = %#v, want %#v", keys, hot)
+	}
+	for _, corrupt := range []string{
+		"CAL1s\x05ab", // 截断
+		"CAL1s\xff\xff\xff\xff\xff\xff\xff\xff\xff\x01", // 长度超出上限
+		"CAL1x", // 未知类型
+	} {
+		if _, err = cache.ReadAccessLog(strings.NewReader(corrupt)); err == nil {
+			t.Fatalf("ReadAccessLog(%q) succeeded", corrupt)
+		}
+	}
+}
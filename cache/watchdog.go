@@ -1,16 +1,22 @@
 package cache
 
 import (
+	"runtime"
 	"time"
 )
 
+// 可定期回收过期元素的对象
+type expirer interface {
+	DeleteExpired()
+}
+
 type watchdog struct {
 	interval time.Duration
 	stop     chan struct{}
 }
 
 // 启动看门狗
-func (w *watchdog) run(c ExpireCache) {
+func (w *watchdog) run(c expirer) {
 	var ticker = time.NewTicker(w.interval)
 	for {
 		select {
@@ -31,3 +37,12 @@ func (w *watchdog) Interval() time.Duration {
 func stopWatchdog(c *expire) {
 	c.stop <- struct{}{}
 }
+
+// 启动看门狗，定期回收缓存c中的过期元素
+// c 应为加锁的包装类型，保证回收过程与读写互斥
+func startWatchdog(e *expire, c expirer) {
+	if e.interval > 0 {
+		go e.run(c)
+		runtime.SetFinalizer(e, stopWatchdog)
+	}
+}
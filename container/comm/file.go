@@ -27,14 +27,29 @@ func ReadFile(filename string) []string {
 			break
 		}
 
-		wordSlice := strings.Fields(line)
-		for _, word := range wordSlice {
-			if word = extractStr(strings.ToLower(word)); word != "" {
-				words = append(words, word)
-			}
-		}
+		words = appendWords(words, line)
+	}
+
+	return words
+}
+
+// ReadWords 按与 ReadFile 相同的规则切分r中的单词，读取失败时返回错误
+func ReadWords(r io.Reader) ([]string, error) {
+	var words []string
+	var scanner = bufio.NewScanner(r)
+	for scanner.Scan() {
+		words = appendWords(words, scanner.Text())
 	}
+	return words, scanner.Err()
+}
 
+// 转为小写并只保留字母
+func appendWords(words []string, line string) []string {
+	for _, word := range strings.Fields(line) {
+		if word = extractStr(strings.ToLower(word)); word != "" {
+			words = append(words, word)
+		}
+	}
 	return words
 }
 
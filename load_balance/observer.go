@@ -0,0 +1,29 @@
+package load_balance
+
+// Observer 负载均衡事件观察者，方法会被并发调用，需要并发安全且尽快返回
+type Observer interface {
+	// 选中节点
+	OnPick(node string)
+	// 获取节点失败
+	OnError(err error)
+}
+
+// BalanceWithObserver 在任意负载均衡器外层通知选取结果
+type BalanceWithObserver struct {
+	LoadBalance
+	observer Observer
+}
+
+func NewBalanceWithObserver(lb LoadBalance, o Observer) *BalanceWithObserver {
+	return &BalanceWithObserver{LoadBalance: lb, observer: o}
+}
+
+func (b *BalanceWithObserver) Get(key string) (string, error) {
+	var node, err = b.LoadBalance.Get(key)
+	if err != nil {
+		b.observer.OnError(err)
+		return "", err
+	}
+	b.observer.OnPick(node)
+	return node, nil
+}
@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/1005281342/basic_component/load_balance"
+)
+
+// BalanceMetrics 负载均衡指标，实现 load_balance.Observer 与 Collector
+type BalanceMetrics struct {
+	name   string
+	errors uint64 // 获取节点失败次数
+
+	lock  sync.RWMutex
+	nodes map[string]*nodeCounter
+}
+
+type nodeCounter struct {
+	picks  uint64
+	errors uint64
+}
+
+func NewBalanceMetrics(name string) *BalanceMetrics {
+	return &BalanceMetrics{name: name, nodes: make(map[string]*nodeCounter)}
+}
+
+// NewBalance 创建可观察的负载均衡器及其指标
+func NewBalance(name string, lb load_balance.LoadBalance) (*load_balance.BalanceWithObserver, *BalanceMetrics) {
+	var m = NewBalanceMetrics(name)
+	return load_balance.NewBalanceWithObserver(lb, m), m
+}
+
+func (m *BalanceMetrics) node(addr string) *nodeCounter {
+	m.lock.RLock()
+	var n, ok = m.nodes[addr]
+	m.lock.RUnlock()
+	if ok {
+		return n
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if n, ok = m.nodes[addr]; !ok {
+		n = &nodeCounter{}
+		m.nodes[addr] = n
+	}
+	return n
+}
+
+func (m *BalanceMetrics) OnPick(node string) {
+	atomic.AddUint64(&m.node(node).picks, 1)
+}
+
+func (m *BalanceMetrics) OnError(error) {
+	atomic.AddUint64(&m.errors, 1)
+}
+
+// ReportError 记录请求选中节点失败，由调用方在请求失败后调用
+func (m *BalanceMetrics) ReportError(node string) {
+	atomic.AddUint64(&m.node(node).errors, 1)
+}
+
+func (m *BalanceMetrics) Collect() []Family {
+	m.lock.RLock()
+	var addrs = make([]string, 0, len(m.nodes))
+	for addr := range m.nodes {
+		addrs = append(addrs, addr)
+	}
+	m.lock.RUnlock()
+	sort.Strings(addrs)
+
+	var picks = Family{Name: "lb_picks_total", Help: "Number of times a node was picked.", Type: Counter}
+	var nodeErrors = Family{Name: "lb_node_errors_total", Help: "Number of failed requests reported for a node.", Type: Counter}
+	for _, addr := range addrs {
+		var n = m.node(addr)
+		var labels = instance(m.name, Label{Name: "node", Value: addr})
+		picks.Samples = append(picks.Samples, Sample{Labels: labels, Value: float64(atomic.LoadUint64(&n.picks))})
+		nodeErrors.Samples = append(nodeErrors.Samples, Sample{Labels: labels, Value: float64(atomic.LoadUint64(&n.errors))})
+	}
+	return []Family{
+		picks,
+		nodeErrors,
+		{Name: "lb_errors_total", Help: "Number of failed node picks.", Type: Counter,
+			Samples: []Sample{{Labels: instance(m.name), Value: float64(atomic.LoadUint64(&m.errors))}}},
+	}
+}
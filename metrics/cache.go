@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/1005281342/basic_component/cache"
+)
+
+// CacheMetrics 缓存指标，实现 cache.Observer 与 Collector
+type CacheMetrics struct {
+	name      string
+	hits      uint64
+	misses    uint64
+	puts      uint64
+	evictions uint64
+
+	lock  sync.RWMutex
+	cache cache.Cache // 统计元素个数与容量，为nil时不导出
+}
+
+func NewCacheMetrics(name string) *CacheMetrics {
+	return &CacheMetrics{name: name}
+}
+
+// NewCache 创建可观察缓存及其指标
+func NewCache(name string, ct cache.CacheType, opt *cache.Opt) (*cache.ObservedCache, *CacheMetrics, error) {
+	var m = NewCacheMetrics(name)
+	var c, err = cache.NewObservedCache(ct, opt, m)
+	if err != nil {
+		return nil, nil, err
+	}
+	m.Bind(c.Unwrap())
+	return c, m, nil
+}
+
+// Bind 绑定缓存，导出元素个数；实现 cache.InspectCache 时同时导出容量
+func (m *CacheMetrics) Bind(c cache.Cache) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.cache = c
+}
+
+func (m *CacheMetrics) OnGet(hit bool) {
+	if hit {
+		atomic.AddUint64(&m.hits, 1)
+	} else {
+		atomic.AddUint64(&m.misses, 1)
+	}
+}
+
+func (m *CacheMetrics) OnPut() {
+	atomic.AddUint64(&m.puts, 1)
+}
+
+func (m *CacheMetrics) OnEvict() {
+	atomic.AddUint64(&m.evictions, 1)
+}
+
+func (m *CacheMetrics) counter(name, help string, v *uint64) Family {
+	return Family{Name: name, Help: help, Type: Counter,
+		Samples: []Sample{{Labels: instance(m.name), Value: float64(atomic.LoadUint64(v))}}}
+}
+
+func (m *CacheMetrics) gauge(name, help string, v int) Family {
+	return Family{Name: name, Help: help, Type: Gauge,
+		Samples: []Sample{{Labels: instance(m.name), Value: float64(v)}}}
+}
+
+func (m *CacheMetrics) Collect() []Family {
+	var families = []Family{
+		m.counter("cache_hits_total", "Number of cache lookups that found a value.", &m.hits),
+		m.counter("cache_misses_total", "Number of cache lookups that found no value.", &m.misses),
+		m.counter("cache_puts_total", "Number of cache writes.", &m.puts),
+		m.counter("cache_evictions_total", "Number of entries evicted, expired, removed or cleared.", &m.evictions),
+	}
+	m.lock.RLock()
+	var c = m.cache
+	m.lock.RUnlock()
+	if c != nil {
+		families = append(families, m.gauge("cache_size", "Number of entries in the cache.", c.Len()))
+		if ic, ok := c.(cache.InspectCache); ok {
+			families = append(families, m.gauge("cache_capacity", "Capacity of the cache, 0 for unbounded.", ic.Cap()))
+		}
+	}
+	return families
+}
@@ -0,0 +1,153 @@
+// Package metrics 以 Prometheus 文本格式导出缓存与负载均衡的统计指标
+//
+// 不依赖 Prometheus 客户端库，指标按用户给定的实例名称打上 instance 标签：
+//
+//	var reg = metrics.NewRegistry()
+//	var c, m, _ = metrics.NewCache("user", cache.LRU, &cache.Opt{Capacity: 1000})
+//	reg.Register(m)
+//	http.Handle("/metrics", reg)
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 指标类型
+const (
+	Counter = "counter"
+	Gauge   = "gauge"
+)
+
+// Label 标签
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample 样本
+type Sample struct {
+	Labels []Label
+	Value  float64
+}
+
+// Family 同名指标的全部样本
+type Family struct {
+	Name    string
+	Help    string
+	Type    string
+	Samples []Sample
+}
+
+// Collector 指标收集器，Collect 会被并发调用
+type Collector interface {
+	Collect() []Family
+}
+
+// Registry 收集器注册表，实现 http.Handler
+type Registry struct {
+	lock       sync.RWMutex
+	collectors []Collector
+}
+
+// DefaultRegistry 默认注册表
+var DefaultRegistry = NewRegistry()
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) Register(c Collector) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// Unregister 移除收集器，return 是否存在
+func (r *Registry) Unregister(c Collector) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for i, cc := range r.collectors {
+		if cc == c {
+			r.collectors = append(r.collectors[:i], r.collectors[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Gather 收集全部指标，同名指标合并，按名称排序
+func (r *Registry) Gather() []Family {
+	r.lock.RLock()
+	var collectors = append([]Collector(nil), r.collectors...)
+	r.lock.RUnlock()
+
+	var families = make(map[string]*Family)
+	for _, c := range collectors {
+		for _, f := range c.Collect() {
+			if exist, ok := families[f.Name]; ok {
+				exist.Samples = append(exist.Samples, f.Samples...)
+				continue
+			}
+			var f = f
+			families[f.Name] = &f
+		}
+	}
+	var result = make([]Family, 0, len(families))
+	for _, f := range families {
+		result = append(result, *f)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// WriteText 按 Prometheus 文本格式写出全部指标
+func (r *Registry) WriteText(w io.Writer) error {
+	var bw = bufio.NewWriter(w)
+	for _, f := range r.Gather() {
+		bw.WriteString("# HELP " + f.Name + " " + escapeHelp(f.Help) + "\n")
+		bw.WriteString("# TYPE " + f.Name + " " + f.Type + "\n")
+		for _, s := range f.Samples {
+			bw.WriteString(f.Name)
+			if len(s.Labels) > 0 {
+				bw.WriteByte('{')
+				for i, l := range s.Labels {
+					if i > 0 {
+						bw.WriteByte(',')
+					}
+					bw.WriteString(l.Name + `="` + escapeLabel(l.Value) + `"`)
+				}
+				bw.WriteByte('}')
+			}
+			bw.WriteString(" " + strconv.FormatFloat(s.Value, 'g', -1, 64) + "\n")
+		}
+	}
+	return bw.Flush()
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = r.WriteText(w)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func instance(name string, labels ...Label) []Label {
+	return append([]Label{{Name: "instance", Value: name}}, labels...)
+}
@@ -0,0 +1,97 @@
+package metrics_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/1005281342/basic_component/cache"
+	"github.com/1005281342/basic_component/metrics"
+)
+
+// 依次返回给定节点，节点为空时返回错误
+type stubBalance struct {
+	nodes []string
+	i     int
+}
+
+func (b *stubBalance) Add(params ...string) error { b.nodes = append(b.nodes, params[0]); return nil }
+func (b *stubBalance) Update()                    {}
+func (b *stubBalance) Get(string) (string, error) {
+	if len(b.nodes) == 0 {
+		return "", errors.New("empty")
+	}
+	b.i++
+	return b.nodes[b.i%len(b.nodes)], nil
+}
+
+func mustContain(t *testing.T, text string, lines ...string) {
+	t.Helper()
+	for _, l := range lines {
+		if !strings.Contains(text, l+"\n") {
+			t.Fatalf("missing %q in:\n%s", l, text)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	var reg = metrics.NewRegistry()
+	var c, cm, err = metrics.NewCache("user", cache.LRU, &cache.Opt{Capacity: 2, Interval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg.Register(cm)
+	c.Put("a", 1)
+	c.Put("b", 2)
+	c.Put("c", 3)
+	c.Get("c")
+	c.Get("a")
+
+	var lb, bm = metrics.NewBalance(`svc "a"`, &stubBalance{})
+	reg.Register(bm)
+	if _, err = lb.Get(""); err == nil {
+		t.Fatalf("Get on empty balance succeeded")
+	}
+	_ = lb.Add("10.0.0.1")
+	_ = lb.Add("10.0.0.2")
+	for i := 0; i < 3; i++ {
+		_, _ = lb.Get("")
+	}
+	bm.ReportError("10.0.0.1")
+
+	// 淘汰回调异步执行
+	time.Sleep(100 * time.Millisecond)
+	var w = httptest.NewRecorder()
+	reg.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	mustContain(t, w.Body.String(),
+		"# TYPE cache_hits_total counter",
+		`cache_hits_total{instance="user"} 1`,
+		`cache_misses_total{instance="user"} 1`,
+		`cache_puts_total{instance="user"} 3`,
+		`cache_evictions_total{instance="user"} 1`,
+		`cache_size{instance="user"} 2`,
+		`cache_capacity{instance="user"} 2`,
+		`lb_picks_total{instance="svc \"a\"",node="10.0.0.1"} 1`,
+		`lb_picks_total{instance="svc \"a\"",node="10.0.0.2"} 2`,
+		`lb_node_errors_total{instance="svc \"a\"",node="10.0.0.1"} 1`,
+		`lb_errors_total{instance="svc \"a\""} 1`,
+	)
+
+	// 同名指标合并为一组
+	var m2 = metrics.NewCacheMetrics("order")
+	reg.Register(m2)
+	var text strings.Builder
+	_ = reg.WriteText(&text)
+	if n := strings.Count(text.String(), "# TYPE cache_hits_total"); n != 1 {
+		t.Fatalf("TYPE cache_hits_total appears %d times", n)
+	}
+	mustContain(t, text.String(), `cache_hits_total{instance="order"} 0`)
+	if !reg.Unregister(m2) || reg.Unregister(m2) {
+		t.Fatalf("Unregister twice, want true then false")
+	}
+}
@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"time"
+)
+
+// NewTokenBucket 令牌桶：每秒补充rate个令牌，最多积累burst个，允许突发
+func NewTokenBucket(rate float64, burst int, opt *Opt) (*Limiter, error) {
+	if rate <= 0 || burst <= 0 {
+		return nil, ErrInvalid
+	}
+	return newLimiter(opt, func() state {
+		return &tokenBucket{rate: rate, burst: burst, tokens: float64(burst)}
+	})
+}
+
+// NewLeakyBucket 漏桶：请求按每秒rate个的固定间隔放行，不允许突发，
+// capacity为排队的请求数上限，超过时 Reserve/Wait 失败
+func NewLeakyBucket(rate float64, capacity int, opt *Opt) (*Limiter, error) {
+	if rate <= 0 || capacity <= 0 {
+		return nil, ErrInvalid
+	}
+	return newLimiter(opt, func() state {
+		return &leakyBucket{interval: time.Duration(float64(time.Second) / rate), capacity: capacity}
+	})
+}
+
+type tokenBucket struct {
+	rate   float64
+	burst  int
+	tokens float64   // 可为负数，表示已预约的未来令牌
+	last   time.Time // 最后补充令牌的时刻
+}
+
+func (b *tokenBucket) advance(now time.Time) {
+	if !b.last.IsZero() && now.After(b.last) {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > float64(b.burst) {
+			b.tokens = float64(b.burst)
+		}
+	}
+	if now.After(b.last) {
+		b.last = now
+	}
+}
+
+func (b *tokenBucket) reserve(now time.Time, n int, maxWait time.Duration) (time.Duration, error) {
+	if n > b.burst {
+		return 0, ErrExceedsBurst
+	}
+	b.advance(now)
+	var tokens = b.tokens - float64(n)
+	var wait time.Duration
+	if tokens < 0 {
+		wait = time.Duration(-tokens / b.rate * float64(time.Second))
+	}
+	if wait > maxWait {
+		return 0, ErrExceedsDeadline
+	}
+	b.tokens = tokens
+	return wait, nil
+}
+
+func (b *tokenBucket) cancel(now time.Time, at time.Time, n int) {
+	b.advance(now)
+	if b.tokens += float64(n); b.tokens > float64(b.burst) {
+		b.tokens = float64(b.burst)
+	}
+}
+
+// 按虚拟调度时刻实现的漏桶，next为下一个请求的放行时刻
+type leakyBucket struct {
+	interval time.Duration
+	capacity int
+	next     time.Time
+}
+
+func (b *leakyBucket) reserve(now time.Time, n int, maxWait time.Duration) (time.Duration, error) {
+	if n > b.capacity {
+		return 0, ErrExceedsBurst
+	}
+	var start = b.next
+	if start.Before(now) {
+		start = now
+	}
+	var wait = start.Sub(now)
+	// 排在前面的请求数加上本次请求不超过容量
+	if int(wait/b.interval)+n > b.capacity {
+		return 0, ErrQueueFull
+	}
+	if wait > maxWait {
+		return 0, ErrExceedsDeadline
+	}
+	b.next = start.Add(time.Duration(n) * b.interval)
+	return wait, nil
+}
+
+func (b *leakyBucket) cancel(now time.Time, at time.Time, n int) {
+	// 只归还排在最后的预约，避免打乱其他预约的放行时刻
+	if b.next.Equal(at.Add(time.Duration(n) * b.interval)) {
+		b.next = at
+	}
+}
@@ -0,0 +1,223 @@
+// Package ratelimit 按key限流，支持令牌桶、漏桶与滑动窗口日志
+//
+// 每个key的限流状态保存在容量有限的 cache.LRUCache 中，长时间不活跃的key会被淘汰，内存不会无限增长。
+// 被淘汰的key再次出现时从初始状态开始计算，因此容量应大于同时活跃的key数。
+//
+//	var l, _ = ratelimit.NewTokenBucket(10, 20, nil) // 每个key每秒10个，突发20个
+//	if !l.Allow(clientIP) {
+//		// 拒绝
+//	}
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/1005281342/basic_component/cache"
+)
+
+const (
+	// 默认最多保存的key数
+	DefaultMaxKeys = 10000
+
+	// 状态缓存的回收间隔，状态不会过期，只通过容量淘汰
+	stateInterval = time.Minute
+	// 不限制等待时长
+	infDuration = time.Duration(math.MaxInt64)
+)
+
+var (
+	// 速率、突发容量或窗口不是正数
+	ErrInvalid = errors.New("ratelimit: rate, burst and window must be positive")
+	// 请求数超过限流器的突发容量，永远无法满足
+	ErrExceedsBurst = errors.New("ratelimit: n exceeds limiter burst")
+	// 需要等待的时长超过ctx的截止时间
+	ErrExceedsDeadline = errors.New("ratelimit: wait would exceed context deadline")
+	// 漏桶排队的请求数已达上限
+	ErrQueueFull = errors.New("ratelimit: leaky bucket queue is full")
+)
+
+// Opt 限流器配置
+type Opt struct {
+	MaxKeys int              // 最多保存的key数，默认10000，超过时淘汰最久未使用的key
+	Clock   cache.TimeSource // 时钟，默认使用系统时间，Wait 按该时钟计算等待时长后实际睡眠
+}
+
+// 单个key的限流状态，在缓存锁内调用
+type state interface {
+	// 在now时刻预约n个请求，等待时长超过maxWait或n超过突发容量时不预约，return 需要等待的时长
+	reserve(now time.Time, n int, maxWait time.Duration) (time.Duration, error)
+	// 取消在at时刻生效的n个请求
+	cancel(now time.Time, at time.Time, n int)
+}
+
+// Limiter 按key限流，并发安全
+type Limiter struct {
+	states   *cache.LRUCache
+	clock    cache.TimeSource
+	newState func() state
+}
+
+func newLimiter(opt *Opt, newState func() state) (*Limiter, error) {
+	var o Opt
+	if opt != nil {
+		o = *opt
+	}
+	if o.MaxKeys <= 0 {
+		o.MaxKeys = DefaultMaxKeys
+	}
+	if o.Clock == nil {
+		o.Clock = realClock{}
+	}
+	var states, err = cache.NewLRUCache(&cache.Opt{Capacity: o.MaxKeys, Interval: stateInterval, Clock: o.Clock})
+	if err != nil {
+		return nil, err
+	}
+	return &Limiter{states: states, clock: o.Clock, newState: newState}, nil
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// Allow 是否允许1个请求
+func (l *Limiter) Allow(key interface{}) bool {
+	return l.AllowN(key, 1)
+}
+
+// AllowN 是否允许n个请求，允许时计入限流
+func (l *Limiter) AllowN(key interface{}, n int) bool {
+	var _, _, err = l.reserve(key, n, 0)
+	return err == nil
+}
+
+// Reserve 预约1个请求
+func (l *Limiter) Reserve(key interface{}) *Reservation {
+	return l.ReserveN(key, 1)
+}
+
+// ReserveN 预约n个请求，调用方需要等待 Delay 后执行，不执行时调用 Cancel 归还
+func (l *Limiter) ReserveN(key interface{}, n int) *Reservation {
+	var now, delay, err = l.reserve(key, n, infDuration)
+	var r = &Reservation{limiter: l, key: key, n: n, err: err}
+	if err == nil {
+		r.at = now.Add(delay)
+	}
+	return r
+}
+
+// Wait 等待直到允许1个请求
+func (l *Limiter) Wait(ctx context.Context, key interface{}) error {
+	return l.WaitN(ctx, key, 1)
+}
+
+// WaitN 等待直到允许n个请求，ctx取消或等待时长超过截止时间时返回错误且不计入限流
+func (l *Limiter) WaitN(ctx context.Context, key interface{}, n int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var maxWait = infDuration
+	if deadline, ok := ctx.Deadline(); ok {
+		maxWait = time.Until(deadline)
+	}
+	var now, delay, err = l.reserve(key, n, maxWait)
+	if err != nil {
+		return err
+	}
+	if delay <= 0 {
+		return nil
+	}
+	var timer = time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.cancel(key, now.Add(delay), n)
+		return ctx.Err()
+	}
+}
+
+// Len 当前保存状态的key数
+func (l *Limiter) Len() int {
+	return l.states.Len()
+}
+
+// Reset 清除key的限流状态
+func (l *Limiter) Reset(key interface{}) {
+	l.states.Remove(key)
+}
+
+func (l *Limiter) reserve(key interface{}, n int, maxWait time.Duration) (time.Time, time.Duration, error) {
+	var (
+		now   = l.clock.Now()
+		delay time.Duration
+		err   error
+	)
+	if n <= 0 {
+		return now, 0, nil
+	}
+	l.states.Compute(key, func(old interface{}, exists bool) (interface{}, bool) {
+		var s, ok = old.(state)
+		if !ok {
+			s = l.newState()
+		}
+		delay, err = s.reserve(now, n, maxWait)
+		// 新key预约失败时不保存状态
+		return s, exists || err == nil
+	})
+	return now, delay, err
+}
+
+func (l *Limiter) cancel(key interface{}, at time.Time, n int) {
+	var now = l.clock.Now()
+	l.states.Compute(key, func(old interface{}, exists bool) (interface{}, bool) {
+		if s, ok := old.(state); ok {
+			s.cancel(now, at, n)
+		}
+		return old, exists
+	})
+}
+
+// Reservation 预约结果
+type Reservation struct {
+	limiter *Limiter
+	key     interface{}
+	n       int
+	at      time.Time // 允许执行的时刻
+	err     error
+}
+
+// OK 是否预约成功，n超过突发容量或漏桶排队已满时失败
+func (r *Reservation) OK() bool {
+	return r.err == nil
+}
+
+// Err 预约失败的原因
+func (r *Reservation) Err() error {
+	return r.err
+}
+
+// Delay 距离允许执行还需等待的时长，预约失败时为无穷大
+func (r *Reservation) Delay() time.Duration {
+	if r.err != nil {
+		return infDuration
+	}
+	if d := r.at.Sub(r.limiter.clock.Now()); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// Cancel 放弃执行并尽量归还预约，已到执行时刻的预约不归还
+func (r *Reservation) Cancel() {
+	if r.err != nil || r.n <= 0 || !r.at.After(r.limiter.clock.Now()) {
+		return
+	}
+	r.limiter.cancel(r.key, r.at, r.n)
+	r.n = 0
+}
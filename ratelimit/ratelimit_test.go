@@ -0,0 +1,152 @@
+package ratelimit_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/1005281342/basic_component/cache/cachetest"
+	"github.com/1005281342/basic_component/ratelimit"
+)
+
+// 连续调用Allow，return 允许的次数
+func allowed(l *ratelimit.Limiter, key interface{}, times int) int {
+	var n int
+	for i := 0; i < times; i++ {
+		if l.Allow(key) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestTokenBucket(t *testing.T) {
+	var clock = cachetest.NewFakeClock()
+	var l, err = ratelimit.NewTokenBucket(10, 5, &ratelimit.Opt{Clock: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 突发5个，之后每100ms补充1个
+	if n := allowed(l, "a", 10); n != 5 {
+		t.Fatalf("allowed = %d, want 5", n)
+	}
+	if n := allowed(l, "b", 10); n != 5 {
+		t.Fatalf("allowed(b) = %d, want 5", n)
+	}
+	clock.Advance(250 * time.Millisecond)
+	if n := allowed(l, "a", 10); n != 2 {
+		t.Fatalf("allowed after 250ms = %d, want 2", n)
+	}
+	if l.AllowN("a", 6) {
+		t.Fatalf("AllowN(6) with burst 5 = true")
+	}
+
+	// 预约未来的令牌
+	var r = l.ReserveN("a", 3)
+	if !r.OK() || r.Delay() != 250*time.Millisecond {
+		t.Fatalf("ReserveN(3) = %v, %v, want 250ms", r.OK(), r.Delay())
+	}
+	r.Cancel()
+	clock.Advance(50 * time.Millisecond)
+	if !l.Allow("a") {
+		t.Fatalf("Allow after Cancel = false")
+	}
+	if r = l.ReserveN("a", 6); r.OK() || r.Err() != ratelimit.ErrExceedsBurst {
+		t.Fatalf("ReserveN(6) err = %v, want ErrExceedsBurst", r.Err())
+	}
+}
+
+func TestLeakyBucket(t *testing.T) {
+	var clock = cachetest.NewFakeClock()
+	var l, err = ratelimit.NewLeakyBucket(10, 3, &ratelimit.Opt{Clock: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 不允许突发
+	if n := allowed(l, "a", 10); n != 1 {
+		t.Fatalf("allowed = %d, want 1", n)
+	}
+	clock.Advance(100 * time.Millisecond)
+	var delays []time.Duration
+	for i := 0; i < 4; i++ {
+		var r = l.Reserve("a")
+		if !r.OK() {
+			if r.Err() != ratelimit.ErrQueueFull || i != 3 {
+				t.Fatalf("Reserve #%d err = %v", i, r.Err())
+			}
+			break
+		}
+		delays = append(delays, r.Delay())
+	}
+	if fmt.Sprint(delays) != "[0s 100ms 200ms]" {
+		t.Fatalf("delays = %v, want [0s 100ms 200ms]", delays)
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	var clock = cachetest.NewFakeClock()
+	var l, err = ratelimit.NewSlidingWindow(3, time.Second, &ratelimit.Opt{Clock: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if !l.Allow("a") {
+			t.Fatalf("Allow #%d = false", i)
+		}
+		clock.Advance(300 * time.Millisecond)
+	}
+	// 窗口内已有3个请求，最早的在100ms后滑出
+	if l.Allow("a") {
+		t.Fatalf("Allow over limit = true")
+	}
+	if r := l.Reserve("a"); r.Delay() != 100*time.Millisecond {
+		t.Fatalf("Reserve delay = %v, want 100ms", r.Delay())
+	} else {
+		r.Cancel()
+	}
+	clock.Advance(100 * time.Millisecond)
+	if !l.Allow("a") || l.Allow("a") {
+		t.Fatalf("want exactly one request after the oldest slides out")
+	}
+}
+
+func TestLimiterMaxKeys(t *testing.T) {
+	var l, err = ratelimit.NewTokenBucket(1, 1, &ratelimit.Opt{MaxKeys: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 1000; i++ {
+		l.Allow(i)
+	}
+	if n := l.Len(); n != 100 {
+		t.Fatalf("Len() = %d, want 100", n)
+	}
+}
+
+func TestLimiterWait(t *testing.T) {
+	var l, err = ratelimit.NewTokenBucket(100, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var start = time.Now()
+	for i := 0; i < 5; i++ {
+		if err = l.Wait(context.Background(), "a"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d < 35*time.Millisecond {
+		t.Fatalf("5 waits took %v, want about 40ms", d)
+	}
+
+	var ctx, cancel = context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if err = l.WaitN(ctx, "a", 1); err != ratelimit.ErrExceedsDeadline {
+		t.Fatalf("WaitN past deadline = %v, want ErrExceedsDeadline", err)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if err = l.Wait(ctx, "a"); err != context.Canceled {
+		t.Fatalf("Wait with canceled ctx = %v, want Canceled", err)
+	}
+}
@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"time"
+)
+
+// NewSlidingWindow 滑动窗口日志：任意window时长内最多limit个请求，精确但每个key保存最多limit个时间戳
+func NewSlidingWindow(limit int, window time.Duration, opt *Opt) (*Limiter, error) {
+	if limit <= 0 || window <= 0 {
+		return nil, ErrInvalid
+	}
+	return newLimiter(opt, func() state {
+		return &slidingWindow{limit: limit, window: window}
+	})
+}
+
+// 按时间升序记录窗口内请求的执行时刻，预约的请求记录为未来时刻
+type slidingWindow struct {
+	limit  int
+	window time.Duration
+	log    []time.Time
+}
+
+func (w *slidingWindow) reserve(now time.Time, n int, maxWait time.Duration) (time.Duration, error) {
+	if n > w.limit {
+		return 0, ErrExceedsBurst
+	}
+	// 丢弃滑出窗口的记录
+	var i int
+	for i < len(w.log) && !w.log[i].After(now.Add(-w.window)) {
+		i++
+	}
+	w.log = append(w.log[:0], w.log[i:]...)
+
+	var at = now
+	if over := len(w.log) + n - w.limit; over > 0 {
+		// 等到第over个记录滑出窗口
+		at = w.log[over-1].Add(w.window)
+	}
+	if last := len(w.log) - 1; last >= 0 && w.log[last].After(at) {
+		at = w.log[last]
+	}
+	var wait = at.Sub(now)
+	if wait > maxWait {
+		return 0, ErrExceedsDeadline
+	}
+	for j := 0; j < n; j++ {
+		w.log = append(w.log, at)
+	}
+	return wait, nil
+}
+
+func (w *slidingWindow) cancel(now time.Time, at time.Time, n int) {
+	// 从最后删除n个该时刻的记录
+	for j := len(w.log) - 1; j >= 0 && n > 0; j-- {
+		if w.log[j].Equal(at) {
+			w.log = append(w.log[:j], w.log[j+1:]...)
+			n--
+		}
+	}
+}